@@ -4,6 +4,21 @@ import axios from 'axios'
 // Go backend (see vite.config.js).
 const API_BASE = '/api'
 
+// The backend answers failures with {"error": {"code", "message", "details"}}.
+// Surface that message (and code) on the thrown error so components can show it.
+axios.interceptors.response.use(
+  (response) => response,
+  (error) => {
+    const body = error.response?.data?.error
+    if (body && typeof body === 'object') {
+      const details = (body.details || []).map((d) => `${d.field}: ${d.message}`)
+      error.message = details.length ? `${body.message} (${details.join('; ')})` : body.message
+      error.code = body.code
+    }
+    return Promise.reject(error)
+  },
+)
+
 export const chatAPI = {
   sendMessage: async (message) => {
     const response = await axios.post(`${API_BASE}/chat`, { message })
@@ -1,27 +1,145 @@
 package main
 
 import (
-    "log"
+    "context"
+    "errors"
+    "log/slog"
     "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "studyai/internal/agent"
+    "studyai/internal/ai"
     "studyai/internal/api"
+    "studyai/internal/config"
+    "studyai/internal/health"
+    "studyai/internal/media"
+    "studyai/internal/store"
+    "studyai/internal/telemetry"
 )
 
 func main() {
-    // Original endpoints
-    http.HandleFunc("/agent/run", api.StudyHandler)
-    http.HandleFunc("/chat", api.ChatHandler)
-
-    // New image analysis endpoints
-    http.HandleFunc("/analyze-image", api.ImageAnalysisHandler)
-    
-    // Quiz endpoints
-    http.HandleFunc("/generate-quiz", api.GenerateQuizHandler)
-    http.HandleFunc("/submit-quiz", api.SubmitQuizHandler)
-    
-    // Progress tracking endpoints
-    http.HandleFunc("/progress", api.GetProgressHandler)
-    http.HandleFunc("/update-progress", api.UpdateProgressHandler)
-
-    log.Println("Study Agent running on :8080")
-    log.Fatal(http.ListenAndServe(":8080", nil))
+    cfg, err := config.Load(os.Args[1:], os.Getenv)
+    if err != nil {
+        slog.Error("invalid configuration", "err", err)
+        os.Exit(2)
+    }
+
+    // Structured JSON logs; log level debug also emits trace spans.
+    slog.SetDefault(telemetry.NewLogger(os.Stdout, telemetry.ParseLevel(cfg.Log.Level)))
+    slog.Info("configuration loaded", "config", cfg)
+    if cfg.Server.AdminToken == "" {
+        slog.Warn("no admin token configured; /admin endpoints are unauthenticated")
+    }
+    if cfg.Server.TeacherToken == "" && cfg.Server.AdminToken == "" {
+        slog.Warn("no teacher or admin token configured; teacher endpoints such as the question bank are unauthenticated")
+    }
+
+    if err := run(cfg); err != nil {
+        slog.Error("server stopped with error", "err", err)
+        os.Exit(1)
+    }
+}
+
+func run(cfg config.Config) error {
+    st, err := store.Open(cfg.Storage.Dir)
+    if err != nil {
+        return err
+    }
+    if !st.Persistent() {
+        slog.Warn("no storage directory configured; quizzes, attempts and progress are kept in memory only")
+    }
+
+    llm := ai.NewClient(cfg.LLM)
+    ocr := media.NewOCRService(cfg.OCR)
+    mediaService, err := media.NewService(llm, ai.NewEmbedder(cfg.Retrieval), st, cfg.Adaptive, cfg.Quiz, cfg.Retrieval)
+    if err != nil {
+        return err
+    }
+    if err := mediaService.LoadStandardFiles(cfg.Standards.Files); err != nil {
+        return err
+    }
+    ready := health.NewChecker(cfg.Health.CheckTimeout.Std(), cfg.Health.CacheTTL.Std(),
+        health.Check{Name: "llm", Probe: llm.Ping},
+        health.Check{Name: "ocr", Probe: ocr.Ping},
+        health.Check{Name: "storage", Critical: true, Probe: st.Ping},
+    )
+
+    planner := agent.New(llm, cfg.Guardrails, cfg.Rules)
+    tutor, err := agent.NewTutor(llm, planner, mediaService, st, cfg.Tutor)
+    if err != nil {
+        return err
+    }
+
+    server := api.NewServer(api.Deps{
+        Config: cfg,
+        LLM:    llm,
+        Agent:  planner,
+        Tutor:  tutor,
+        Media:  mediaService,
+        OCR:    ocr,
+        Ready:  ready,
+    })
+
+    srv := &http.Server{
+        Addr:         cfg.Server.Addr,
+        Handler:      server.Handler(),
+        ReadTimeout:  cfg.Server.ReadTimeout.Std(),
+        WriteTimeout: cfg.Server.WriteTimeout.Std(),
+        IdleTimeout:  cfg.Server.IdleTimeout.Std(),
+        ErrorLog:     slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    serveErr := make(chan error, 1)
+    go func() {
+        tls := cfg.Server.TLSCertFile != ""
+        slog.Info("Study Agent running", "addr", cfg.Server.Addr, "tls", tls)
+        if tls {
+            serveErr <- srv.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+        } else {
+            serveErr <- srv.ListenAndServe()
+        }
+    }()
+
+    select {
+    case err := <-serveErr:
+        return err
+    case <-ctx.Done():
+    }
+    stop()
+
+    // Fail readiness first and keep serving for the drain delay, so load
+    // balancers see /readyz fail and stop sending traffic before the
+    // listener closes; then let in-flight requests (and the LLM calls they
+    // are waiting on) finish. A second signal skips the rest of the delay.
+    slog.Info("shutdown signal received; draining", "delay", cfg.Server.DrainDelay.String(), "timeout", cfg.Server.ShutdownTimeout.String())
+    ready.SetDraining()
+    again, stopAgain := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    select {
+    case <-time.After(cfg.Server.DrainDelay.Std()):
+    case <-again.Done():
+        slog.Warn("second shutdown signal received; closing the listener now")
+    case err := <-serveErr:
+        stopAgain()
+        return err
+    }
+    stopAgain()
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+    defer cancel()
+
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        return err
+    }
+    if err := llm.Drain(shutdownCtx); err != nil {
+        return errors.New("timed out waiting for in-flight LLM requests")
+    }
+
+    slog.Info("server stopped cleanly")
+    return nil
 }
@@ -0,0 +1,346 @@
+// Package adaptive tailors quizzes to a student. It estimates ability from
+// graded answers with a one-parameter (Rasch) item response model, picks the
+// difficulty whose expected success rate is closest to a target, and spreads
+// questions across topics in favour of the weakest ones.
+package adaptive
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"studyai/internal/config"
+	"studyai/internal/models"
+)
+
+// ErrNoTopics is returned when no topic was requested and the student has no
+// history to choose one from.
+var ErrNoTopics = errors.New("no topic requested and no study history to choose from")
+
+// Levels lists the quiz difficulty levels from easiest to hardest.
+var Levels = []string{"easy", "medium", "hard"}
+
+// itemDifficulty is the Rasch difficulty, in logits, of each level.
+var itemDifficulty = map[string]float64{"easy": -1, "medium": 0, "hard": 1}
+
+// NormalizeLevel maps free-form difficulty labels onto Levels. Unknown or
+// empty labels are treated as medium.
+func NormalizeLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "easy", "low", "beginner":
+		return "easy"
+	case "hard", "high", "advanced":
+		return "hard"
+	default:
+		return "medium"
+	}
+}
+
+// ItemDifficulty returns the Rasch difficulty of a level.
+func ItemDifficulty(level string) float64 {
+	return itemDifficulty[NormalizeLevel(level)]
+}
+
+// Probability is the chance a student of ability theta answers an item of
+// difficulty b correctly.
+func Probability(theta, b float64) float64 {
+	return 1 / (1 + math.Exp(-(theta - b)))
+}
+
+// Response is one graded answer used for estimation.
+type Response struct {
+	Topic      string
+	Difficulty float64
+	Correct    bool
+	Weight     float64 // recency weight in (0, 1]
+}
+
+// Estimate is a maximum a posteriori ability estimate.
+type Estimate struct {
+	Theta float64
+	SE    float64
+	N     int
+}
+
+// EstimateAbility fits theta to rs under a normal prior with the given mean
+// and standard deviation. The prior keeps estimates finite for all-correct or
+// all-wrong histories and shrinks them when there is little data.
+func EstimateAbility(rs []Response, priorMean, priorSD float64) Estimate {
+	precision := 1 / (priorSD * priorSD)
+	score := func(theta float64) (grad, info float64) {
+		grad = -(theta - priorMean) * precision
+		info = precision
+		for _, r := range rs {
+			p := Probability(theta, r.Difficulty)
+			y := 0.0
+			if r.Correct {
+				y = 1
+			}
+			grad += r.Weight * (y - p)
+			info += r.Weight * p * (1 - p)
+		}
+		return grad, info
+	}
+
+	// Newton-Raphson; the log posterior is concave so this converges quickly.
+	theta := priorMean
+	for range 50 {
+		grad, info := score(theta)
+		step := math.Max(-1, math.Min(1, grad/info))
+		theta += step
+		if math.Abs(step) < 1e-6 {
+			break
+		}
+	}
+	theta = math.Max(-4, math.Min(4, theta))
+	_, info := score(theta)
+	return Estimate{Theta: theta, SE: 1 / math.Sqrt(info), N: len(rs)}
+}
+
+// Responses flattens attempts into weighted responses. The most recent
+// attempt has weight 1 and each older one decays so that an answer halfLife
+// attempts back counts half.
+func Responses(attempts []models.QuizAttempt, halfLife float64) []Response {
+	sorted := slices.Clone(attempts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SubmittedAt.After(sorted[j].SubmittedAt)
+	})
+
+	var rs []Response
+	for age, a := range sorted {
+		weight := math.Pow(0.5, float64(age)/halfLife)
+		for _, item := range a.Items {
+			topic, level := item.Topic, item.Difficulty
+			if topic == "" {
+				topic = a.Topic
+			}
+			if level == "" {
+				level = a.Difficulty
+			}
+			rs = append(rs, Response{
+				Topic:      topic,
+				Difficulty: ItemDifficulty(level),
+				Correct:    item.Correct,
+				Weight:     weight,
+			})
+		}
+	}
+	return rs
+}
+
+// ChooseLevel returns the level whose expected success rate for theta is
+// closest to target.
+func ChooseLevel(theta, target float64) string {
+	best, bestGap := "medium", math.Inf(1)
+	for _, level := range Levels {
+		gap := math.Abs(Probability(theta, itemDifficulty[level]) - target)
+		if gap < bestGap {
+			best, bestGap = level, gap
+		}
+	}
+	return best
+}
+
+// MostInformativeLevel returns the level that tells the most about a student
+// of ability theta. Under the Rasch model an item is most informative when its
+// difficulty equals the ability.
+func MostInformativeLevel(theta float64) string {
+	return ChooseLevel(theta, 0.5)
+}
+
+// Stop reasons reported by SessionDone.
+const (
+	StopPrecision    = "precision_reached"
+	StopMaxQuestions = "max_questions"
+)
+
+// SessionDone reports whether an adaptive test may stop after answered
+// questions with the given estimate, and why.
+func SessionDone(est Estimate, answered, minQuestions, maxQuestions int, targetSE float64) (bool, string) {
+	switch {
+	case answered >= maxQuestions:
+		return true, StopMaxQuestions
+	case answered >= minQuestions && est.SE <= targetSE:
+		return true, StopPrecision
+	default:
+		return false, ""
+	}
+}
+
+// Input is what Plan knows about the student and the requested quiz.
+type Input struct {
+	Profile      models.ProgressProfile
+	Attempts     []models.QuizAttempt
+	Topic        string // empty lets the history choose the topics
+	NumQuestions int
+}
+
+// Plan decides the difficulty and topic mix of the next quiz.
+func Plan(cfg config.Adaptive, in Input) (models.AdaptivePlan, error) {
+	rs := Responses(in.Attempts, cfg.HalfLife)
+
+	// Without per-question history, seed the prior from the profile's
+	// average score so returning students do not start from scratch.
+	priorMean := 0.0
+	if len(rs) == 0 && in.Profile.QuizzesAttempted > 0 {
+		p := math.Max(0.05, math.Min(0.95, float64(in.Profile.AverageScore)/100))
+		priorMean = math.Log(p / (1 - p))
+	}
+	global := EstimateAbility(rs, priorMean, 1)
+
+	topics := candidateTopics(in)
+	if len(topics) == 0 {
+		return models.AdaptivePlan{}, ErrNoTopics
+	}
+
+	type candidate struct {
+		name string
+		est  Estimate
+		need float64
+	}
+	var cands []candidate
+	for _, t := range topics {
+		var trs []Response
+		for _, r := range rs {
+			if sameTopic(r.Topic, t) {
+				trs = append(trs, r)
+			}
+		}
+		// Topic abilities are shrunk toward the overall estimate.
+		est := EstimateAbility(trs, global.Theta, 1)
+		need := 1 - Probability(est.Theta, 0)
+		if containsTopic(in.Profile.WeakAreas, t) {
+			need += 0.25
+		}
+		cands = append(cands, candidate{name: t, est: est, need: need})
+	}
+	if in.Topic == "" {
+		sort.SliceStable(cands, func(i, j int) bool { return cands[i].need > cands[j].need })
+		limit := min(cfg.MaxTopics, in.NumQuestions)
+		if len(cands) > limit {
+			cands = cands[:limit]
+		}
+	}
+
+	needs := make([]float64, len(cands))
+	for i, c := range cands {
+		needs[i] = c.need
+	}
+	counts := allocate(in.NumQuestions, needs)
+
+	plan := models.AdaptivePlan{
+		Ability:       round2(global.Theta),
+		StandardError: round2(global.SE),
+		Responses:     global.N,
+		TargetSuccess: cfg.TargetSuccess,
+	}
+	for i, c := range cands {
+		level := ChooseLevel(c.est.Theta, cfg.TargetSuccess)
+		plan.Topics = append(plan.Topics, models.TopicAllocation{
+			Topic:           c.name,
+			Questions:       counts[i],
+			Ability:         round2(c.est.Theta),
+			Difficulty:      level,
+			ExpectedSuccess: round2(Probability(c.est.Theta, itemDifficulty[level])),
+		})
+		switch {
+		case plan.Difficulty == "":
+			plan.Difficulty = level
+		case plan.Difficulty != level:
+			plan.Difficulty = "mixed"
+		}
+	}
+
+	if global.N == 0 {
+		plan.Reason = fmt.Sprintf("No graded answers yet; starting at %s difficulty and refining after the first attempt.", plan.Difficulty)
+	} else {
+		plan.Reason = fmt.Sprintf("Ability %.2f ± %.2f logits from %d graded answers; difficulty chosen for about %.0f%% expected success, with more questions on weaker topics.",
+			global.Theta, global.SE, global.N, cfg.TargetSuccess*100)
+	}
+	return plan, nil
+}
+
+// candidateTopics lists the topics a quiz may draw from, in first-seen order.
+func candidateTopics(in Input) []string {
+	if in.Topic != "" {
+		return []string{in.Topic}
+	}
+	var topics []string
+	add := func(t string) {
+		if t = strings.TrimSpace(t); t != "" && !containsTopic(topics, t) {
+			topics = append(topics, t)
+		}
+	}
+	for _, t := range in.Profile.WeakAreas {
+		add(t)
+	}
+	for _, t := range in.Profile.Topics {
+		add(t)
+	}
+	for _, a := range in.Attempts {
+		for _, item := range a.Items {
+			if item.Topic != "" {
+				add(item.Topic)
+			} else {
+				add(a.Topic)
+			}
+		}
+	}
+	return topics
+}
+
+// allocate splits n questions across weights, giving every entry at least one
+// and distributing the rest by largest remainder.
+func allocate(n int, weights []float64) []int {
+	counts := make([]int, len(weights))
+	if len(weights) == 0 {
+		return counts
+	}
+	var total float64
+	for i, w := range weights {
+		counts[i] = 1
+		total += w
+	}
+	rest := n - len(weights)
+	if rest <= 0 {
+		return counts
+	}
+
+	type share struct {
+		i    int
+		frac float64
+	}
+	shares := make([]share, len(weights))
+	given := 0
+	for i, w := range weights {
+		exact := float64(rest) / float64(len(weights))
+		if total > 0 {
+			exact = float64(rest) * w / total
+		}
+		whole := int(exact)
+		counts[i] += whole
+		given += whole
+		shares[i] = share{i, exact - float64(whole)}
+	}
+	sort.SliceStable(shares, func(a, b int) bool { return shares[a].frac > shares[b].frac })
+	for k := 0; given < rest; k++ {
+		counts[shares[k%len(shares)].i]++
+		given++
+	}
+	return counts
+}
+
+func sameTopic(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func containsTopic(list []string, t string) bool {
+	return slices.ContainsFunc(list, func(s string) bool { return sameTopic(s, t) })
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
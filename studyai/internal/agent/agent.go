@@ -1,36 +1,55 @@
 package agent
 
 import (
+    "context"
+    "log/slog"
     "strings"
 
     "studyai/internal/ai"
+    "studyai/internal/config"
     "studyai/internal/guardrails"
     "studyai/internal/models"
     "studyai/internal/rules"
     "studyai/internal/scoring"
+    "studyai/internal/telemetry"
     "studyai/internal/validation"
 )
 
-func Run(req models.StudyRequest) (models.AgentResponse, error) {
+// Agent evaluates study plans: validate → guardrails → rules → score → explain.
+type Agent struct {
+    llm        *ai.Client
+    guardrails config.Guardrails
+    rules      config.Rules
+}
+
+// New creates an Agent using the given LLM client and thresholds.
+func New(llm *ai.Client, guardrails config.Guardrails, rules config.Rules) *Agent {
+    return &Agent{llm: llm, guardrails: guardrails, rules: rules}
+}
+
+// Run evaluates a study plan. Invalid or refused plans return an
+// *apperr.Error (validation_failed or guardrail_refused).
+func (a *Agent) Run(ctx context.Context, req models.StudyRequest) (models.AgentResponse, error) {
+    ctx, span := telemetry.StartSpan(ctx, "agent.Run")
+    defer span.End()
+
     if err := validation.Validate(req); err != nil {
-        return models.AgentResponse{
-            Decision:   "Refused",
-            Disclaimer: err.Error(),
-        }, nil
+        span.SetAttributes(slog.String("agent.refused", "validation"))
+        span.RecordError(err)
+        return models.AgentResponse{}, err
     }
 
     req.Difficulty = normalizeDifficulty(req.Difficulty)
 
-    if err := guardrails.Check(req); err != nil {
-        return models.AgentResponse{
-            Decision:   "Refused",
-            Disclaimer: err.Error(),
-        }, nil
+    if err := guardrails.Check(a.guardrails, req); err != nil {
+        span.SetAttributes(slog.String("agent.refused", "guardrails"))
+        span.RecordError(err)
+        return models.AgentResponse{}, err
     }
 
-    ruleResult := rules.Apply(req)
+    ruleResult := rules.Apply(a.rules, req)
     score := scoring.Calculate(req, ruleResult)
-    explanation := ai.Explain(req, ruleResult, score)
+    explanation := a.llm.Explain(ctx, req, ruleResult, score)
 
     return models.AgentResponse{
         Decision:    "Study Plan Evaluation",
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"studyai/internal/ai"
+	"studyai/internal/apperr"
+	"studyai/internal/config"
+	"studyai/internal/guardrails"
+	"studyai/internal/media"
+	"studyai/internal/models"
+	"studyai/internal/store"
+	"studyai/internal/telemetry"
+)
+
+// Tutor run outcomes.
+const (
+	outcomeAnswered  = "answered"
+	outcomeStepLimit = "step_limit"
+	outcomeError     = "error"
+)
+
+// Tool call outcomes, as audited.
+const (
+	callOK       = "ok"
+	callError    = "error"
+	callRejected = "rejected"
+)
+
+// maxToolResult bounds how much of one tool result the model is shown, in
+// bytes.
+const maxToolResult = 4000
+
+const tutorInstructions = "You are a patient tutor talking with one student. " +
+	"Use the tools to look up the student's progress, their due flashcards, a practice quiz or an evaluation of a study plan whenever that helps you answer; do not guess at what a tool can tell you. " +
+	"Never reveal the answers to quiz questions or flashcards; help the student work them out. " +
+	"Answer in plain language and keep it short."
+
+// unfinishedReply is the reply when the model used up its steps, or gave an
+// empty answer, without answering.
+const unfinishedReply = "I wasn't able to finish looking into that. Could you ask again, perhaps about one thing at a time?"
+
+// Tutor is a tutor agent that answers a student's message by calling tools
+// through the LLM provider's function-calling protocol, in a loop bounded by
+// config.Tutor. Every run is kept with an audit log of its tool calls.
+type Tutor struct {
+	llm     *ai.Client
+	planner *Agent
+	media   *media.Service
+	cfg     config.Tutor
+	runs    *store.Collection[models.TutorRun]
+	tools   []tool
+}
+
+// NewTutor creates a Tutor whose tools use media and, to evaluate study
+// plans, planner, keeping its runs in st.
+func NewTutor(llm *ai.Client, planner *Agent, m *media.Service, st *store.Store, cfg config.Tutor) (*Tutor, error) {
+	runs, err := store.NewCollection[models.TutorRun](st, "tutor_runs")
+	if err != nil {
+		return nil, err
+	}
+	t := &Tutor{llm: llm, planner: planner, media: m, cfg: cfg, runs: runs}
+	t.tools = t.toolset()
+	return t, nil
+}
+
+// Run answers a student's message. Each step the model either answers or
+// asks for tool calls, whose results it sees the next step; the last step
+// offers no tools, so it has to answer. Tool failures are reported to the
+// model rather than failing the run. The run is saved, and returned, even
+// when the LLM fails; that error is returned as well.
+func (t *Tutor) Run(ctx context.Context, req models.TutorRequest) (models.TutorRun, error) {
+	ctx, span := telemetry.StartSpan(ctx, "agent.Tutor",
+		slog.String("tutor.student", req.StudentID),
+	)
+	defer span.End()
+
+	if err := guardrails.Tutor(t.media.InAssessment(req.StudentID)); err != nil {
+		span.SetAttributes(slog.String("agent.refused", "guardrails"))
+		span.RecordError(err)
+		return models.TutorRun{}, err
+	}
+
+	run := models.TutorRun{
+		ID:        newRunID(),
+		StudentID: req.StudentID,
+		Message:   req.Message,
+		ToolCalls: []models.ToolCallAudit{},
+		StartedAt: time.Now().UTC(),
+	}
+	messages := []ai.Message{
+		{Role: "system", Content: tutorInstructions},
+		{Role: "user", Content: req.Message},
+	}
+	specs := make([]ai.Tool, len(t.tools))
+	for i, tl := range t.tools {
+		specs[i] = ai.Tool{Type: "function", Function: tl.spec}
+	}
+
+	for run.Outcome == "" && run.Steps < t.cfg.MaxSteps {
+		run.Steps++
+		offered := specs
+		if run.Steps == t.cfg.MaxSteps || len(run.ToolCalls) >= t.cfg.MaxToolCalls {
+			offered = nil
+		}
+		reply, err := t.llm.Converse(ctx, "tutor", messages, offered)
+		if err != nil {
+			span.RecordError(err)
+			run.Outcome, run.Error = outcomeError, err.Error()
+			if saveErr := t.save(&run); saveErr != nil {
+				slog.ErrorContext(ctx, "could not save failed tutor run", "run", run.ID, "err", saveErr)
+			}
+			return run, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			run.Reply, run.Outcome = strings.TrimSpace(reply.Content), outcomeAnswered
+			break
+		}
+
+		messages = append(messages, reply)
+		for _, call := range reply.ToolCalls {
+			// Calls past the limit, or asked for when no tools were offered,
+			// are answered without running them.
+			allowed := offered != nil && len(run.ToolCalls) < t.cfg.MaxToolCalls
+			result, audit := t.call(ctx, req.StudentID, call, run.Steps, allowed)
+			run.ToolCalls = append(run.ToolCalls, audit)
+			messages = append(messages, ai.Message{Role: "tool", ToolCallID: call.ID, Content: result})
+		}
+	}
+
+	if run.Outcome == "" {
+		run.Outcome = outcomeStepLimit
+	}
+	if run.Reply == "" {
+		telemetry.FallbackActivations.Inc("tutor", run.Outcome)
+		run.Reply = unfinishedReply
+	}
+	if err := t.save(&run); err != nil {
+		return run, apperr.Wrap(apperr.CodeInternal, "failed to save tutor run", err)
+	}
+	slog.InfoContext(ctx, "tutor run finished", "run", run.ID, "outcome", run.Outcome, "steps", run.Steps, "tool_calls", len(run.ToolCalls))
+	return run, nil
+}
+
+// ListRuns returns the tutor runs, newest first, optionally only a
+// student's.
+func (t *Tutor) ListRuns(studentID string) models.TutorRunList {
+	runs := t.runs.Filter(func(r models.TutorRun) bool { return studentID == "" || r.StudentID == studentID })
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return models.TutorRunList{Total: len(runs), Runs: runs}
+}
+
+// GetRun returns one tutor run with its audit log.
+func (t *Tutor) GetRun(id string) (models.TutorRun, error) {
+	run, ok := t.runs.Get(id)
+	if !ok {
+		return run, apperr.New(apperr.CodeNotFound, "tutor run not found")
+	}
+	return run, nil
+}
+
+// call runs one tool call for studentID, unless it is not allowed, and
+// returns what to tell the model along with the call's audit record.
+func (t *Tutor) call(ctx context.Context, studentID string, call ai.ToolCall, step int, allowed bool) (string, models.ToolCallAudit) {
+	audit := models.ToolCallAudit{Step: step, Tool: call.Function.Name, Arguments: call.Function.Arguments, At: time.Now().UTC()}
+	start := time.Now()
+
+	var result any
+	var err error
+	i := slices.IndexFunc(t.tools, func(tl tool) bool { return tl.spec.Name == call.Function.Name })
+	switch {
+	case !allowed:
+		audit.Outcome = callRejected
+		err = errors.New("tool call limit reached; answer with what you already know")
+	case i < 0:
+		err = fmt.Errorf("unknown tool %q", call.Function.Name)
+	default:
+		result, err = t.tools[i].run(ctx, studentID, call.Function.Arguments)
+	}
+	audit.DurationMS = time.Since(start).Milliseconds()
+
+	var content []byte
+	if err == nil {
+		audit.Outcome = callOK
+		content, err = json.Marshal(result)
+	}
+	if err != nil {
+		if audit.Outcome != callRejected {
+			audit.Outcome = callError
+		}
+		audit.Error = toolError(err)
+		content, _ = json.Marshal(map[string]string{"error": audit.Error})
+	}
+	audit.Result = truncate(string(content), maxToolResult)
+
+	label := audit.Tool
+	if i < 0 {
+		label = "unknown"
+	}
+	telemetry.TutorToolCalls.Inc(label, audit.Outcome)
+	slog.InfoContext(ctx, "tutor tool call", "tool", audit.Tool, "outcome", audit.Outcome, "duration_ms", audit.DurationMS)
+	return audit.Result, audit
+}
+
+// save stamps the run's finish time and stores it.
+func (t *Tutor) save(run *models.TutorRun) error {
+	run.FinishedAt = time.Now().UTC()
+	return t.runs.Put(run.ID, *run)
+}
+
+// toolError is the message a failed tool call reports: an *apperr.Error's
+// message and field errors without its cause, which may be internal.
+func toolError(err error) string {
+	var ae *apperr.Error
+	if !errors.As(err, &ae) {
+		return err.Error()
+	}
+	msg := ae.Message
+	for _, f := range ae.Fields {
+		msg += "; " + f.Field + ": " + f.Message
+	}
+	return msg
+}
+
+// truncate shortens s to at most n bytes, marking that it was cut.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "…(truncated)"
+}
+
+// newRunID returns a unique tutor run identifier such as
+// "tutor_1718000000_9f2c4a1b".
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("tutor_%d_%s", time.Now().Unix(), hex.EncodeToString(b))
+}
@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"studyai/internal/ai"
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// Bounds on what the model may ask the tools for.
+const (
+	maxTutorQuizQuestions = 10
+	maxTutorFlashcards    = 20
+)
+
+// tool is a function the tutor model may call. run gets the arguments as the
+// model sent them and acts only on the student the tutor is talking with.
+type tool struct {
+	spec ai.FunctionSpec
+	run  func(ctx context.Context, studentID, args string) (any, error)
+}
+
+// toolset returns the tutor's tools.
+func (t *Tutor) toolset() []tool {
+	return []tool{
+		{
+			spec: ai.FunctionSpec{
+				Name:        "get_progress",
+				Description: "Look up the student's progress: topics studied, weak areas, quiz statistics, topic mastery and recurring misconceptions.",
+				Parameters:  object(nil),
+			},
+			run: t.getProgress,
+		},
+		{
+			spec: ai.FunctionSpec{
+				Name:        "generate_quiz",
+				Description: "Create a practice quiz for the student on a topic. Returns the quiz ID and questions without their answers; the student takes it in the app.",
+				Parameters: object(map[string]any{
+					"topic":         map[string]any{"type": "string", "description": "what the quiz covers"},
+					"num_questions": map[string]any{"type": "integer", "minimum": 1, "maximum": maxTutorQuizQuestions, "description": "defaults to 5"},
+					"difficulty":    map[string]any{"type": "string", "enum": []string{"easy", "medium", "hard"}},
+				}, "topic"),
+			},
+			run: t.generateQuiz,
+		},
+		{
+			spec: ai.FunctionSpec{
+				Name:        "get_due_flashcards",
+				Description: "List the flashcards the student should review today, most overdue first, without their answers.",
+				Parameters: object(map[string]any{
+					"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": maxTutorFlashcards, "description": "defaults to 10"},
+				}),
+			},
+			run: t.dueFlashcards,
+		},
+		{
+			spec: ai.FunctionSpec{
+				Name:        "evaluate_study_plan",
+				Description: "Score a study plan for risk of burnout or under-preparation and explain the result.",
+				Parameters: object(map[string]any{
+					"goal":            map[string]any{"type": "string", "description": "what the student is studying for"},
+					"available_hours": map[string]any{"type": "integer", "minimum": 1, "description": "total hours available across the whole duration"},
+					"duration_days":   map[string]any{"type": "integer", "minimum": 1},
+					"difficulty":      map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}},
+				}, "goal", "available_hours", "duration_days", "difficulty"),
+			},
+			run: t.evaluatePlan,
+		},
+	}
+}
+
+func (t *Tutor) getProgress(_ context.Context, studentID, _ string) (any, error) {
+	return t.media.GetStudentProgress(studentID)
+}
+
+// tutorQuiz is a generated quiz as the model sees it: without answer keys.
+type tutorQuiz struct {
+	QuizID     string              `json:"quiz_id"`
+	Topic      string              `json:"topic"`
+	Difficulty string              `json:"difficulty"`
+	Questions  []tutorQuizQuestion `json:"questions"`
+}
+
+type tutorQuizQuestion struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type,omitempty"`
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+func (t *Tutor) generateQuiz(ctx context.Context, studentID, args string) (any, error) {
+	var in struct {
+		Topic        string `json:"topic"`
+		NumQuestions int    `json:"num_questions"`
+		Difficulty   string `json:"difficulty"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	in.Topic = strings.TrimSpace(in.Topic)
+	var fields []apperr.FieldError
+	if in.Topic == "" || len(in.Topic) > 200 {
+		fields = append(fields, apperr.FieldError{Field: "topic", Message: "must be 1 to 200 characters"})
+	}
+	if in.NumQuestions == 0 {
+		in.NumQuestions = 5
+	}
+	if in.NumQuestions < 1 || in.NumQuestions > maxTutorQuizQuestions {
+		fields = append(fields, apperr.FieldError{Field: "num_questions", Message: "must be between 1 and 10"})
+	}
+	if in.Difficulty != "" && !slices.Contains([]string{"easy", "medium", "hard"}, in.Difficulty) {
+		fields = append(fields, apperr.FieldError{Field: "difficulty", Message: "must be easy, medium or hard"})
+	}
+	if len(fields) > 0 {
+		return nil, apperr.Validation(fields...)
+	}
+
+	quiz, err := t.media.GenerateQuiz(ctx, models.QuizRequest{
+		TopicName:    in.Topic,
+		NumQuestions: in.NumQuestions,
+		Difficulty:   in.Difficulty,
+		StudentID:    studentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := tutorQuiz{QuizID: quiz.QuizID, Topic: quiz.Topic, Difficulty: quiz.Difficulty, Questions: []tutorQuizQuestion{}}
+	for _, q := range quiz.Questions {
+		out.Questions = append(out.Questions, tutorQuizQuestion{ID: q.ID, Type: q.Type, Question: q.Question, Options: q.Options})
+	}
+	return out, nil
+}
+
+// tutorFlashcard is a due flashcard as the model sees it: without its back.
+type tutorFlashcard struct {
+	ID     string    `json:"id"`
+	Topic  string    `json:"topic"`
+	Front  string    `json:"front"`
+	DueAt  time.Time `json:"due_at"`
+	Lapses int       `json:"lapses"`
+}
+
+func (t *Tutor) dueFlashcards(_ context.Context, studentID, args string) (any, error) {
+	var in struct {
+		Limit int `json:"limit"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Limit < 1 {
+		in.Limit = 10
+	}
+	due := t.media.DueFlashcards(studentID, min(in.Limit, maxTutorFlashcards))
+	cards := []tutorFlashcard{}
+	for _, c := range due.Cards {
+		cards = append(cards, tutorFlashcard{ID: c.ID, Topic: c.Topic, Front: c.Front, DueAt: c.DueAt, Lapses: c.Lapses})
+	}
+	return map[string]any{"total_due": due.Total, "cards": cards}, nil
+}
+
+func (t *Tutor) evaluatePlan(ctx context.Context, _, args string) (any, error) {
+	var req models.StudyRequest
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, err
+	}
+	return t.planner.Run(ctx, req)
+}
+
+// decodeArgs parses a tool call's JSON arguments; the model may send none.
+func decodeArgs(args string, v any) error {
+	if strings.TrimSpace(args) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(args), v); err != nil {
+		return apperr.Wrap(apperr.CodeInvalidRequest, "arguments must be a JSON object matching the tool's parameters", err)
+	}
+	return nil
+}
+
+// object is a JSON Schema object with the given properties.
+func object(properties map[string]any, required ...string) map[string]any {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -1,11 +1,13 @@
 package ai
 
 import (
+    "context"
     "fmt"
     "studyai/internal/models"
+    "studyai/internal/telemetry"
 )
 
-func Explain(req models.StudyRequest, result models.RuleResult, score int) string {
+func (c *Client) Explain(ctx context.Context, req models.StudyRequest, result models.RuleResult, score int) string {
     prompt := fmt.Sprintf(
         `
 A student submitted a study plan.
@@ -41,9 +43,10 @@ Rules:
         result.Issues,
     )
 
-    explanation, err := callLLM(prompt)
+    explanation, err := c.Call(ctx, "explain", prompt)
     if err != nil {
         // graceful fallback = huge plus for judges
+        telemetry.FallbackActivations.Inc("explain", "llm_error")
         return "The study plan was evaluated using predefined rules and scoring logic. Some risks were identified, and results are advisory only."
     }
 
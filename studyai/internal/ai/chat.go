@@ -1,8 +1,10 @@
 package ai
 
+import "context"
+
 // Chat sends a raw user message to the configured LLM and returns the reply.
-// It reuses the existing callLLM helper in this package.
-func Chat(message string) (string, error) {
+// It reuses the existing Call helper in this package.
+func (c *Client) Chat(ctx context.Context, message string) (string, error) {
     prompt := "User: " + message + "\n\nRespond concisely. Mention uncertainty and do not guarantee outcomes." 
-    return callLLM(prompt)
+    return c.Call(ctx, "chat", prompt)
 }
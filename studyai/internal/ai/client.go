@@ -100,93 +100,226 @@ package ai
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "errors"
     "fmt"
-    "log"
+    "log/slog"
     "net/http"
-    "os"
+    "strings"
+    "sync"
     "time"
-)
 
-const groqURL = "https://api.groq.com/openai/v1/chat/completions"
+    "studyai/internal/apperr"
+    "studyai/internal/config"
+    "studyai/internal/telemetry"
+)
 
 type chatRequest struct {
     Model    string        `json:"model"`
-    Messages []chatMessage `json:"messages"`
-    Temperature float32   `json:"temperature,omitempty"`
-}
-
-type chatMessage struct {
-    Role    string `json:"role"`
-    Content string `json:"content"`
+    Messages []Message     `json:"messages"`
+    Temperature float64   `json:"temperature,omitempty"`
+    Tools    []Tool        `json:"tools,omitempty"`
 }
 
 type chatResponse struct {
     Choices []struct {
-        Message chatMessage `json:"message"`
+        Message Message `json:"message"`
     } `json:"choices"`
 }
 
-func callLLM(prompt string) (string, error) {
-    apiKey := os.Getenv("GROQ_API_KEY")
-    if apiKey == "" {
-        log.Println("GROQ_API_KEY not set; LLM call will fail and caller should fallback")
-        return "", errors.New("GROQ_API_KEY not set")
+// Message is one turn of a conversation in the provider's function-calling
+// protocol. An assistant message either has Content or asks for ToolCalls; a
+// "tool" message answers the call named by ToolCallID.
+type Message struct {
+    Role       string     `json:"role"`
+    Content    string     `json:"content"`
+    ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+    ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is the model's request to call one tool.
+type ToolCall struct {
+    ID       string       `json:"id"`
+    Type     string       `json:"type"` // always "function"
+    Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the function to call; Arguments is a JSON object.
+type FunctionCall struct {
+    Name      string `json:"name"`
+    Arguments string `json:"arguments"`
+}
+
+// Tool offers the model a function it may call.
+type Tool struct {
+    Type     string       `json:"type"` // always "function"
+    Function FunctionSpec `json:"function"`
+}
+
+// FunctionSpec describes a callable function; Parameters is a JSON Schema
+// object.
+type FunctionSpec struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+    Parameters  any    `json:"parameters"`
+}
+
+// Client talks to an OpenAI-compatible chat-completions provider.
+type Client struct {
+    cfg  config.LLM
+    http *http.Client
+
+    // inFlight tracks LLM calls that have not returned yet so shutdown can drain them.
+    inFlight sync.WaitGroup
+}
+
+// NewClient creates a Client for the given provider settings.
+func NewClient(cfg config.LLM) *Client {
+    return &Client{
+        cfg:  cfg,
+        http: &http.Client{Timeout: cfg.Timeout.Std()},
+    }
+}
+
+// Call sends prompt to the provider. feature names the calling use case
+// (e.g. "quiz.generate") and is used to label latency and error metrics.
+// Failures are *apperr.Error values coded llm_unavailable or quota_exceeded.
+func (c *Client) Call(ctx context.Context, feature, prompt string) (string, error) {
+    reply, err := c.Converse(ctx, feature, []Message{{Role: "user", Content: prompt}}, nil)
+    if err != nil {
+        return "", err
+    }
+    return reply.Content, nil
+}
+
+// Converse sends a conversation to the provider, offering it tools it may
+// call instead of answering. The reply is the assistant's next message:
+// either an answer in Content or a request for ToolCalls, whose results the
+// caller appends as "tool" messages before conversing again. feature and
+// failures are as for Call.
+func (c *Client) Converse(ctx context.Context, feature string, messages []Message, tools []Tool) (Message, error) {
+    c.inFlight.Add(1)
+    defer c.inFlight.Done()
+    telemetry.LLMRequestsInFlight.Add(1)
+    defer telemetry.LLMRequestsInFlight.Add(-1)
+
+    ctx, span := telemetry.StartSpan(ctx, "ai.Call",
+        slog.String("llm.provider", c.cfg.Provider),
+        slog.String("llm.feature", feature),
+    )
+    defer span.End()
+
+    start := time.Now()
+    reply, err := c.do(ctx, messages, tools)
+    telemetry.LLMRequestDuration.Observe(time.Since(start).Seconds(), c.cfg.Provider, feature)
+    if err != nil {
+        telemetry.LLMRequests.Inc(c.cfg.Provider, feature, "error")
+        span.RecordError(err)
+        return Message{}, err
+    }
+    telemetry.LLMRequests.Inc(c.cfg.Provider, feature, "ok")
+    return reply, nil
+}
+
+// CallJSON calls the LLM and requests JSON-formatted output
+func (c *Client) CallJSON(ctx context.Context, feature, prompt string) (string, error) {
+    jsonPrompt := prompt + "\n\nReturn ONLY valid JSON, no additional text."
+    return c.Call(ctx, feature, jsonPrompt)
+}
+
+func (c *Client) do(ctx context.Context, messages []Message, tools []Tool) (Message, error) {
+    if c.cfg.APIKey == "" {
+        slog.WarnContext(ctx, "LLM API key not set; LLM call will fail and caller should fallback")
+        return Message{}, apperr.New(apperr.CodeLLMUnavailable, "LLM API key not set")
     }
 
     reqBody := chatRequest{
-        Model: "llama-3.1-8b-instant",
-        Temperature: 0.3, // low randomness = safer explanations
-        Messages: []chatMessage{
+        Model: c.cfg.Model,
+        Temperature: c.cfg.Temperature,
+        Messages: append([]Message{
             {
                 Role: "system",
                 Content: "You are an educational advisory AI. You must explain decisions clearly, mention uncertainty, and never guarantee outcomes.",
             },
-            {
-                Role:    "user",
-                Content: prompt,
-            },
-        },
+        }, messages...),
+        Tools: tools,
     }
 
     bodyBytes, _ := json.Marshal(reqBody)
 
-    req, _ := http.NewRequest("POST", groqURL, bytes.NewBuffer(bodyBytes))
-    req.Header.Set("Authorization", "Bearer "+apiKey)
+    req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint("/chat/completions"), bytes.NewBuffer(bodyBytes))
+    if err != nil {
+        return Message{}, err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey.Value())
     req.Header.Set("Content-Type", "application/json")
 
-    client := &http.Client{Timeout: 10 * time.Second}
-    resp, err := client.Do(req)
+    resp, err := c.http.Do(req)
     if err != nil {
-        return "", err
+        return Message{}, apperr.Wrap(apperr.CodeLLMUnavailable, "LLM provider unreachable", err)
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return Message{}, apperr.New(apperr.CodeQuotaExceeded, "LLM provider quota exceeded; try again later")
+    }
     if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-        return "", fmt.Errorf("LLM API returned status %d", resp.StatusCode)
+        return Message{}, apperr.Wrap(apperr.CodeLLMUnavailable, "LLM provider returned an error", fmt.Errorf("status %d", resp.StatusCode))
     }
 
     var parsed chatResponse
     if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-        return "", err
+        return Message{}, apperr.Wrap(apperr.CodeLLMUnavailable, "LLM provider returned an invalid response", err)
     }
 
     if len(parsed.Choices) == 0 {
-        return "", errors.New("no response from Groq")
+        return Message{}, apperr.New(apperr.CodeLLMUnavailable, fmt.Sprintf("no response from %s", c.cfg.Provider))
     }
 
-    return parsed.Choices[0].Message.Content, nil
+    return parsed.Choices[0].Message, nil
 }
 
-// CallLLM is the public wrapper for callLLM - allows other packages to use the LLM
-func CallLLM(prompt string) (string, error) {
-    return callLLM(prompt)
+func (c *Client) endpoint(path string) string {
+    return strings.TrimRight(c.cfg.BaseURL, "/") + path
 }
 
-// CallLLMJSON calls the LLM and requests JSON-formatted output
-func CallLLMJSON(prompt string) (string, error) {
-    jsonPrompt := prompt + "\n\nReturn ONLY valid JSON, no additional text."
-    return callLLM(jsonPrompt)
+// Drain blocks until every in-flight LLM call has returned or ctx is done.
+func (c *Client) Drain(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        c.inFlight.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
 }
 
+// Ping checks that the LLM provider is configured and reachable by listing
+// the available models.
+func (c *Client) Ping(ctx context.Context) error {
+    if c.cfg.APIKey == "" {
+        return errors.New("LLM API key not set")
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/models"), nil)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey.Value())
+
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("LLM API returned status %d", resp.StatusCode)
+    }
+    return nil
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/config"
+	"studyai/internal/telemetry"
+)
+
+// embeddingProvider labels embedding calls in the LLM metrics.
+const embeddingProvider = "embeddings"
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embedder turns text into vectors with an OpenAI-compatible embeddings API,
+// such as a local model server.
+type Embedder struct {
+	cfg  config.Retrieval
+	http *http.Client
+}
+
+// NewEmbedder creates an Embedder for the given retrieval settings.
+func NewEmbedder(cfg config.Retrieval) *Embedder {
+	return &Embedder{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout.Std()},
+	}
+}
+
+// Enabled reports whether an embedding model is configured.
+func (e *Embedder) Enabled() bool {
+	return e != nil && e.cfg.EmbeddingBaseURL != "" && e.cfg.EmbeddingModel != ""
+}
+
+// Embed returns one vector per text, in order. Failures are *apperr.Error
+// values coded llm_unavailable.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if !e.Enabled() {
+		return nil, apperr.New(apperr.CodeLLMUnavailable, "no embedding model configured")
+	}
+	ctx, span := telemetry.StartSpan(ctx, "ai.Embed")
+	defer span.End()
+
+	start := time.Now()
+	vectors, err := e.do(ctx, texts)
+	telemetry.LLMRequestDuration.Observe(time.Since(start).Seconds(), embeddingProvider, "embed")
+	if err != nil {
+		telemetry.LLMRequests.Inc(embeddingProvider, "embed", "error")
+		span.RecordError(err)
+		return nil, err
+	}
+	telemetry.LLMRequests.Inc(embeddingProvider, "embed", "ok")
+	return vectors, nil
+}
+
+func (e *Embedder) do(ctx context.Context, texts []string) ([][]float64, error) {
+	body, _ := json.Marshal(embeddingRequest{Model: e.cfg.EmbeddingModel, Input: texts})
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(e.cfg.EmbeddingBaseURL, "/")+"/embeddings", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.EmbeddingAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.EmbeddingAPIKey.Value())
+	}
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.CodeLLMUnavailable, "embedding provider unreachable", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, apperr.Wrap(apperr.CodeLLMUnavailable, "embedding provider returned an error", fmt.Errorf("status %d", resp.StatusCode))
+	}
+
+	var parsed embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, apperr.Wrap(apperr.CodeLLMUnavailable, "embedding provider returned an invalid response", err)
+	}
+	vectors := make([][]float64, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return nil, apperr.New(apperr.CodeLLMUnavailable, "embedding provider returned too few vectors")
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/config"
+)
+
+// requireAdmin rejects requests without the configured admin bearer token.
+// When no token is configured the admin endpoints are open (development).
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearer("admin", next, s.cfg.Server.AdminToken)
+}
+
+// requireTeacher protects teacher endpoints such as question bank editing. The
+// teacher token or the admin token is accepted; with neither configured the
+// endpoints are open (development).
+func (s *Server) requireTeacher(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearer("teacher", next, s.cfg.Server.TeacherToken, s.cfg.Server.AdminToken)
+}
+
+// isTeacher reports whether r carries the teacher or admin token, for
+// endpoints open to everyone where some options are for teachers only.
+func (s *Server) isTeacher(r *http.Request) bool {
+	return bearerMatches(r, s.cfg.Server.TeacherToken, s.cfg.Server.AdminToken)
+}
+
+// requireBearer accepts a request whose bearer token matches any of the
+// non-empty tokens. When every token is empty the check is skipped.
+func requireBearer(role string, next http.HandlerFunc, tokens ...config.Secret) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !bearerMatches(r, tokens...) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, apperr.New(apperr.CodeUnauthorized, "missing or invalid "+role+" token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bearerMatches reports whether r's bearer token matches any of the
+// non-empty tokens, or whether every token is empty.
+func bearerMatches(r *http.Request, tokens ...config.Secret) bool {
+	got := []byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	configured, ok := false, false
+	for _, t := range tokens {
+		if want := t.Value(); want != "" {
+			configured = true
+			ok = ok || subtle.ConstantTimeCompare(got, []byte(want)) == 1
+		}
+	}
+	return !configured || ok
+}
+
+// AdminConfigHandler returns the effective configuration with secrets redacted.
+func (s *Server) AdminConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, s.cfg)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/media"
+	"studyai/internal/models"
+)
+
+// defaultAttemptLimit is how many attempts a history request returns when no
+// limit is given.
+const defaultAttemptLimit = 50
+
+// attemptFilter reads the attempt history query parameters.
+func attemptFilter(r *http.Request) (media.AttemptFilter, error) {
+	f := media.AttemptFilter{
+		Topic:    r.URL.Query().Get("topic"),
+		MinScore: intQuery(r, "min_score", 0),
+		MaxScore: intQuery(r, "max_score", 100),
+		Limit:    intQuery(r, "limit", defaultAttemptLimit),
+	}
+	var fields []apperr.FieldError
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+		end  bool
+	}{{"from", &f.From, false}, {"to", &f.To, true}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseDateQuery(v, p.end)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: p.name, Message: "must be a date (YYYY-MM-DD) or an RFC 3339 time"})
+			continue
+		}
+		*p.dst = t
+	}
+	if f.MaxScore < f.MinScore {
+		fields = append(fields, apperr.FieldError{Field: "max_score", Message: "must not be less than min_score"})
+	}
+	if len(fields) > 0 {
+		return f, apperr.Validation(fields...)
+	}
+	return f, nil
+}
+
+// parseDateQuery parses an RFC 3339 time or a plain date. A plain date means
+// the start of that day (UTC), or its last instant when end is set.
+func parseDateQuery(v string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return t, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// ListAttemptsHandler lists a student's quiz attempts
+func (s *Server) ListAttemptsHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := attemptFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, s.media.ListAttempts(r.PathValue("studentID"), f))
+}
+
+// GetAttemptHandler returns one recorded attempt in full
+func (s *Server) GetAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	attempt, err := s.media.GetAttempt(r.PathValue("attemptID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, attempt)
+}
+
+// RetakeQuizHandler creates a fresh copy of a previous quiz
+func (s *Server) RetakeQuizHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RetakeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	quiz, err := s.media.RetakeQuiz(r.PathValue("quizID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, quiz)
+}
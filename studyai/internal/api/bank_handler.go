@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"studyai/internal/apperr"
+	"studyai/internal/bank"
+	"studyai/internal/media"
+	"studyai/internal/models"
+)
+
+// bankFilter reads the question bank filter query parameters.
+func bankFilter(r *http.Request) media.BankFilter {
+	q := r.URL.Query()
+	return media.BankFilter{
+		Topic:      q.Get("topic"),
+		Tag:        q.Get("tag"),
+		Difficulty: q.Get("difficulty"),
+		Source:     q.Get("source"),
+	}
+}
+
+// bankFormat returns the validated format query parameter, defaulting to JSON.
+func bankFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	return bank.FormatJSON
+}
+
+// ListBankQuestionsHandler lists question bank entries matching the filters
+func (s *Server) ListBankQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.ListBankQuestions(bankFilter(r)))
+}
+
+// GetBankQuestionHandler returns one question bank entry
+func (s *Server) GetBankQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := s.media.GetBankQuestion(r.PathValue("questionID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, q)
+}
+
+// CreateBankQuestionHandler adds a question to the bank
+func (s *Server) CreateBankQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BankQuestion
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q, err := s.media.CreateBankQuestion(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, q)
+}
+
+// UpdateBankQuestionHandler replaces a question bank entry
+func (s *Server) UpdateBankQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BankQuestion
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q, err := s.media.UpdateBankQuestion(r.PathValue("questionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, q)
+}
+
+// DeleteBankQuestionHandler removes a question from the bank
+func (s *Server) DeleteBankQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.media.DeleteBankQuestion(r.PathValue("questionID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, models.StatusResponse{Status: "success", Message: "Question deleted"})
+}
+
+// readUpload reads an uploaded file sent as the raw request body.
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, apperr.New(apperr.CodePayloadTooLarge, "request body exceeds "+strconv.Itoa(maxBodyBytes)+" bytes")
+		}
+		return nil, apperr.Wrap(apperr.CodeInvalidRequest, "failed to read request body", err)
+	}
+	return data, nil
+}
+
+// ImportBankQuestionsHandler adds or updates bank entries from an uploaded file
+func (s *Server) ImportBankQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := s.media.ImportBankQuestions(bankFormat(r), data)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// ExportBankQuestionsHandler downloads bank entries in the requested format
+func (s *Server) ExportBankQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	format := bankFormat(r)
+	data, err := s.media.ExportBankQuestions(format, bankFilter(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ext := format
+	if format == bank.FormatQTI {
+		ext = "xml"
+	}
+	w.Header().Set("Content-Type", bank.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="questions.`+ext+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// ListCourseMaterialsHandler lists the course materials without their text
+func (s *Server) ListCourseMaterialsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.ListCourseMaterials())
+}
+
+// GetCourseMaterialHandler returns one course material with its text
+func (s *Server) GetCourseMaterialHandler(w http.ResponseWriter, r *http.Request) {
+	m, err := s.media.GetCourseMaterial(r.PathValue("materialID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, m)
+}
+
+// CreateCourseMaterialHandler chunks, indexes and stores a course material
+func (s *Server) CreateCourseMaterialHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CourseMaterial
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	m, err := s.media.CreateCourseMaterial(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, m)
+}
+
+// DeleteCourseMaterialHandler removes a course material and its passages
+func (s *Server) DeleteCourseMaterialHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.media.DeleteCourseMaterial(r.PathValue("materialID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, models.StatusResponse{Status: "success", Message: "Course material deleted"})
+}
+
+// SearchCourseMaterialsHandler returns the passages retrieved for a query
+func (s *Server) SearchCourseMaterialsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.SearchPassages(r.Context(), r.URL.Query().Get("q")))
+}
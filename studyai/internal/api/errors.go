@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/telemetry"
+)
+
+// ErrorResponse is the JSON envelope returned for every failed request.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes the failure. Code is stable and machine-readable;
+// Message is for humans; Details lists invalid fields for validation errors.
+type ErrorBody struct {
+	Code      apperr.Code         `json:"code" validate:"enum=invalid_request|validation_failed|guardrail_refused|not_found|method_not_allowed|conflict|unauthorized|payload_too_large|llm_unavailable|ocr_failed|quota_exceeded|internal"`
+	Message   string              `json:"message"`
+	Details   []apperr.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// writeError renders err as an ErrorResponse. Errors that are not
+// *apperr.Error are reported as internal without leaking their text.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := apperr.From(err)
+	status := e.HTTPStatus()
+
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(r.Context(), level, "request failed", "code", e.Code, "status", status, "err", err)
+
+	writeJSON(w, r, status, ErrorResponse{Error: ErrorBody{
+		Code:      e.Code,
+		Message:   e.Message,
+		Details:   e.Fields,
+		RequestID: telemetry.RequestID(r.Context()),
+	}})
+}
+
+// writeJSON encodes v with the given status code.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "encode response error", "err", err)
+	}
+}
+
+// decodeJSON decodes the request body into v, reporting malformed JSON as
+// invalid_request.
+func decodeJSON(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return apperr.Wrap(apperr.CodeInvalidRequest, "invalid request body", err)
+	}
+	return nil
+}
+
+// withErrorEnvelope converts plain-text error responses produced outside our
+// handlers (e.g. the mux's 404 and 405 replies) into the JSON envelope.
+func withErrorEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&envelopeWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+type envelopeWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	intercepted bool
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (e *envelopeWriter) WriteHeader(status int) {
+	if e.wroteHeader {
+		return
+	}
+	e.wroteHeader = true
+	if status >= 400 && strings.HasPrefix(e.Header().Get("Content-Type"), "text/plain") {
+		e.intercepted = true
+		e.status = status
+		return
+	}
+	e.ResponseWriter.WriteHeader(status)
+}
+
+func (e *envelopeWriter) Write(b []byte) (int, error) {
+	if !e.wroteHeader {
+		e.WriteHeader(http.StatusOK)
+	}
+	if !e.intercepted {
+		return e.ResponseWriter.Write(b)
+	}
+	e.body.Write(b)
+	if bytes.HasSuffix(b, []byte("\n")) {
+		e.flushEnvelope()
+	}
+	return len(b), nil
+}
+
+func (e *envelopeWriter) flushEnvelope() {
+	if !e.intercepted {
+		return
+	}
+	e.intercepted = false
+
+	code := apperr.CodeInternal
+	switch e.status {
+	case http.StatusNotFound:
+		code = apperr.CodeNotFound
+	case http.StatusMethodNotAllowed:
+		code = apperr.CodeMethodNotAllowed
+	case http.StatusUnauthorized:
+		code = apperr.CodeUnauthorized
+	case http.StatusRequestEntityTooLarge:
+		code = apperr.CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		code = apperr.CodeQuotaExceeded
+	default:
+		if e.status < http.StatusInternalServerError {
+			code = apperr.CodeInvalidRequest
+		}
+	}
+
+	e.Header().Del("X-Content-Type-Options")
+	e.Header().Del("Content-Length")
+	writeJSON(e.ResponseWriter, e.r, e.status, ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   strings.TrimSpace(e.body.String()),
+		RequestID: telemetry.RequestID(e.r.Context()),
+	}})
+}
+
+func (e *envelopeWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"studyai/internal/models"
+)
+
+// Defaults for the flashcard query parameters.
+const (
+	defaultDueLimit     = 50
+	defaultForecastDays = 14
+)
+
+// DueFlashcardsHandler lists the flashcards a student should review today
+func (s *Server) DueFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := intQuery(r, "limit", defaultDueLimit)
+	writeJSON(w, r, http.StatusOK, s.media.DueFlashcards(r.PathValue("studentID"), limit))
+}
+
+// ReviewFlashcardHandler records a recall grade and reschedules the card
+func (s *Server) ReviewFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.FlashcardReviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	card, err := s.media.ReviewFlashcard(r.PathValue("studentID"), r.PathValue("cardID"), req.Grade)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, card)
+}
+
+// ReviewForecastHandler reports how many cards fall due on each upcoming day
+func (s *Server) ReviewForecastHandler(w http.ResponseWriter, r *http.Request) {
+	days := intQuery(r, "days", defaultForecastDays)
+	writeJSON(w, r, http.StatusOK, s.media.ReviewForecast(r.PathValue("studentID"), days))
+}
+
+// intQuery reads an integer query parameter that has already been validated
+// against the OpenAPI document, returning def when it is absent.
+func intQuery(r *http.Request, name string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// SetRubricHandler replaces the rubric of a free-response quiz question
+func (s *Server) SetRubricHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RubricRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q, err := s.media.SetRubric(r.PathValue("quizID"), r.PathValue("questionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, q)
+}
+
+// OverrideGradeHandler replaces rubric scores on a recorded free-response answer
+func (s *Server) OverrideGradeHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.GradeOverrideRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	attempt, err := s.media.OverrideGrade(r.PathValue("attemptID"), r.PathValue("questionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, attempt)
+}
@@ -1,81 +1,39 @@
 package api
 
 import (
-    "encoding/json"
-    "log"
     "net/http"
-    "studyai/internal/agent"
-    "studyai/internal/ai"
     "studyai/internal/models"
 )
 
-func setCORS(w http.ResponseWriter) {
-    w.Header().Set("Access-Control-Allow-Origin", "*")
-    w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-    w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-}
-
-func StudyHandler(w http.ResponseWriter, r *http.Request) {
-    setCORS(w)
-    if r.Method == http.MethodOptions {
-        w.WriteHeader(http.StatusNoContent)
-        return
-    }
-
-    if r.Method != http.MethodPost {
-        w.Header().Set("Allow", http.MethodPost)
-        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
+func (s *Server) StudyHandler(w http.ResponseWriter, r *http.Request) {
     var req models.StudyRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+    if err := decodeJSON(r, &req); err != nil {
+        writeError(w, r, err)
         return
     }
 
-    resp, err := agent.Run(req)
+    resp, err := s.agent.Run(r.Context(), req)
     if err != nil {
-        log.Printf("agent.Run error: %v", err)
-        http.Error(w, "internal error", http.StatusInternalServerError)
+        writeError(w, r, err)
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(resp); err != nil {
-        log.Printf("encode response error: %v", err)
-    }
+    writeJSON(w, r, http.StatusOK, resp)
 }
 
-// ChatHandler proxies simple chat messages to the LLM via internal/ai
-func ChatHandler(w http.ResponseWriter, r *http.Request) {
-    setCORS(w)
-    if r.Method == http.MethodOptions {
-        w.WriteHeader(http.StatusNoContent)
-        return
-    }
-
-    if r.Method != http.MethodPost {
-        w.Header().Set("Allow", http.MethodPost)
-        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
-    var req struct {
-        Message string `json:"message"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+// ChatHandler answers chat messages, grounded in the course materials when they are relevant
+func (s *Server) ChatHandler(w http.ResponseWriter, r *http.Request) {
+    var req models.ChatRequest
+    if err := decodeJSON(r, &req); err != nil {
+        writeError(w, r, err)
         return
     }
 
-    reply, err := ai.Chat(req.Message)
+    resp, err := s.media.Chat(r.Context(), req.Message)
     if err != nil {
-        log.Printf("ai.Chat error: %v", err)
-        http.Error(w, "failed to get chat response", http.StatusInternalServerError)
+        writeError(w, r, err)
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]string{"reply": reply})
+    writeJSON(w, r, http.StatusOK, resp)
 }
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/health"
+)
+
+// HealthzHandler is the liveness probe: it only reports that the process is
+// up and serving HTTP.
+func (s *Server) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// ReadyzHandler is the readiness probe: it reports whether the LLM provider,
+// OCR engine and storage are reachable. It answers 503 only when a critical
+// dependency is down or the server is draining; a failing LLM or OCR check
+// yields a "degraded" 200 because those features have fallbacks.
+func (s *Server) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.ready.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, status, report)
+}
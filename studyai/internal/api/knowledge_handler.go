@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// GetCurriculumHandler returns the curriculum graph
+func (s *Server) GetCurriculumHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.GetCurriculum())
+}
+
+// SetCurriculumHandler replaces the curriculum graph
+func (s *Server) SetCurriculumHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Curriculum
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	c, err := s.media.SetCurriculum(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, c)
+}
+
+// KnowledgeMapHandler returns a student's mastery over the curriculum with
+// gaps and the recommended next topic
+func (s *Server) KnowledgeMapHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.KnowledgeMap(r.PathValue("studentID"), r.URL.Query().Get("subject")))
+}
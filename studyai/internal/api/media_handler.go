@@ -1,194 +1,178 @@
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
-	"studyai/internal/media"
+	"studyai/internal/apperr"
 	"studyai/internal/models"
 )
 
 // ImageAnalysisHandler handles image/PDF analysis requests
-func ImageAnalysisHandler(w http.ResponseWriter, r *http.Request) {
-	setCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-
-	if r.Method != http.MethodPost {
-		w.Header().Set("Allow", http.MethodPost)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+func (s *Server) ImageAnalysisHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.ImageAnalysisRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Extract text from image using OCR
-	ocrService := media.NewOCRService()
-	extractedText, err := ocrService.ExtractTextFromImage(req.ImageData)
+	extractedText, err := s.ocr.ExtractTextFromImage(r.Context(), req.ImageData)
 	if err != nil {
-		log.Printf("OCR error: %v", err)
-		// Graceful fallback - return error but don't crash
-		http.Error(w, "failed to process image: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Analyze the extracted content
-	analysis, err := media.AnalyzeEducationalContent(extractedText, req)
+	analysis, err := s.media.AnalyzeEducationalContent(r.Context(), extractedText, req)
 	if err != nil {
-		log.Printf("analysis error: %v", err)
-		http.Error(w, "failed to analyze content", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(analysis); err != nil {
-		log.Printf("encode response error: %v", err)
-	}
+	writeJSON(w, r, http.StatusOK, analysis)
 }
 
 // GenerateQuizHandler generates a quiz on a specific topic
-func GenerateQuizHandler(w http.ResponseWriter, r *http.Request) {
-	setCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
+func (s *Server) GenerateQuizHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.QuizRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if r.Method != http.MethodPost {
-		w.Header().Set("Allow", http.MethodPost)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	// A known seed reveals the option order, so only teachers may pick one.
+	if req.Seed != 0 && !s.isTeacher(r) {
+		writeError(w, r, apperr.Validation(apperr.FieldError{Field: "seed", Message: "may only be set with the teacher token"}))
 		return
 	}
 
-	var req models.QuizRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	// Generate quiz questions using AI
+	quizResp, err := s.media.GenerateQuiz(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Validate quiz request
-	if req.NumQuestions < 1 || req.NumQuestions > 20 {
-		http.Error(w, "num_questions must be between 1 and 20", http.StatusBadRequest)
+	writeJSON(w, r, http.StatusOK, quizResp)
+}
+
+// WorksheetQuizHandler turns worksheet questions into a playable quiz
+func (s *Server) WorksheetQuizHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.WorksheetQuizRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Generate quiz questions using AI
-	quizResp, err := media.GenerateQuiz(req)
+	quiz, err := s.media.WorksheetQuiz(r.Context(), req)
 	if err != nil {
-		log.Printf("quiz generation error: %v", err)
-		http.Error(w, "failed to generate quiz", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(quizResp); err != nil {
-		log.Printf("encode response error: %v", err)
-	}
+	writeJSON(w, r, http.StatusOK, quiz)
 }
 
-// SubmitQuizHandler evaluates submitted quiz answers
-func SubmitQuizHandler(w http.ResponseWriter, r *http.Request) {
-	setCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
+// GetQuizHandler returns a stored quiz in the order the given student sees it
+func (s *Server) GetQuizHandler(w http.ResponseWriter, r *http.Request) {
+	quiz, err := s.media.GetQuiz(r.PathValue("quizID"), r.URL.Query().Get("student_id"))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if r.Method != http.MethodPost {
-		w.Header().Set("Allow", http.MethodPost)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	writeJSON(w, r, http.StatusOK, quiz)
+}
 
+// SubmitQuizHandler evaluates submitted quiz answers
+func (s *Server) SubmitQuizHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.QuizSubmissionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Evaluate quiz (in production, you'd need to store original quiz)
-	result, err := media.EvaluateQuiz(req, req.Questions)
+	if quizID := r.PathValue("quizID"); quizID != "" {
+		req.QuizID = quizID
+	}
+
+	// Evaluate quiz against the stored answer key when the quiz is known
+	result, err := s.media.EvaluateQuiz(r.Context(), req, req.Questions)
 	if err != nil {
-		log.Printf("quiz evaluation error: %v", err)
-		http.Error(w, "failed to evaluate quiz", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("encode response error: %v", err)
-	}
+	writeJSON(w, r, http.StatusOK, result)
 }
 
-// GetProgressHandler retrieves student progress profile
-func GetProgressHandler(w http.ResponseWriter, r *http.Request) {
-	setCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
+// NextQuizHandler generates a quiz tailored to the student's history
+func (s *Server) NextQuizHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.NextQuizRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if r.Method != http.MethodGet {
-		w.Header().Set("Allow", http.MethodGet)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	if studentID := r.PathValue("studentID"); studentID != "" {
+		req.StudentID = studentID
+	}
+	if req.StudentID == "" {
+		writeError(w, r, apperr.Validation(apperr.FieldError{Field: "student_id", Message: "is required"}))
+		return
+	}
+
+	quizResp, err := s.media.NextQuiz(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	studentID := r.URL.Query().Get("student_id")
+	writeJSON(w, r, http.StatusOK, quizResp)
+}
+
+// GetProgressHandler retrieves student progress profile
+func (s *Server) GetProgressHandler(w http.ResponseWriter, r *http.Request) {
+	studentID := r.PathValue("studentID")
+	if studentID == "" {
+		studentID = r.URL.Query().Get("student_id")
+	}
 	if studentID == "" {
-		http.Error(w, "student_id parameter required", http.StatusBadRequest)
+		writeError(w, r, apperr.Validation(apperr.FieldError{Field: "student_id", Message: "is required"}))
 		return
 	}
 
 	// Retrieve progress (in production, this would query a database)
-	profile, err := media.GetStudentProgress(studentID)
+	profile, err := s.media.GetStudentProgress(studentID)
 	if err != nil {
-		log.Printf("progress retrieval error: %v", err)
-		http.Error(w, fmt.Sprintf("student not found: %s", studentID), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("encode response error: %v", err)
-	}
+	writeJSON(w, r, http.StatusOK, profile)
 }
 
 // UpdateProgressHandler updates student profile
-func UpdateProgressHandler(w http.ResponseWriter, r *http.Request) {
-	setCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
+func (s *Server) UpdateProgressHandler(w http.ResponseWriter, r *http.Request) {
+	var profile models.ProgressProfile
+	if err := decodeJSON(r, &profile); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if r.Method != http.MethodPost {
-		w.Header().Set("Allow", http.MethodPost)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+	if studentID := r.PathValue("studentID"); studentID != "" {
+		profile.StudentID = studentID
 	}
-
-	var profile models.ProgressProfile
-	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	if profile.StudentID == "" {
+		writeError(w, r, apperr.Validation(apperr.FieldError{Field: "student_id", Message: "is required"}))
 		return
 	}
 
 	// Update progress (in production, this would save to a database)
-	err := media.UpdateStudentProgress(profile)
+	err := s.media.UpdateStudentProgress(profile)
 	if err != nil {
-		log.Printf("progress update error: %v", err)
-		http.Error(w, "failed to update progress", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Progress updated"})
+	writeJSON(w, r, http.StatusOK, models.StatusResponse{Status: "success", Message: "Progress updated"})
 }
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"studyai/internal/telemetry"
+)
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// withTelemetry assigns every request an ID (honouring an incoming
+// X-Request-ID), starts the root span (continuing an incoming W3C traceparent),
+// records latency metrics and writes one structured access log line.
+func withTelemetry(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = telemetry.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := telemetry.WithRequestID(r.Context(), requestID)
+		ctx = telemetry.ContextWithRemoteParent(ctx, r.Header.Get("traceparent"))
+		ctx, span := telemetry.StartSpan(ctx, "http.request",
+			slog.String("http.method", r.Method),
+			slog.String("http.path", r.URL.Path),
+		)
+		defer span.End()
+		w.Header().Set("traceparent", span.TraceParent())
+
+		telemetry.HTTPRequestsInFlight.Add(1)
+		defer telemetry.HTTPRequestsInFlight.Add(-1)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		// r.Pattern is filled in by the ServeMux once the request was routed.
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		elapsed := time.Since(start)
+		status := strconv.Itoa(rec.status)
+		telemetry.HTTPRequestDuration.Observe(elapsed.Seconds(), r.Method, route, status)
+		span.SetAttributes(slog.String("http.route", route), slog.Int("http.status", rec.status))
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(ctx, level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", float64(elapsed.Microseconds())/1000,
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// withCORS allows browser clients from any origin and answers preflight
+// requests before they reach the method-specific routes.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, traceparent")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, traceparent")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/media"
+	"studyai/internal/models"
+)
+
+// resourceFilter reads the resource catalog filter query parameters.
+func resourceFilter(r *http.Request) media.ResourceFilter {
+	q := r.URL.Query()
+	return media.ResourceFilter{
+		Topic:      q.Get("topic"),
+		Type:       q.Get("type"),
+		Difficulty: q.Get("difficulty"),
+	}
+}
+
+// ListResourcesHandler lists resource catalog entries matching the filters
+func (s *Server) ListResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.ListResources(resourceFilter(r)))
+}
+
+// GetResourceHandler returns one resource catalog entry
+func (s *Server) GetResourceHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := s.media.GetResource(r.PathValue("resourceID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// CreateResourceHandler adds a resource to the catalog
+func (s *Server) CreateResourceHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Resource
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := s.media.CreateResource(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// UpdateResourceHandler replaces a resource catalog entry
+func (s *Server) UpdateResourceHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Resource
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := s.media.UpdateResource(r.PathValue("resourceID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// DeleteResourceHandler removes a resource from the catalog
+func (s *Server) DeleteResourceHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.media.DeleteResource(r.PathValue("resourceID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, models.StatusResponse{Status: "success", Message: "Resource deleted"})
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"studyai/internal/telemetry"
+)
+
+// Handler registers all StudyAI endpoints on a fresh mux and wraps it with
+// CORS handling, request logging, tracing and metrics. Method mismatches are
+// answered with 405 and an Allow header by the mux itself; those and other
+// plain-text errors are rewritten into the JSON error envelope.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	for _, rt := range s.routes() {
+		op := s.spec.Paths[rt.Path][strings.ToLower(rt.Method)]
+		h := s.validated(op, rt.Handler)
+		if rt.Admin {
+			h = s.requireAdmin(h)
+		}
+		if rt.Teacher {
+			h = s.requireTeacher(h)
+		}
+		mux.HandleFunc(rt.Method+" "+rt.Path, h)
+	}
+
+	mux.HandleFunc("GET /openapi.json", s.OpenAPIHandler)
+	mux.Handle("GET /metrics", telemetry.MetricsHandler())
+
+	return withTelemetry(withCORS(withErrorEnvelope(mux)))
+}
+
+// OpenAPIHandler serves the generated OpenAPI 3 document.
+func (s *Server) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.spec)
+}
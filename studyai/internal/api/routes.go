@@ -0,0 +1,338 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/bank"
+	"studyai/internal/curriculum"
+	"studyai/internal/models"
+	"studyai/internal/openapi"
+)
+
+// maxBodyBytes bounds request bodies; base64 images are the largest payloads.
+const maxBodyBytes = 20 << 20
+
+// route is one API operation: how it is documented in the OpenAPI document
+// and which handler serves it. Requests are validated against the generated
+// document before the handler runs.
+type route struct {
+	Method     string
+	Path       string
+	ID         string
+	Summary    string
+	Tag        string
+	Deprecated bool
+	Params     []openapi.Parameter
+	Request    any // zero value of the JSON body type; nil when there is no body
+	Response   any // zero value of the JSON response type
+	Admin      bool
+	Teacher    bool           // requires the teacher (or admin) token
+	Upload     bool           // body is a raw file in the format named by ?format=
+	Errors     map[int]string // documented error statuses besides 400/401
+	Handler    http.HandlerFunc
+}
+
+func pathParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "path", Required: true, Description: description, Schema: &openapi.Schema{Type: "string", MinLength: openapi.Int(1)}}
+}
+
+func queryParam(name, description string, required bool) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Required: required, Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func enumQueryParam(name, description string, values []string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string", Enum: values}}
+}
+
+func intQueryParam(name, description string, minimum, maximum int) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "integer", Minimum: openapi.Float(float64(minimum)), Maximum: openapi.Float(float64(maximum))}}
+}
+
+// routes lists every documented operation. The unversioned RPC-style paths
+// are kept as deprecated aliases for existing clients.
+func (s *Server) routes() []route {
+	refused := map[int]string{422: "Plan refused by a guardrail (guardrail_refused)"}
+	llmErrors := map[int]string{429: "LLM provider quota exceeded (quota_exceeded)", 503: "LLM provider unavailable (llm_unavailable)"}
+	ocrErrors := map[int]string{422: "No text found in the image (ocr_failed)", 429: "OCR quota exceeded (quota_exceeded)", 502: "OCR engine failed (ocr_failed)", 503: "OCR engine not configured (ocr_failed)"}
+
+	studentID := pathParam("studentID", "student identifier")
+	quizID := pathParam("quizID", "quiz identifier returned when the quiz was created")
+	sessionID := pathParam("sessionID", "session identifier returned when the session was started")
+	sessionErrors := map[int]string{404: "Unknown session (not_found)", 409: "Session completed or question already answered (conflict)"}
+	questionID := pathParam("questionID", "question bank entry identifier")
+	quizQuestionID := pathParam("questionID", "question identifier within the quiz")
+	attemptID := pathParam("attemptID", "attempt identifier returned with the quiz result")
+	attemptFilters := []openapi.Parameter{
+		studentID,
+		queryParam("topic", "only attempts on this topic", false),
+		queryParam("from", "only attempts submitted on or after this date (YYYY-MM-DD or RFC 3339)", false),
+		queryParam("to", "only attempts submitted on or before this date (YYYY-MM-DD or RFC 3339)", false),
+		intQueryParam("min_score", "only attempts scoring at least this much", 0, 100),
+		intQueryParam("max_score", "only attempts scoring at most this much", 0, 100),
+		intQueryParam("limit", "maximum attempts to return (default 50)", 1, 500),
+	}
+	timerErrors := map[int]string{404: "Unknown quiz or question (not_found)", 409: "Not started, already submitted, or past the time limit under the reject policy (conflict)"}
+	questionErrors := map[int]string{404: "Unknown question (not_found)"}
+	bankFilters := []openapi.Parameter{
+		queryParam("topic", "only questions on this topic", false),
+		queryParam("tag", "only questions carrying this tag", false),
+		enumQueryParam("difficulty", "only questions at this difficulty", []string{"easy", "medium", "hard"}),
+		queryParam("source", "only questions from this source", false),
+	}
+	format := enumQueryParam("format", "file format (default json)", bank.Formats)
+	solutionID := pathParam("solutionID", "solution identifier returned when the solution was started")
+	resourceID := pathParam("resourceID", "resource catalog entry identifier")
+	resourceErrors := map[int]string{404: "Unknown resource (not_found)"}
+	resourceFilters := []openapi.Parameter{
+		queryParam("topic", "only resources on this topic", false),
+		enumQueryParam("type", "only resources of this type", []string{"video", "article", "book", "interactive"}),
+		enumQueryParam("difficulty", "only resources at this difficulty", []string{"beginner", "intermediate", "advanced"}),
+	}
+	materialID := pathParam("materialID", "course material identifier")
+	materialErrors := map[int]string{404: "Unknown course material (not_found)"}
+	runID := pathParam("runID", "tutor run identifier returned with the tutor's reply")
+	tutorErrors := map[int]string{422: "Student is sitting an assessment (guardrail_refused)", 429: llmErrors[429], 503: llmErrors[503]}
+	solutionErrors := map[int]string{422: "Question is part of an assessment in progress (guardrail_refused)", 429: llmErrors[429], 503: llmErrors[503]}
+
+	return []route{
+		// Version 1
+		{Method: "POST", Path: "/v1/study-plans/evaluations", ID: "evaluateStudyPlan", Tag: "study-plans",
+			Summary: "Evaluate a study plan", Request: models.StudyRequest{}, Response: models.AgentResponse{}, Errors: refused, Handler: s.StudyHandler},
+		{Method: "POST", Path: "/v1/chat/messages", ID: "sendChatMessage", Tag: "chat",
+			Summary: "Send a message to the study assistant", Request: models.ChatRequest{}, Response: models.ChatResponse{}, Errors: llmErrors, Handler: s.ChatHandler},
+		{Method: "POST", Path: "/v1/tutor", ID: "askTutor", Tag: "tutor",
+			Summary: "Ask the tutor agent, which looks up progress, due flashcards, quizzes and study plans with tools before answering", Request: models.TutorRequest{}, Response: models.TutorRun{}, Errors: tutorErrors, Handler: s.TutorHandler},
+		{Method: "GET", Path: "/v1/tutor/runs", ID: "listTutorRuns", Tag: "tutor", Teacher: true,
+			Summary: "List tutor runs with their tool-call audit logs", Params: []openapi.Parameter{queryParam("student_id", "only this student's runs", false)}, Response: models.TutorRunList{}, Handler: s.ListTutorRunsHandler},
+		{Method: "GET", Path: "/v1/tutor/runs/{runID}", ID: "getTutorRun", Tag: "tutor", Teacher: true,
+			Summary: "Get a tutor run with its tool-call audit log", Params: []openapi.Parameter{runID}, Response: models.TutorRun{}, Errors: map[int]string{404: "Unknown tutor run (not_found)"}, Handler: s.GetTutorRunHandler},
+		{Method: "POST", Path: "/v1/analyses", ID: "analyzeImage", Tag: "analyses",
+			Summary: "Analyze a worksheet or document image", Request: models.ImageAnalysisRequest{}, Response: models.ImageAnalysisResponse{}, Errors: ocrErrors, Handler: s.ImageAnalysisHandler},
+		{Method: "POST", Path: "/v1/solutions", ID: "createSolution", Tag: "solutions",
+			Summary: "Start a step-by-step worked solution for a homework question", Request: models.SolutionRequest{}, Response: models.WorkedSolution{}, Errors: solutionErrors, Handler: s.CreateSolutionHandler},
+		{Method: "GET", Path: "/v1/solutions/{solutionID}", ID: "getSolution", Tag: "solutions",
+			Summary: "Get the revealed steps of a worked solution", Params: []openapi.Parameter{solutionID}, Response: models.WorkedSolution{}, Errors: map[int]string{404: "Unknown solution (not_found)"}, Handler: s.GetSolutionHandler},
+		{Method: "POST", Path: "/v1/solutions/{solutionID}/reveal", ID: "revealSolution", Tag: "solutions",
+			Summary: "Reveal the next step of a worked solution: nudge, then hint, then full solution", Params: []openapi.Parameter{solutionID}, Response: models.WorkedSolution{}, Errors: map[int]string{404: "Unknown solution (not_found)", 422: "Withheld during an assessment in progress (guardrail_refused)"}, Handler: s.RevealSolutionHandler},
+		{Method: "POST", Path: "/v1/quizzes", ID: "createQuiz", Tag: "quizzes",
+			Summary: "Generate a quiz", Request: models.QuizRequest{}, Response: models.StudentQuiz{}, Handler: s.GenerateQuizHandler},
+		{Method: "POST", Path: "/v1/quizzes/worksheet", ID: "createWorksheetQuiz", Tag: "quizzes",
+			Summary: "Turn worksheet questions into a playable quiz", Request: models.WorksheetQuizRequest{}, Response: models.StudentQuiz{}, Errors: llmErrors, Handler: s.WorksheetQuizHandler},
+		{Method: "GET", Path: "/v1/quizzes/{quizID}", ID: "getQuiz", Tag: "quizzes",
+			Summary: "Get a quiz, in a student's own question order when it is shuffled", Params: []openapi.Parameter{quizID, queryParam("student_id", "student whose question order to use", false)}, Response: models.StudentQuiz{}, Errors: map[int]string{404: "Unknown quiz (not_found)"}, Handler: s.GetQuizHandler},
+		{Method: "POST", Path: "/v1/quizzes/{quizID}/start", ID: "startQuiz", Tag: "quizzes",
+			Summary: "Start a student's timer on a quiz", Params: []openapi.Parameter{quizID}, Request: models.QuizStartRequest{}, Response: models.QuizTimer{}, Errors: timerErrors, Handler: s.StartQuizHandler},
+		{Method: "PUT", Path: "/v1/quizzes/{quizID}/answers/{questionID}", ID: "saveQuizAnswer", Tag: "quizzes",
+			Summary: "Save the answer to one question of a started quiz", Params: []openapi.Parameter{quizID, quizQuestionID}, Request: models.QuizItemAnswerRequest{}, Response: models.QuizTimer{}, Errors: timerErrors, Handler: s.SaveAnswerHandler},
+		{Method: "POST", Path: "/v1/quizzes/{quizID}/attempts", ID: "submitQuizAttempt", Tag: "attempts",
+			Summary: "Submit answers for a quiz", Params: []openapi.Parameter{quizID}, Request: models.QuizSubmissionRequest{}, Response: models.QuizResult{}, Errors: map[int]string{409: "Already submitted, a timed quiz that was not started, or late under the reject policy (conflict)"}, Handler: s.SubmitQuizHandler},
+		{Method: "POST", Path: "/v1/quizzes/{quizID}/retake", ID: "retakeQuiz", Tag: "quizzes",
+			Summary: "Create a fresh copy of a previous quiz with a new option order", Params: []openapi.Parameter{quizID}, Request: models.RetakeRequest{}, Response: models.StudentQuiz{}, Errors: map[int]string{404: "Unknown quiz (not_found)"}, Handler: s.RetakeQuizHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/attempts", ID: "listStudentAttempts", Tag: "attempts",
+			Summary: "List a student's quiz attempts, newest first", Params: attemptFilters, Response: models.AttemptList{}, Handler: s.ListAttemptsHandler},
+		{Method: "GET", Path: "/v1/attempts/{attemptID}", ID: "getAttempt", Tag: "attempts",
+			Summary: "Get a recorded attempt with its per-question outcomes and result", Params: []openapi.Parameter{attemptID}, Response: models.QuizAttempt{}, Errors: map[int]string{404: "Unknown attempt (not_found)"}, Handler: s.GetAttemptHandler},
+		{Method: "PUT", Path: "/v1/quizzes/{quizID}/questions/{questionID}/rubric", ID: "setQuestionRubric", Tag: "grading", Teacher: true,
+			Summary: "Replace a free-response question's rubric", Params: []openapi.Parameter{quizID, quizQuestionID}, Request: models.RubricRequest{}, Response: models.QuizQuestion{}, Errors: map[int]string{404: "Unknown quiz or question (not_found)"}, Handler: s.SetRubricHandler},
+		{Method: "PUT", Path: "/v1/attempts/{attemptID}/items/{questionID}/grade", ID: "overrideGrade", Tag: "grading", Teacher: true,
+			Summary: "Override the rubric grade of a free-response answer", Params: []openapi.Parameter{attemptID, quizQuestionID}, Request: models.GradeOverrideRequest{}, Response: models.QuizAttempt{}, Errors: map[int]string{404: "Unknown attempt or question (not_found)"}, Handler: s.OverrideGradeHandler},
+		{Method: "POST", Path: "/v1/students/{studentID}/next-quiz", ID: "createNextQuiz", Tag: "quizzes",
+			Summary: "Generate a quiz adapted to the student's ability and weak topics", Params: []openapi.Parameter{studentID}, Request: models.NextQuizRequest{}, Response: models.StudentQuiz{}, Handler: s.NextQuizHandler},
+		{Method: "POST", Path: "/v1/sessions", ID: "startTestSession", Tag: "sessions",
+			Summary: "Start an adaptive test session", Request: models.TestSessionRequest{}, Response: models.TestSession{}, Errors: llmErrors, Handler: s.StartSessionHandler},
+		{Method: "GET", Path: "/v1/sessions/{sessionID}", ID: "getTestSession", Tag: "sessions",
+			Summary: "Get a test session's state and current question", Params: []openapi.Parameter{sessionID}, Response: models.TestSession{}, Errors: sessionErrors, Handler: s.GetSessionHandler},
+		{Method: "POST", Path: "/v1/sessions/{sessionID}/answers", ID: "answerTestSession", Tag: "sessions",
+			Summary: "Answer the current question and receive the next", Params: []openapi.Parameter{sessionID}, Request: models.SessionAnswerRequest{}, Response: models.SessionAnswerResult{}, Errors: sessionErrors, Handler: s.AnswerSessionHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/flashcards/due", ID: "listDueFlashcards", Tag: "flashcards",
+			Summary: "List flashcards due for review today", Params: []openapi.Parameter{studentID, intQueryParam("limit", "maximum cards to return (default 50)", 1, 500)}, Response: models.DueFlashcards{}, Handler: s.DueFlashcardsHandler},
+		{Method: "POST", Path: "/v1/students/{studentID}/flashcards/{cardID}/reviews", ID: "reviewFlashcard", Tag: "flashcards",
+			Summary: "Submit a recall grade for a flashcard", Params: []openapi.Parameter{studentID, pathParam("cardID", "flashcard identifier")}, Request: models.FlashcardReviewRequest{}, Response: models.Flashcard{}, Errors: map[int]string{404: "Unknown flashcard (not_found)"}, Handler: s.ReviewFlashcardHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/flashcards/forecast", ID: "getReviewForecast", Tag: "flashcards",
+			Summary: "Upcoming review load per day", Params: []openapi.Parameter{studentID, intQueryParam("days", "number of days to forecast (default 14)", 1, 90)}, Response: models.ReviewForecast{}, Handler: s.ReviewForecastHandler},
+		{Method: "GET", Path: "/v1/questions", ID: "listBankQuestions", Tag: "question-bank", Teacher: true,
+			Summary: "List question bank entries", Params: bankFilters, Response: models.BankQuestionList{}, Handler: s.ListBankQuestionsHandler},
+		{Method: "POST", Path: "/v1/questions", ID: "createBankQuestion", Tag: "question-bank", Teacher: true,
+			Summary: "Add a question to the bank", Request: models.BankQuestion{}, Response: models.BankQuestion{}, Handler: s.CreateBankQuestionHandler},
+		{Method: "GET", Path: "/v1/questions/export", ID: "exportBankQuestions", Tag: "question-bank", Teacher: true,
+			Summary: "Download bank entries as JSON, CSV, GIFT or QTI", Params: append([]openapi.Parameter{format}, bankFilters...), Handler: s.ExportBankQuestionsHandler},
+		{Method: "POST", Path: "/v1/questions/import", ID: "importBankQuestions", Tag: "question-bank", Teacher: true, Upload: true,
+			Summary: "Add or update bank entries from a JSON, CSV, GIFT or QTI file", Params: []openapi.Parameter{format}, Response: models.BankImportResult{}, Errors: map[int]string{413: "File too large (payload_too_large)"}, Handler: s.ImportBankQuestionsHandler},
+		{Method: "GET", Path: "/v1/questions/{questionID}", ID: "getBankQuestion", Tag: "question-bank", Teacher: true,
+			Summary: "Get a question bank entry", Params: []openapi.Parameter{questionID}, Response: models.BankQuestion{}, Errors: questionErrors, Handler: s.GetBankQuestionHandler},
+		{Method: "PUT", Path: "/v1/questions/{questionID}", ID: "updateBankQuestion", Tag: "question-bank", Teacher: true,
+			Summary: "Replace a question bank entry", Params: []openapi.Parameter{questionID}, Request: models.BankQuestion{}, Response: models.BankQuestion{}, Errors: questionErrors, Handler: s.UpdateBankQuestionHandler},
+		{Method: "DELETE", Path: "/v1/questions/{questionID}", ID: "deleteBankQuestion", Tag: "question-bank", Teacher: true,
+			Summary: "Remove a question from the bank", Params: []openapi.Parameter{questionID}, Response: models.StatusResponse{}, Errors: questionErrors, Handler: s.DeleteBankQuestionHandler},
+		{Method: "GET", Path: "/v1/curriculum", ID: "getCurriculum", Tag: "curriculum",
+			Summary: "Get the curriculum graph of topics and prerequisites", Response: models.Curriculum{}, Handler: s.GetCurriculumHandler},
+		{Method: "PUT", Path: "/v1/curriculum", ID: "setCurriculum", Tag: "curriculum", Teacher: true,
+			Summary: "Replace the curriculum graph", Request: models.Curriculum{}, Response: models.Curriculum{}, Handler: s.SetCurriculumHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/knowledge-map", ID: "getKnowledgeMap", Tag: "curriculum",
+			Summary: "A student's topic mastery, knowledge gaps and recommended next topic", Params: []openapi.Parameter{studentID, queryParam("subject", "only topics in this subject", false)}, Response: models.KnowledgeMap{}, Handler: s.KnowledgeMapHandler},
+		{Method: "GET", Path: "/v1/standards", ID: "listStandards", Tag: "curriculum",
+			Summary: "List the standards catalog", Params: []openapi.Parameter{queryParam("framework", "only standards in this framework", false), queryParam("subject", "only standards in this subject", false), queryParam("grade", "only standards for this grade", false)}, Response: models.StandardList{}, Handler: s.ListStandardsHandler},
+		{Method: "POST", Path: "/v1/standards/import", ID: "importStandards", Tag: "curriculum", Teacher: true, Upload: true,
+			Summary: "Add or update catalog standards from a JSON or CSV file", Params: []openapi.Parameter{enumQueryParam("format", "file format (default json)", curriculum.StandardFormats)}, Response: models.StandardImportResult{}, Errors: map[int]string{413: "File too large (payload_too_large)"}, Handler: s.ImportStandardsHandler},
+		{Method: "PUT", Path: "/v1/quizzes/{quizID}/questions/{questionID}/standards", ID: "tagQuestionStandards", Tag: "curriculum", Teacher: true,
+			Summary: "Replace the standard codes of a quiz question", Params: []openapi.Parameter{quizID, quizQuestionID}, Request: models.StandardTagRequest{}, Response: models.QuizQuestion{}, Errors: map[int]string{404: "Unknown quiz or question (not_found)"}, Handler: s.TagQuestionStandardsHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/standards", ID: "getStandardsReport", Tag: "curriculum",
+			Summary: "A student's mastery of each standard", Params: []openapi.Parameter{studentID, queryParam("framework", "only standards in this framework", false)}, Response: models.StandardsReport{}, Handler: s.StandardsReportHandler},
+		{Method: "GET", Path: "/v1/course-materials", ID: "listCourseMaterials", Tag: "course-materials",
+			Summary: "List the course materials that ground chat, quizzes and analyses", Response: models.CourseMaterialList{}, Handler: s.ListCourseMaterialsHandler},
+		{Method: "POST", Path: "/v1/course-materials", ID: "createCourseMaterial", Tag: "course-materials", Teacher: true,
+			Summary: "Upload notes or a textbook chapter to split into passages and index", Request: models.CourseMaterial{}, Response: models.CourseMaterial{}, Handler: s.CreateCourseMaterialHandler},
+		{Method: "GET", Path: "/v1/course-materials/search", ID: "searchCourseMaterials", Tag: "course-materials",
+			Summary: "Retrieve the passages most relevant to a query", Params: []openapi.Parameter{queryParam("q", "the query, such as a question or topic", true)}, Response: models.PassageSearch{}, Handler: s.SearchCourseMaterialsHandler},
+		{Method: "GET", Path: "/v1/course-materials/{materialID}", ID: "getCourseMaterial", Tag: "course-materials",
+			Summary: "Get a course material with its text", Params: []openapi.Parameter{materialID}, Response: models.CourseMaterial{}, Errors: materialErrors, Handler: s.GetCourseMaterialHandler},
+		{Method: "DELETE", Path: "/v1/course-materials/{materialID}", ID: "deleteCourseMaterial", Tag: "course-materials", Teacher: true,
+			Summary: "Remove a course material and its passages", Params: []openapi.Parameter{materialID}, Response: models.StatusResponse{}, Errors: materialErrors, Handler: s.DeleteCourseMaterialHandler},
+		{Method: "GET", Path: "/v1/resources", ID: "listResources", Tag: "resources",
+			Summary: "List the learning resource catalog", Params: resourceFilters, Response: models.ResourceList{}, Handler: s.ListResourcesHandler},
+		{Method: "POST", Path: "/v1/resources", ID: "createResource", Tag: "resources", Admin: true,
+			Summary: "Add a learning resource to the catalog", Request: models.Resource{}, Response: models.Resource{}, Handler: s.CreateResourceHandler},
+		{Method: "GET", Path: "/v1/resources/{resourceID}", ID: "getResource", Tag: "resources",
+			Summary: "Get a resource catalog entry", Params: []openapi.Parameter{resourceID}, Response: models.Resource{}, Errors: resourceErrors, Handler: s.GetResourceHandler},
+		{Method: "PUT", Path: "/v1/resources/{resourceID}", ID: "updateResource", Tag: "resources", Admin: true,
+			Summary: "Replace a resource catalog entry", Params: []openapi.Parameter{resourceID}, Request: models.Resource{}, Response: models.Resource{}, Errors: resourceErrors, Handler: s.UpdateResourceHandler},
+		{Method: "DELETE", Path: "/v1/resources/{resourceID}", ID: "deleteResource", Tag: "resources", Admin: true,
+			Summary: "Remove a resource from the catalog", Params: []openapi.Parameter{resourceID}, Response: models.StatusResponse{}, Errors: resourceErrors, Handler: s.DeleteResourceHandler},
+		{Method: "GET", Path: "/v1/students/{studentID}/progress", ID: "getStudentProgress", Tag: "students",
+			Summary: "Get a student's progress profile", Params: []openapi.Parameter{studentID}, Response: models.ProgressProfile{}, Handler: s.GetProgressHandler},
+		{Method: "PUT", Path: "/v1/students/{studentID}/progress", ID: "updateStudentProgress", Tag: "students",
+			Summary: "Replace a student's progress profile", Params: []openapi.Parameter{studentID}, Request: models.ProgressProfile{}, Response: models.StatusResponse{}, Handler: s.UpdateProgressHandler},
+
+		// Operations
+		{Method: "GET", Path: "/healthz", ID: "healthz", Tag: "operations",
+			Summary: "Liveness probe", Handler: s.HealthzHandler},
+		{Method: "GET", Path: "/readyz", ID: "readyz", Tag: "operations",
+			Summary: "Readiness probe with dependency checks", Handler: s.ReadyzHandler},
+		{Method: "GET", Path: "/admin/config", ID: "getConfig", Tag: "admin", Admin: true,
+			Summary: "Effective configuration with secrets redacted", Handler: s.AdminConfigHandler},
+
+		// Legacy aliases
+		{Method: "POST", Path: "/agent/run", ID: "legacyEvaluateStudyPlan", Tag: "legacy", Deprecated: true,
+			Summary: "Use POST /v1/study-plans/evaluations", Request: models.StudyRequest{}, Response: models.AgentResponse{}, Errors: refused, Handler: s.StudyHandler},
+		{Method: "POST", Path: "/chat", ID: "legacyChat", Tag: "legacy", Deprecated: true,
+			Summary: "Use POST /v1/chat/messages", Request: models.ChatRequest{}, Response: models.ChatResponse{}, Errors: llmErrors, Handler: s.ChatHandler},
+		{Method: "POST", Path: "/analyze-image", ID: "legacyAnalyzeImage", Tag: "legacy", Deprecated: true,
+			Summary: "Use POST /v1/analyses", Request: models.ImageAnalysisRequest{}, Response: models.ImageAnalysisResponse{}, Errors: ocrErrors, Handler: s.ImageAnalysisHandler},
+		{Method: "POST", Path: "/generate-quiz", ID: "legacyGenerateQuiz", Tag: "legacy", Deprecated: true,
+			Summary: "Use POST /v1/quizzes", Request: models.QuizRequest{}, Response: models.StudentQuiz{}, Handler: s.GenerateQuizHandler},
+		{Method: "POST", Path: "/submit-quiz", ID: "legacySubmitQuiz", Tag: "legacy", Deprecated: true,
+			Summary: "Use POST /v1/quizzes/{quizID}/attempts", Request: models.QuizSubmissionRequest{}, Response: models.QuizResult{}, Handler: s.SubmitQuizHandler},
+		{Method: "GET", Path: "/progress", ID: "legacyGetProgress", Tag: "legacy", Deprecated: true,
+			Summary: "Use GET /v1/students/{studentID}/progress", Params: []openapi.Parameter{queryParam("student_id", "student identifier", true)}, Response: models.ProgressProfile{}, Handler: s.GetProgressHandler},
+		{Method: "POST", Path: "/update-progress", ID: "legacyUpdateProgress", Tag: "legacy", Deprecated: true,
+			Summary: "Use PUT /v1/students/{studentID}/progress", Request: models.ProgressProfile{}, Response: models.StatusResponse{}, Handler: s.UpdateProgressHandler},
+	}
+}
+
+// buildSpec generates the OpenAPI document for the given routes and applies
+// constraints that come from configuration rather than struct tags.
+func (s *Server) buildSpec(routes []route) *openapi.Document {
+	doc := openapi.New("StudyAI API", "1.0.0", "AI-assisted study planning, document analysis, quizzes and progress tracking.")
+
+	for _, rt := range routes {
+		op := &openapi.Operation{
+			OperationID: rt.ID,
+			Summary:     rt.Summary,
+			Tags:        []string{rt.Tag},
+			Deprecated:  rt.Deprecated,
+			Parameters:  rt.Params,
+			Responses:   map[string]*openapi.Response{},
+		}
+		errorSchema := doc.SchemaFor(ErrorResponse{})
+		if rt.Request != nil || len(rt.Params) > 0 {
+			op.Responses["400"] = openapi.JSONResponse("Malformed request (invalid_request) or failed validation (validation_failed)", errorSchema)
+		}
+		if rt.Request != nil {
+			op.RequestBody = openapi.JSONBody(doc.SchemaFor(rt.Request))
+		}
+		if rt.Upload {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+				"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			}}
+		}
+		if rt.Response != nil {
+			op.Responses["200"] = openapi.JSONResponse("Success", doc.SchemaFor(rt.Response))
+		} else {
+			op.Responses["200"] = &openapi.Response{Description: "Success"}
+		}
+		if rt.Admin {
+			op.Responses["401"] = openapi.JSONResponse("Missing or invalid admin token (unauthorized)", errorSchema)
+		}
+		if rt.Teacher {
+			op.Responses["401"] = openapi.JSONResponse("Missing or invalid teacher token (unauthorized)", errorSchema)
+		}
+		for status, description := range rt.Errors {
+			op.Responses[strconv.Itoa(status)] = openapi.JSONResponse(description, errorSchema)
+		}
+		op.Responses["default"] = openapi.JSONResponse("Unexpected error (internal)", errorSchema)
+		doc.AddOperation(rt.Method, rt.Path, op)
+	}
+
+	for _, name := range []string{"QuizRequest", "NextQuizRequest"} {
+		if quiz := doc.Component(name); quiz != nil {
+			quiz.Properties["num_questions"].Maximum = openapi.Float(float64(s.cfg.Quiz.MaxQuestions))
+		}
+	}
+	return doc
+}
+
+// validated checks path/query parameters and the JSON body against op before
+// calling next. The body is buffered so next can decode it normally; upload
+// bodies are left for the handler to read.
+func (s *Server) validated(op *openapi.Operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var errs []openapi.FieldError
+		query := r.URL.Query()
+		for _, p := range op.Parameters {
+			switch p.In {
+			case "path":
+				raw := r.PathValue(p.Name)
+				errs = append(errs, s.spec.ValidateParameter(p, raw, raw != "")...)
+			case "query":
+				_, present := query[p.Name]
+				errs = append(errs, s.spec.ValidateParameter(p, query.Get(p.Name), present)...)
+			}
+		}
+
+		if op.RequestBody != nil && op.RequestBody.Content["application/json"].Schema != nil {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, r, apperr.New(apperr.CodePayloadTooLarge, "request body exceeds "+strconv.Itoa(maxBodyBytes)+" bytes"))
+					return
+				}
+				writeError(w, r, apperr.Wrap(apperr.CodeInvalidRequest, "failed to read request body", err))
+				return
+			}
+			bodyErrs := s.spec.ValidateJSON(op.RequestBody.Content["application/json"].Schema, body)
+			if len(bodyErrs) == 1 && bodyErrs[0].Field == "" && strings.HasPrefix(bodyErrs[0].Message, "invalid JSON") {
+				writeError(w, r, apperr.New(apperr.CodeInvalidRequest, bodyErrs[0].Message))
+				return
+			}
+			errs = append(errs, bodyErrs...)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if len(errs) > 0 {
+			fields := make([]apperr.FieldError, len(errs))
+			for i, e := range errs {
+				fields[i] = apperr.FieldError{Field: e.Field, Message: e.Message}
+			}
+			writeError(w, r, apperr.Validation(fields...))
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"studyai/internal/agent"
+	"studyai/internal/ai"
+	"studyai/internal/config"
+	"studyai/internal/health"
+	"studyai/internal/media"
+	"studyai/internal/openapi"
+)
+
+// Deps are the services the HTTP layer is wired to.
+type Deps struct {
+	Config config.Config
+	LLM    *ai.Client
+	Agent  *agent.Agent
+	Tutor  *agent.Tutor
+	Media  *media.Service
+	OCR    *media.OCRService
+	Ready  *health.Checker
+}
+
+// Server holds the dependencies used by the HTTP handlers.
+type Server struct {
+	cfg   config.Config
+	llm   *ai.Client
+	agent *agent.Agent
+	tutor *agent.Tutor
+	media *media.Service
+	ocr   *media.OCRService
+	ready *health.Checker
+	spec  *openapi.Document
+}
+
+// NewServer creates a Server from its dependencies.
+func NewServer(d Deps) *Server {
+	s := &Server{
+		cfg:   d.Config,
+		llm:   d.LLM,
+		agent: d.Agent,
+		tutor: d.Tutor,
+		media: d.Media,
+		ocr:   d.OCR,
+		ready: d.Ready,
+	}
+	s.spec = s.buildSpec(s.routes())
+	return s
+}
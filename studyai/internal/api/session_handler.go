@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// StartSessionHandler starts an adaptive test session and returns its first question
+func (s *Server) StartSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TestSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	session, err := s.media.StartSession(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, session)
+}
+
+// GetSessionHandler returns the current state of a test session
+func (s *Server) GetSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := s.media.GetSession(r.PathValue("sessionID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, session)
+}
+
+// AnswerSessionHandler grades one answer and serves the next question
+func (s *Server) AnswerSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.SessionAnswerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := s.media.AnswerSession(r.Context(), r.PathValue("sessionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// CreateSolutionHandler builds a hint ladder for one homework question
+func (s *Server) CreateSolutionHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.SolutionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sol, err := s.media.CreateSolution(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, sol)
+}
+
+// GetSolutionHandler returns the revealed rungs of a worked solution
+func (s *Server) GetSolutionHandler(w http.ResponseWriter, r *http.Request) {
+	sol, err := s.media.GetSolution(r.PathValue("solutionID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, sol)
+}
+
+// RevealSolutionHandler reveals the next rung of a worked solution
+func (s *Server) RevealSolutionHandler(w http.ResponseWriter, r *http.Request) {
+	sol, err := s.media.RevealSolution(r.PathValue("solutionID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, sol)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/curriculum"
+	"studyai/internal/media"
+	"studyai/internal/models"
+)
+
+// ListStandardsHandler lists catalog standards matching the filters
+func (s *Server) ListStandardsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	writeJSON(w, r, http.StatusOK, s.media.ListStandards(media.StandardFilter{
+		Framework: q.Get("framework"),
+		Subject:   q.Get("subject"),
+		Grade:     q.Get("grade"),
+	}))
+}
+
+// ImportStandardsHandler adds or updates catalog standards from an uploaded file
+func (s *Server) ImportStandardsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = curriculum.FormatJSON
+	}
+	result, err := s.media.ImportStandards(format, data)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// TagQuestionStandardsHandler replaces the standard codes of a quiz question
+func (s *Server) TagQuestionStandardsHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.StandardTagRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q, err := s.media.TagQuestionStandards(r.PathValue("quizID"), r.PathValue("questionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, q)
+}
+
+// StandardsReportHandler returns a student's mastery per standard
+func (s *Server) StandardsReportHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.media.StandardsReport(r.PathValue("studentID"), r.URL.Query().Get("framework")))
+}
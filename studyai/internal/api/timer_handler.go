@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// StartQuizHandler starts a student's timer on a quiz
+func (s *Server) StartQuizHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.QuizStartRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	timer, err := s.media.StartQuiz(r.PathValue("quizID"), req.StudentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, timer)
+}
+
+// SaveAnswerHandler saves the answer to one question of a started quiz
+func (s *Server) SaveAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.QuizItemAnswerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	timer, err := s.media.SaveAnswer(r.PathValue("quizID"), r.PathValue("questionID"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, timer)
+}
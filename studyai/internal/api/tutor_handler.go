@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"studyai/internal/models"
+)
+
+// TutorHandler answers a student's message with the tool-using tutor agent
+func (s *Server) TutorHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TutorRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	run, err := s.tutor.Run(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, run)
+}
+
+// ListTutorRunsHandler lists tutor runs, optionally for one student
+func (s *Server) ListTutorRunsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.tutor.ListRuns(r.URL.Query().Get("student_id")))
+}
+
+// GetTutorRunHandler returns one tutor run with its tool-call audit log
+func (s *Server) GetTutorRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := s.tutor.GetRun(r.PathValue("runID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, run)
+}
@@ -0,0 +1,120 @@
+// Package apperr defines typed application errors with machine-readable codes
+// that the HTTP layer maps to status codes and a uniform JSON envelope.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error identifier.
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "invalid_request"    // malformed body or parameters
+	CodeValidationFailed Code = "validation_failed"  // well-formed but violates constraints
+	CodeGuardrailRefused Code = "guardrail_refused"  // refused by a safety guardrail
+	CodeNotFound         Code = "not_found"          // unknown route or resource
+	CodeMethodNotAllowed Code = "method_not_allowed" // route exists for other methods
+	CodeConflict         Code = "conflict"           // request conflicts with the resource's state
+	CodeUnauthorized     Code = "unauthorized"       // missing or invalid credentials
+	CodePayloadTooLarge  Code = "payload_too_large"  // body exceeds the size limit
+	CodeLLMUnavailable   Code = "llm_unavailable"    // LLM provider unreachable or failing
+	CodeOCRFailed        Code = "ocr_failed"         // text could not be extracted
+	CodeQuotaExceeded    Code = "quota_exceeded"     // a provider or local quota was hit
+	CodeInternal         Code = "internal"           // unexpected server failure
+)
+
+// HTTPStatus returns the default HTTP status for the code.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeInvalidRequest, CodeValidationFailed:
+		return http.StatusBadRequest
+	case CodeGuardrailRefused:
+		return http.StatusUnprocessableEntity
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case CodeLLMUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeOCRFailed:
+		return http.StatusBadGateway
+	case CodeQuotaExceeded:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError describes why a single request field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an application error with a code, a client-safe message, optional
+// field details and an optional underlying cause that is never shown to clients.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// Status overrides Code.HTTPStatus when non-zero.
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the status to respond with.
+func (e *Error) HTTPStatus() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return e.Code.HTTPStatus()
+}
+
+// New creates an Error.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an Error that keeps err as its cause.
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Validation creates a validation_failed error carrying field details.
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Message: "request validation failed", Fields: fields}
+}
+
+// From returns err as an *Error, classifying unknown errors as internal.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Wrap(CodeInternal, "internal error", err)
+}
+
+// Is reports whether err carries the given code.
+func Is(err error, code Code) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,210 @@
+// Package bank validates question bank entries and converts them to and from
+// the interchange formats teachers use: JSON, CSV, GIFT (Moodle) and QTI 1.2.
+// Only single-answer multiple-choice questions are supported; anything else
+// is reported per item rather than failing the whole file.
+package bank
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// Supported formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatGIFT = "gift"
+	FormatQTI  = "qti"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatJSON, FormatCSV, FormatGIFT, FormatQTI}
+
+// ContentType returns the MIME type used when exporting format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatGIFT:
+		return "text/plain; charset=utf-8"
+	case FormatQTI:
+		return "application/xml; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// Item is one decoded question, or the reason it could not be decoded.
+type Item struct {
+	Question models.BankQuestion
+	Err      error
+}
+
+// Decode parses a file in format. The error is non-nil only when the file as a
+// whole cannot be read; problems with individual questions are set on items.
+func Decode(format string, data []byte) ([]Item, error) {
+	var items []Item
+	var err error
+	switch format {
+	case FormatJSON:
+		items, err = decodeJSON(data)
+	case FormatCSV:
+		items, err = decodeCSV(data)
+	case FormatGIFT:
+		items, err = decodeGIFT(data)
+	case FormatQTI:
+		items, err = decodeQTI(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].Err != nil {
+			continue
+		}
+		if fields := Normalize(&items[i].Question); len(fields) > 0 {
+			items[i].Err = fieldsError(fields)
+		}
+	}
+	return items, nil
+}
+
+// Encode writes qs to w in format.
+func Encode(format string, w io.Writer, qs []models.BankQuestion) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(qs)
+	case FormatCSV:
+		return encodeCSV(w, qs)
+	case FormatGIFT:
+		return encodeGIFT(w, qs)
+	case FormatQTI:
+		return encodeQTI(w, qs)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Normalize tidies q in place, defaulting the difficulty to medium, and
+// returns every problem that makes it unusable.
+func Normalize(q *models.BankQuestion) []apperr.FieldError {
+	var fields []apperr.FieldError
+	fail := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+
+	q.Question = strings.TrimSpace(q.Question)
+	q.Topic = strings.TrimSpace(q.Topic)
+	q.Explanation = strings.TrimSpace(q.Explanation)
+	q.Source = strings.TrimSpace(q.Source)
+	q.Difficulty = strings.ToLower(strings.TrimSpace(q.Difficulty))
+
+	var tags []string
+	for _, t := range q.Tags {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	q.Tags = tags
+
+	var standards []string
+	for _, c := range q.Standards {
+		if c = strings.TrimSpace(c); c != "" && !slices.Contains(standards, c) {
+			standards = append(standards, c)
+		}
+	}
+	q.Standards = standards
+
+	if q.Question == "" {
+		fail("question", "must not be empty")
+	}
+	if q.Topic == "" {
+		fail("topic", "must not be empty")
+	}
+	switch q.Difficulty {
+	case "":
+		q.Difficulty = "medium"
+	case "easy", "medium", "hard":
+	default:
+		fail("difficulty", "must be one of easy, medium, hard")
+	}
+
+	seen := map[string]bool{}
+	for i := range q.Options {
+		q.Options[i] = strings.TrimSpace(q.Options[i])
+		key := strings.ToLower(q.Options[i])
+		switch {
+		case key == "":
+			fail(fmt.Sprintf("options[%d]", i), "must not be empty")
+		case seen[key]:
+			fail(fmt.Sprintf("options[%d]", i), "duplicates another option")
+		}
+		seen[key] = true
+	}
+	if len(q.Options) < 2 {
+		fail("options", "must have at least 2 items")
+	}
+	if q.CorrectAnswer < 0 || q.CorrectAnswer >= len(q.Options) {
+		fail("correct_answer", fmt.Sprintf("must be between 0 and %d", max(len(q.Options)-1, 0)))
+	}
+
+	if len(q.Misconceptions) > 0 {
+		for i := range q.Misconceptions {
+			q.Misconceptions[i] = strings.TrimSpace(q.Misconceptions[i])
+		}
+		switch {
+		case len(q.Misconceptions) != len(q.Options):
+			fail("misconceptions", "must have one label per option")
+		case !slices.ContainsFunc(q.Misconceptions, func(m string) bool { return m != "" }):
+			q.Misconceptions = nil
+		case q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) && q.Misconceptions[q.CorrectAnswer] != "":
+			fail(fmt.Sprintf("misconceptions[%d]", q.CorrectAnswer), "must be empty for the correct option")
+		}
+	}
+	return fields
+}
+
+func fieldsError(fields []apperr.FieldError) error {
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+// decodeJSON accepts an array of questions or an object with a "questions"
+// array, as returned by the list endpoint.
+func decodeJSON(data []byte) ([]Item, error) {
+	var raw []json.RawMessage
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Questions []json.RawMessage `json:"questions"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		raw = wrapper.Questions
+	} else if err := json.Unmarshal(trimmed, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	items := make([]Item, len(raw))
+	for i, r := range raw {
+		if err := json.Unmarshal(r, &items[i].Question); err != nil {
+			items[i].Err = fmt.Errorf("invalid question: %w", err)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,232 @@
+package bank
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"studyai/internal/models"
+)
+
+// sampleQuestions exercises the characters and fields each format has to
+// escape or carry.
+func sampleQuestions() []models.BankQuestion {
+	return []models.BankQuestion{
+		{
+			ID:             "bq_1",
+			Topic:          "Algebra",
+			Difficulty:     "easy",
+			Tags:           []string{"linear", "equations"},
+			Standards:      []string{"CCSS.8.EE.7"},
+			Source:         "Chapter 2",
+			Question:       "Solve 2x + 3 = 11. What is x?",
+			Options:        []string{"4", "7", "8"},
+			CorrectAnswer:  0,
+			Explanation:    "Subtract 3, then divide by 2.",
+			Misconceptions: []string{"", "adds 3 instead of subtracting", "forgets to divide"},
+		},
+		{
+			ID:            "bq_2",
+			Topic:         "Algebra",
+			Difficulty:    "hard",
+			Question:      "Which set is {x : x > 0}? Use a ~ b = c # d",
+			Options:       []string{"positive reals", "x = {1, 2}", `a\b ~c #d`},
+			CorrectAnswer: 2,
+			Explanation:   "Escapes: = ~ # { } : must survive",
+		},
+		{
+			ID:            "bq_3",
+			Topic:         "Geometry",
+			Difficulty:    "medium",
+			Question:      "How many sides does a hexagon have?",
+			Options:       []string{"5", "6"},
+			CorrectAnswer: 1,
+		},
+	}
+}
+
+// carried clears the fields format does not carry, so a decoded question can
+// be compared with the one that was encoded.
+func carried(format string, q models.BankQuestion) models.BankQuestion {
+	switch format {
+	case FormatGIFT, FormatQTI:
+		q.Standards = nil
+		q.Misconceptions = nil
+	}
+	return q
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			want := sampleQuestions()
+			var buf bytes.Buffer
+			if err := Encode(format, &buf, want); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			items, err := Decode(format, buf.Bytes())
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, buf.String())
+			}
+			if len(items) != len(want) {
+				t.Fatalf("decoded %d items, want %d\n%s", len(items), len(want), buf.String())
+			}
+			for i, item := range items {
+				if item.Err != nil {
+					t.Errorf("item %d: %v", i+1, item.Err)
+					continue
+				}
+				if w := carried(format, want[i]); !reflect.DeepEqual(item.Question, w) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i+1, item.Question, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsUnknownFormat(t *testing.T) {
+	if _, err := Decode("xlsx", []byte("x")); err == nil {
+		t.Fatal("Decode accepted an unknown format")
+	}
+	if err := Encode("xlsx", &bytes.Buffer{}, nil); err == nil {
+		t.Fatal("Encode accepted an unknown format")
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		items   int
+		errs    []string // per item; "" for none
+		fileErr bool
+	}{
+		{
+			name:  "array",
+			data:  `[{"topic":"A","question":"Q?","options":["a","b"],"correct_answer":1}]`,
+			items: 1,
+			errs:  []string{""},
+		},
+		{
+			name:  "list wrapper",
+			data:  `{"total":1,"questions":[{"topic":"A","question":"Q?","options":["a","b"],"correct_answer":0}]}`,
+			items: 1,
+			errs:  []string{""},
+		},
+		{
+			name:  "per-item rejection",
+			data:  `[{"topic":"A","question":"Q?","options":["a","b"],"correct_answer":0}, {"topic":"A","question":"Q?","options":"a"}, {"topic":"","question":"Q?","options":["a","a"],"correct_answer":3}]`,
+			items: 3,
+			errs:  []string{"", "invalid question", "topic must not be empty"},
+		},
+		{name: "not JSON", data: `questions`, fileErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode(FormatJSON, []byte(tt.data))
+			if tt.fileErr {
+				if err == nil {
+					t.Fatal("want a file error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			checkItems(t, items, tt.items, tt.errs)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      models.BankQuestion
+		fields []string
+		check  func(t *testing.T, q models.BankQuestion)
+	}{
+		{
+			name: "tidies and defaults",
+			q: models.BankQuestion{
+				Topic: " Algebra ", Question: " Q? ", Difficulty: " EASY ",
+				Options: []string{" a ", "b"}, Tags: []string{"x", " x ", ""},
+			},
+			check: func(t *testing.T, q models.BankQuestion) {
+				if q.Topic != "Algebra" || q.Question != "Q?" || q.Difficulty != "easy" || q.Options[0] != "a" {
+					t.Errorf("not tidied: %+v", q)
+				}
+				if !reflect.DeepEqual(q.Tags, []string{"x"}) {
+					t.Errorf("tags = %q, want [x]", q.Tags)
+				}
+			},
+		},
+		{
+			name: "difficulty defaults to medium",
+			q:    models.BankQuestion{Topic: "A", Question: "Q?", Options: []string{"a", "b"}},
+			check: func(t *testing.T, q models.BankQuestion) {
+				if q.Difficulty != "medium" {
+					t.Errorf("difficulty = %q, want medium", q.Difficulty)
+				}
+			},
+		},
+		{
+			name:   "every problem reported",
+			q:      models.BankQuestion{Difficulty: "extreme", Options: []string{"a", "A"}, CorrectAnswer: 2},
+			fields: []string{"question", "topic", "difficulty", "options[1]", "correct_answer"},
+		},
+		{
+			name:   "misconception on the correct option",
+			q:      models.BankQuestion{Topic: "A", Question: "Q?", Options: []string{"a", "b"}, Misconceptions: []string{"wrong", ""}},
+			fields: []string{"misconceptions[0]"},
+		},
+		{
+			name:   "misconceptions must match options",
+			q:      models.BankQuestion{Topic: "A", Question: "Q?", Options: []string{"a", "b"}, Misconceptions: []string{"", "x", "y"}},
+			fields: []string{"misconceptions"},
+		},
+		{
+			name: "blank misconceptions dropped",
+			q:    models.BankQuestion{Topic: "A", Question: "Q?", Options: []string{"a", "b"}, Misconceptions: []string{"", " "}},
+			check: func(t *testing.T, q models.BankQuestion) {
+				if q.Misconceptions != nil {
+					t.Errorf("misconceptions = %q, want nil", q.Misconceptions)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.q
+			var got []string
+			for _, f := range Normalize(&q) {
+				got = append(got, f.Field)
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("fields = %q, want %q", got, tt.fields)
+			}
+			if tt.check != nil {
+				tt.check(t, q)
+			}
+		})
+	}
+}
+
+// checkItems compares decoded items with the expected count and, per item,
+// a substring of its error ("" for none).
+func checkItems(t *testing.T, items []Item, n int, errs []string) {
+	t.Helper()
+	if len(items) != n {
+		t.Fatalf("decoded %d items, want %d", len(items), n)
+	}
+	for i, want := range errs {
+		switch err := items[i].Err; {
+		case want == "" && err != nil:
+			t.Errorf("item %d: unexpected error %v", i+1, err)
+		case want != "" && err == nil:
+			t.Errorf("item %d: want error containing %q", i+1, want)
+		case want != "" && !strings.Contains(err.Error(), want):
+			t.Errorf("item %d: error %q does not contain %q", i+1, err, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bank
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"studyai/internal/models"
+)
+
+// CSV files have one question per row. Options are spread over option_1,
+// option_2, ... columns, with optional misconception_1, misconception_2, ...
+// labels for them; tags and standards are separated by semicolons, and
+// correct_answer is a 0-based index or an option letter (A, B, ...).
+var csvColumns = []string{"id", "topic", "difficulty", "tags", "standards", "source", "question"}
+
+func encodeCSV(w io.Writer, qs []models.BankQuestion) error {
+	maxOptions := 2
+	labelled := false
+	for _, q := range qs {
+		maxOptions = max(maxOptions, len(q.Options))
+		labelled = labelled || len(q.Misconceptions) > 0
+	}
+
+	cw := csv.NewWriter(w)
+	header := append([]string{}, csvColumns...)
+	for i := range maxOptions {
+		header = append(header, fmt.Sprintf("option_%d", i+1))
+	}
+	header = append(header, "correct_answer", "explanation")
+	if labelled {
+		for i := range maxOptions {
+			header = append(header, fmt.Sprintf("misconception_%d", i+1))
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, q := range qs {
+		row := []string{q.ID, q.Topic, q.Difficulty, strings.Join(q.Tags, ";"), strings.Join(q.Standards, ";"), q.Source, q.Question}
+		for i := range maxOptions {
+			opt := ""
+			if i < len(q.Options) {
+				opt = q.Options[i]
+			}
+			row = append(row, opt)
+		}
+		row = append(row, strconv.Itoa(q.CorrectAnswer), q.Explanation)
+		if labelled {
+			for i := range maxOptions {
+				label := ""
+				if i < len(q.Misconceptions) {
+					label = q.Misconceptions[i]
+				}
+				row = append(row, label)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func decodeCSV(data []byte) ([]Item, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid CSV: missing header row")
+	}
+
+	col := map[string]int{}
+	var optionCols []int
+	labelCols := map[int]string{} // option column to its misconception column
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		col[name] = i
+		if suffix, ok := strings.CutPrefix(name, "option"); ok {
+			optionCols = append(optionCols, i)
+			labelCols[i] = "misconception" + suffix
+		}
+	}
+	for _, required := range []string{"question", "topic", "correct_answer"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV: missing %q column", required)
+		}
+	}
+
+	var items []Item
+	for _, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+
+		q := models.BankQuestion{
+			ID:          get("id"),
+			Topic:       get("topic"),
+			Difficulty:  get("difficulty"),
+			Source:      get("source"),
+			Question:    get("question"),
+			Explanation: get("explanation"),
+		}
+		if tags := get("tags"); tags != "" {
+			q.Tags = strings.Split(tags, ";")
+		}
+		if standards := get("standards"); standards != "" {
+			q.Standards = strings.Split(standards, ";")
+		}
+		labelled := false
+		for _, i := range optionCols {
+			if i < len(row) && strings.TrimSpace(row[i]) != "" {
+				q.Options = append(q.Options, row[i])
+				label := get(labelCols[i])
+				q.Misconceptions = append(q.Misconceptions, label)
+				labelled = labelled || label != ""
+			}
+		}
+		if !labelled {
+			q.Misconceptions = nil
+		}
+
+		item := Item{Question: q}
+		item.Question.CorrectAnswer, item.Err = parseAnswer(get("correct_answer"))
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseAnswer reads a 0-based index or an option letter.
+func parseAnswer(s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if len(s) == 1 {
+		if c := strings.ToUpper(s)[0]; c >= 'A' && c <= 'Z' {
+			return int(c - 'A'), nil
+		}
+	}
+	return 0, fmt.Errorf("correct_answer %q must be a 0-based index or an option letter", s)
+}
@@ -0,0 +1,112 @@
+package bank
+
+import (
+	"reflect"
+	"testing"
+
+	"studyai/internal/models"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []models.BankQuestion // zero entries are not compared
+		errs    []string
+		fileErr bool
+	}{
+		{
+			name: "columns in any order, letter answer, BOM",
+			data: "\uFEFFQuestion,Option_2,Topic,Correct_Answer,Option_1,Tags,Standards\n" +
+				"Capital of France?,Paris,Geography,B,Lyon,europe; capitals,G.1;G.2\n",
+			want: []models.BankQuestion{{
+				Topic: "Geography", Difficulty: "medium", Question: "Capital of France?",
+				Options: []string{"Paris", "Lyon"}, CorrectAnswer: 1,
+				Tags: []string{"europe", "capitals"}, Standards: []string{"G.1", "G.2"},
+			}},
+			errs: []string{""},
+		},
+		{
+			name: "quoted fields and misconception labels",
+			data: "id,topic,question,option_1,option_2,option_3,correct_answer,explanation,misconception_1,misconception_2,misconception_3\n" +
+				`q1,Algebra,"Is 2, 3 a pair?",yes,no,"maybe, ""sometimes""",0,"Line one` + "\n" + `line two",,confuses,` + "\n",
+			want: []models.BankQuestion{{
+				ID: "q1", Topic: "Algebra", Difficulty: "medium", Question: "Is 2, 3 a pair?",
+				Options: []string{"yes", "no", `maybe, "sometimes"`}, CorrectAnswer: 0,
+				Explanation: "Line one\nline two", Misconceptions: []string{"", "confuses", ""},
+			}},
+			errs: []string{""},
+		},
+		{
+			name: "empty option columns skipped and blank rows ignored",
+			data: "topic,question,option_1,option_2,option_3,option_4,correct_answer\n" +
+				"A,Q1?,a,b,,,1\n" +
+				",,,,,,\n" +
+				"A,Q2?,a,,b,,0\n",
+			want: []models.BankQuestion{
+				{Topic: "A", Difficulty: "medium", Question: "Q1?", Options: []string{"a", "b"}, CorrectAnswer: 1},
+				{Topic: "A", Difficulty: "medium", Question: "Q2?", Options: []string{"a", "b"}, CorrectAnswer: 0},
+			},
+			errs: []string{"", ""},
+		},
+		{
+			name: "per-row rejection",
+			data: "topic,question,option_1,option_2,correct_answer,difficulty\n" +
+				"A,Q1?,a,b,first,\n" +
+				"A,Q2?,a,b,5,\n" +
+				"A,Q3?,a,b,0,impossible\n" +
+				"A,Q4?,a,b,A,easy\n",
+			want: []models.BankQuestion{{}, {}, {},
+				{Topic: "A", Difficulty: "easy", Question: "Q4?", Options: []string{"a", "b"}, CorrectAnswer: 0}},
+			errs: []string{"must be a 0-based index or an option letter", "correct_answer must be between 0 and 1", "difficulty must be one of", ""},
+		},
+		{name: "missing required column", data: "topic,question,option_1,option_2\nA,Q?,a,b\n", fileErr: true},
+		{name: "empty file", data: "", fileErr: true},
+		{name: "malformed quoting", data: "topic,question,correct_answer\nA,\"Q?,0\n", fileErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode(FormatCSV, []byte(tt.data))
+			if tt.fileErr {
+				if err == nil {
+					t.Fatal("want a file error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			checkItems(t, items, len(tt.want), tt.errs)
+			for i, want := range tt.want {
+				if want.Question == "" || items[i].Err != nil {
+					continue
+				}
+				if !reflect.DeepEqual(items[i].Question, want) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i+1, items[i].Question, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"3", 3, true},
+		{"a", 0, true},
+		{"C", 2, true},
+		{"AB", 0, false},
+		{"", 0, false},
+		{"?", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAnswer(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("parseAnswer(%q) = %d, %v; want %d, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
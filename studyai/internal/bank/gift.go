@@ -0,0 +1,245 @@
+package bank
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"studyai/internal/models"
+)
+
+// GIFT is Moodle's plain-text question format. Topics are written as
+// $CATEGORY lines; difficulty, tags and source, which GIFT has no syntax for,
+// travel in "// key: value" comments directly above each question. The
+// explanation is stored as general feedback (####).
+
+// giftSpecial are the characters GIFT requires to be backslash-escaped.
+const giftSpecial = `~=#{}:`
+
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(giftSpecial, r) || r == '\\' {
+			b.WriteByte('\\')
+		}
+		if r == '\n' {
+			b.WriteString(`\n`)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func giftUnescape(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped && r == 'n':
+			b.WriteByte('\n')
+		case escaped:
+			b.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		default:
+			b.WriteRune(r)
+		}
+		escaped = false
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func encodeGIFT(w io.Writer, qs []models.BankQuestion) error {
+	bw := bufio.NewWriter(w)
+	topic := ""
+	for i, q := range qs {
+		if i == 0 || q.Topic != topic {
+			topic = q.Topic
+			fmt.Fprintf(bw, "$CATEGORY: %s\n\n", topic)
+		}
+		fmt.Fprintf(bw, "// difficulty: %s\n", q.Difficulty)
+		if len(q.Tags) > 0 {
+			fmt.Fprintf(bw, "// tags: %s\n", strings.Join(q.Tags, "; "))
+		}
+		if q.Source != "" {
+			fmt.Fprintf(bw, "// source: %s\n", q.Source)
+		}
+		if q.ID != "" {
+			fmt.Fprintf(bw, "::%s::", giftEscape(q.ID))
+		}
+		fmt.Fprintf(bw, "%s {\n", giftEscape(q.Question))
+		for j, opt := range q.Options {
+			mark := "~"
+			if j == q.CorrectAnswer {
+				mark = "="
+			}
+			fmt.Fprintf(bw, "\t%s%s\n", mark, giftEscape(opt))
+		}
+		if q.Explanation != "" {
+			fmt.Fprintf(bw, "\t####%s\n", giftEscape(q.Explanation))
+		}
+		bw.WriteString("}\n\n")
+	}
+	return bw.Flush()
+}
+
+func decodeGIFT(data []byte) ([]Item, error) {
+	var items []Item
+	topic := ""
+	meta := map[string]string{}
+	var block []string
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(block, "\n"))
+		block = nil
+		if text == "" {
+			return
+		}
+		item := Item{Question: models.BankQuestion{
+			Topic:      topic,
+			Difficulty: meta["difficulty"],
+			Source:     meta["source"],
+		}}
+		if tags := meta["tags"]; tags != "" {
+			item.Question.Tags = strings.Split(tags, ";")
+		}
+		item.Err = parseGIFTQuestion(text, &item.Question)
+		items = append(items, item)
+		meta = map[string]string{}
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			// A blank line ends a question only once its answers are closed.
+			if len(block) > 0 && strings.Contains(strings.Join(block, "\n"), "}") {
+				flush()
+			}
+		case strings.HasPrefix(line, "$CATEGORY:"):
+			flush()
+			category := strings.TrimSpace(strings.TrimPrefix(line, "$CATEGORY:"))
+			// Moodle categories are paths such as $course$/top/Algebra.
+			topic = category[strings.LastIndex(category, "/")+1:]
+		case strings.HasPrefix(line, "//"):
+			if key, val, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "//")), ":"); ok {
+				meta[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(val)
+			}
+		default:
+			block = append(block, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("invalid GIFT: %w", err)
+	}
+	flush()
+	return items, nil
+}
+
+// parseGIFTQuestion fills q from one GIFT question such as
+// "::id::Question text {=right ~wrong ####explanation}".
+func parseGIFTQuestion(text string, q *models.BankQuestion) error {
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return fmt.Errorf("unterminated ::title::")
+		}
+		q.ID = giftUnescape(text[2 : 2+end])
+		text = text[2+end+2:]
+	}
+	text = strings.TrimPrefix(strings.TrimSpace(text), "[html]")
+
+	open := indexUnescaped(text, "{")
+	closing := lastIndexUnescaped(text, "}")
+	if open < 0 || closing < open {
+		return fmt.Errorf("missing {answers}")
+	}
+	q.Question = giftUnescape(text[:open] + text[closing+1:])
+
+	body := text[open+1 : closing]
+	if general := indexUnescaped(body, "####"); general >= 0 {
+		q.Explanation = giftUnescape(body[general+4:])
+		body = body[:general]
+	}
+
+	correct := -1
+	for _, tok := range splitGIFTAnswers(body) {
+		mark, answer := tok[0], tok[1:]
+		// Drop per-answer feedback ("=answer#feedback") and weights ("~%50%").
+		if fb := indexUnescaped(answer, "#"); fb >= 0 {
+			answer = answer[:fb]
+		}
+		if strings.HasPrefix(answer, "%") {
+			if end := strings.Index(answer[1:], "%"); end >= 0 {
+				answer = answer[end+2:]
+			}
+		}
+		if mark == '=' {
+			if correct >= 0 {
+				return fmt.Errorf("only single-answer multiple-choice questions are supported")
+			}
+			correct = len(q.Options)
+		}
+		q.Options = append(q.Options, giftUnescape(answer))
+	}
+	if correct < 0 {
+		return fmt.Errorf("only multiple-choice questions with one =correct answer are supported")
+	}
+	q.CorrectAnswer = correct
+	return nil
+}
+
+// splitGIFTAnswers splits an answer block at unescaped = and ~ markers,
+// keeping the marker as the first byte of each token.
+func splitGIFTAnswers(body string) []string {
+	var tokens []string
+	start := -1
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if start >= 0 {
+				tokens = append(tokens, body[start:i])
+			}
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, body[start:])
+	}
+	return tokens
+}
+
+func indexUnescaped(s, sub string) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexUnescaped(s, sub string) int {
+	last := -1
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			last = i
+		}
+	}
+	return last
+}
@@ -0,0 +1,113 @@
+package bank
+
+import (
+	"reflect"
+	"testing"
+
+	"studyai/internal/models"
+)
+
+func TestDecodeGIFT(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []models.BankQuestion // zero entries are not compared
+		errs []string
+	}{
+		{
+			name: "title, category path and metadata comments",
+			data: "$CATEGORY: $course$/top/Algebra\n\n" +
+				"// difficulty: hard\n// tags: linear; equations\n// source: Chapter 2\n" +
+				"::q1::Solve 2x = 8 {=4 ~2 ~8}\n",
+			want: []models.BankQuestion{{
+				ID: "q1", Topic: "Algebra", Difficulty: "hard", Tags: []string{"linear", "equations"}, Source: "Chapter 2",
+				Question: "Solve 2x = 8", Options: []string{"4", "2", "8"}, CorrectAnswer: 0,
+			}},
+			errs: []string{""},
+		},
+		{
+			name: "feedback and weights stripped, general feedback kept",
+			data: "$CATEGORY: Science\n\n" +
+				"Water boils at? {\n\t~%-50%90 °C#too low\n\t=100 °C#right\n\t~%0%110 °C\n\t####At sea level.\n}\n",
+			want: []models.BankQuestion{{
+				Topic: "Science", Difficulty: "medium", Question: "Water boils at?",
+				Options: []string{"90 °C", "100 °C", "110 °C"}, CorrectAnswer: 1, Explanation: "At sea level.",
+			}},
+			errs: []string{""},
+		},
+		{
+			name: "escapes and text after the answers",
+			data: "$CATEGORY: Sets\n\n" +
+				`::a\:b::Is \{x\} \= x\~? {=no \#really ~yes} really` + "\n",
+			want: []models.BankQuestion{{
+				ID: "a:b", Topic: "Sets", Difficulty: "medium", Question: "Is {x} = x~?  really",
+				Options: []string{"no #really", "yes"}, CorrectAnswer: 0,
+			}},
+			errs: []string{""},
+		},
+		{
+			name: "metadata applies to the next question only",
+			data: "$CATEGORY: A\n\n// difficulty: easy\nQ1? {=a ~b}\n\nQ2? {=a ~b}\n",
+			want: []models.BankQuestion{
+				{Topic: "A", Difficulty: "easy", Question: "Q1?", Options: []string{"a", "b"}},
+				{Topic: "A", Difficulty: "medium", Question: "Q2?", Options: []string{"a", "b"}},
+			},
+			errs: []string{"", ""},
+		},
+		{
+			name: "blank line inside answers does not split the question",
+			data: "$CATEGORY: A\n\nQ? {\n=a\n\n~b\n}\n",
+			want: []models.BankQuestion{{Topic: "A", Difficulty: "medium", Question: "Q?", Options: []string{"a", "b"}}},
+			errs: []string{""},
+		},
+		{
+			name: "unsupported questions rejected per item",
+			data: "$CATEGORY: A\n\n" +
+				"Two right? {=a =b ~c}\n\n" +
+				"True or false? {T}\n\n" +
+				"::open Title {=a ~b}\n\n" +
+				"Fine? {=a ~b}\n\n" +
+				"No answers at the end of the file\n",
+			want: []models.BankQuestion{{}, {}, {},
+				{Topic: "A", Difficulty: "medium", Question: "Fine?", Options: []string{"a", "b"}}, {}},
+			errs: []string{"only single-answer", "one =correct answer", "unterminated ::title::", "", "missing {answers}"},
+		},
+		{
+			name: "question without a category has no topic",
+			data: "Q? {=a ~b}\n",
+			want: []models.BankQuestion{{}},
+			errs: []string{"topic must not be empty"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode(FormatGIFT, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			checkItems(t, items, len(tt.want), tt.errs)
+			for i, want := range tt.want {
+				if want.Question == "" || items[i].Err != nil {
+					continue
+				}
+				if !reflect.DeepEqual(items[i].Question, want) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i+1, items[i].Question, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGIFTEscape(t *testing.T) {
+	for _, s := range []string{
+		"plain",
+		`a = b ~ c # d { e } f : g`,
+		`back\slash`,
+		"two\nlines",
+		`\n is not a newline here`,
+	} {
+		if got := giftUnescape(giftEscape(s)); got != s {
+			t.Errorf("giftUnescape(giftEscape(%q)) = %q", s, got)
+		}
+	}
+}
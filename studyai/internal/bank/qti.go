@@ -0,0 +1,202 @@
+package bank
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"studyai/internal/models"
+)
+
+// QTI 1.2 documents hold items either at the top level or inside
+// assessment/section. Topic, difficulty, tags and source are carried as
+// qtimetadata fields and the explanation as item feedback.
+
+type qtiDocument struct {
+	XMLName    xml.Name       `xml:"questestinterop"`
+	Items      []qtiItem      `xml:"item"`
+	Assessment *qtiAssessment `xml:"assessment"`
+}
+
+type qtiAssessment struct {
+	Sections []struct {
+		Items []qtiItem `xml:"item"`
+	} `xml:"section"`
+}
+
+type qtiItem struct {
+	Ident        string          `xml:"ident,attr"`
+	Title        string          `xml:"title,attr,omitempty"`
+	Metadata     []qtiField      `xml:"itemmetadata>qtimetadata>qtimetadatafield"`
+	Presentation qtiPresentation `xml:"presentation"`
+	Conditions   []qtiCondition  `xml:"resprocessing>respcondition"`
+	Feedback     []qtiFeedback   `xml:"itemfeedback"`
+}
+
+type qtiField struct {
+	Label string `xml:"fieldlabel"`
+	Entry string `xml:"fieldentry"`
+}
+
+type qtiPresentation struct {
+	Text     string      `xml:"material>mattext"`
+	Response qtiResponse `xml:"response_lid"`
+}
+
+type qtiResponse struct {
+	Ident       string     `xml:"ident,attr"`
+	Cardinality string     `xml:"rcardinality,attr"`
+	Labels      []qtiLabel `xml:"render_choice>response_label"`
+}
+
+type qtiLabel struct {
+	Ident string `xml:"ident,attr"`
+	Text  string `xml:"material>mattext"`
+}
+
+type qtiCondition struct {
+	VarEqual qtiVarEqual `xml:"conditionvar>varequal"`
+	SetVar   qtiSetVar   `xml:"setvar"`
+}
+
+type qtiVarEqual struct {
+	RespIdent string `xml:"respident,attr"`
+	Value     string `xml:",chardata"`
+}
+
+type qtiSetVar struct {
+	Action string `xml:"action,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type qtiFeedback struct {
+	Ident string `xml:"ident,attr"`
+	Text  string `xml:"material>mattext"`
+}
+
+func encodeQTI(w io.Writer, qs []models.BankQuestion) error {
+	doc := qtiDocument{}
+	for i, q := range qs {
+		ident := q.ID
+		if ident == "" {
+			ident = fmt.Sprintf("item_%d", i+1)
+		}
+		item := qtiItem{
+			Ident: ident,
+			Title: q.Topic,
+			Presentation: qtiPresentation{
+				Text:     q.Question,
+				Response: qtiResponse{Ident: "RESPONSE", Cardinality: "Single"},
+			},
+		}
+		for _, f := range []qtiField{
+			{Label: "topic", Entry: q.Topic},
+			{Label: "difficulty", Entry: q.Difficulty},
+			{Label: "tags", Entry: strings.Join(q.Tags, ";")},
+			{Label: "source", Entry: q.Source},
+		} {
+			if f.Entry != "" {
+				item.Metadata = append(item.Metadata, f)
+			}
+		}
+		for j, opt := range q.Options {
+			label := qtiLabel{Ident: optionIdent(j), Text: opt}
+			item.Presentation.Response.Labels = append(item.Presentation.Response.Labels, label)
+		}
+		item.Conditions = []qtiCondition{{
+			VarEqual: qtiVarEqual{RespIdent: "RESPONSE", Value: optionIdent(q.CorrectAnswer)},
+			SetVar:   qtiSetVar{Action: "Set", Value: "1"},
+		}}
+		if q.Explanation != "" {
+			item.Feedback = []qtiFeedback{{Ident: "general", Text: q.Explanation}}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func decodeQTI(data []byte) ([]Item, error) {
+	var doc qtiDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid QTI: %w", err)
+	}
+
+	var items []Item
+	all := doc.Items
+	if doc.Assessment != nil {
+		for _, sec := range doc.Assessment.Sections {
+			all = append(all, sec.Items...)
+		}
+	}
+	for _, qi := range all {
+		q := models.BankQuestion{
+			ID:       qi.Ident,
+			Question: qi.Presentation.Text,
+		}
+		for _, f := range qi.Metadata {
+			switch strings.ToLower(f.Label) {
+			case "topic":
+				q.Topic = f.Entry
+			case "difficulty":
+				q.Difficulty = f.Entry
+			case "tags":
+				if f.Entry != "" {
+					q.Tags = strings.Split(f.Entry, ";")
+				}
+			case "source":
+				q.Source = f.Entry
+			}
+		}
+		if q.Topic == "" {
+			q.Topic = qi.Title
+		}
+		for _, fb := range qi.Feedback {
+			if q.Explanation == "" {
+				q.Explanation = strings.TrimSpace(fb.Text)
+			}
+		}
+
+		item := Item{Question: q}
+		correct := ""
+		for _, c := range qi.Conditions {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(c.SetVar.Value), 64); err == nil && v > 0 {
+				if correct != "" {
+					item.Err = fmt.Errorf("only single-answer multiple-choice items are supported")
+				}
+				correct = strings.TrimSpace(c.VarEqual.Value)
+			}
+		}
+		item.Question.CorrectAnswer = -1
+		for j, l := range qi.Presentation.Response.Labels {
+			item.Question.Options = append(item.Question.Options, l.Text)
+			if l.Ident == correct {
+				item.Question.CorrectAnswer = j
+			}
+		}
+		if item.Err == nil && item.Question.CorrectAnswer < 0 {
+			item.Err = fmt.Errorf("item %s has no correct response", qi.Ident)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// optionIdent labels options A, B, C, ...
+func optionIdent(i int) string {
+	if i >= 0 && i < 26 {
+		return string(rune('A' + i))
+	}
+	return "OPT" + strconv.Itoa(i)
+}
@@ -0,0 +1,112 @@
+package bank
+
+import (
+	"reflect"
+	"testing"
+
+	"studyai/internal/models"
+)
+
+const qtiItemTemplate = `
+<item ident="q1" title="Fallback topic">
+  <itemmetadata><qtimetadata>
+    <qtimetadatafield><fieldlabel>topic</fieldlabel><fieldentry>Algebra</fieldentry></qtimetadatafield>
+    <qtimetadatafield><fieldlabel>Difficulty</fieldlabel><fieldentry>easy</fieldentry></qtimetadatafield>
+    <qtimetadatafield><fieldlabel>tags</fieldlabel><fieldentry>linear;equations</fieldentry></qtimetadatafield>
+  </qtimetadata></itemmetadata>
+  <presentation>
+    <material><mattext>Solve x + 1 = 3 &amp; check</mattext></material>
+    <response_lid ident="RESPONSE" rcardinality="Single"><render_choice>
+      <response_label ident="A"><material><mattext>1</mattext></material></response_label>
+      <response_label ident="B"><material><mattext>2</mattext></material></response_label>
+    </render_choice></response_lid>
+  </presentation>
+  <resprocessing>
+    <respcondition><conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar><setvar action="Set">0</setvar></respcondition>
+    <respcondition><conditionvar><varequal respident="RESPONSE">B</varequal></conditionvar><setvar action="Set">100</setvar></respcondition>
+  </resprocessing>
+  <itemfeedback ident="general"><material><mattext> Subtract 1. </mattext></material></itemfeedback>
+</item>`
+
+func TestDecodeQTI(t *testing.T) {
+	full := models.BankQuestion{
+		ID: "q1", Topic: "Algebra", Difficulty: "easy", Tags: []string{"linear", "equations"},
+		Question: "Solve x + 1 = 3 & check", Options: []string{"1", "2"}, CorrectAnswer: 1, Explanation: "Subtract 1.",
+	}
+	tests := []struct {
+		name    string
+		data    string
+		want    []models.BankQuestion // zero entries are not compared
+		errs    []string
+		fileErr bool
+	}{
+		{
+			name: "top-level item with metadata and feedback",
+			data: `<?xml version="1.0"?><questestinterop>` + qtiItemTemplate + `</questestinterop>`,
+			want: []models.BankQuestion{full},
+			errs: []string{""},
+		},
+		{
+			name: "items inside assessment sections",
+			data: `<questestinterop><assessment ident="a"><section ident="s1">` + qtiItemTemplate +
+				`</section><section ident="s2">` + qtiItemTemplate + `</section></assessment></questestinterop>`,
+			want: []models.BankQuestion{full, full},
+			errs: []string{"", ""},
+		},
+		{
+			name: "title is the topic without metadata",
+			data: `<questestinterop><item ident="q2" title="Geometry">
+				<presentation><material><mattext>Sides of a square?</mattext></material>
+				<response_lid ident="R"><render_choice>
+				<response_label ident="X"><material><mattext>4</mattext></material></response_label>
+				<response_label ident="Y"><material><mattext>3</mattext></material></response_label>
+				</render_choice></response_lid></presentation>
+				<resprocessing><respcondition><conditionvar><varequal respident="R">X</varequal></conditionvar><setvar>1</setvar></respcondition></resprocessing>
+				</item></questestinterop>`,
+			want: []models.BankQuestion{{ID: "q2", Topic: "Geometry", Difficulty: "medium", Question: "Sides of a square?", Options: []string{"4", "3"}}},
+			errs: []string{""},
+		},
+		{
+			name: "unsupported items rejected per item",
+			data: `<questestinterop>
+				<item ident="none" title="T"><presentation><material><mattext>Q?</mattext></material>
+				<response_lid><render_choice><response_label ident="A"><material><mattext>a</mattext></material></response_label>
+				<response_label ident="B"><material><mattext>b</mattext></material></response_label></render_choice></response_lid></presentation></item>
+				<item ident="two" title="T"><presentation><material><mattext>Q?</mattext></material>
+				<response_lid><render_choice><response_label ident="A"><material><mattext>a</mattext></material></response_label>
+				<response_label ident="B"><material><mattext>b</mattext></material></response_label></render_choice></response_lid></presentation>
+				<resprocessing>
+				<respcondition><conditionvar><varequal>A</varequal></conditionvar><setvar>1</setvar></respcondition>
+				<respcondition><conditionvar><varequal>B</varequal></conditionvar><setvar>1</setvar></respcondition>
+				</resprocessing></item>
+				</questestinterop>`,
+			want: []models.BankQuestion{{}, {}},
+			errs: []string{"has no correct response", "only single-answer"},
+		},
+		{name: "not XML", data: `questions`, fileErr: true},
+		{name: "wrong root element", data: `<assessmentItem/>`, fileErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode(FormatQTI, []byte(tt.data))
+			if tt.fileErr {
+				if err == nil {
+					t.Fatal("want a file error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			checkItems(t, items, len(tt.want), tt.errs)
+			for i, want := range tt.want {
+				if want.Question == "" || items[i].Err != nil {
+					continue
+				}
+				if !reflect.DeepEqual(items[i].Question, want) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i+1, items[i].Question, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+// Package config defines the server's typed configuration. Values are layered
+// as defaults < JSON file < environment < command-line flags and validated
+// once at startup; packages receive the section they need instead of reading
+// the environment themselves.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config is the complete server configuration.
+type Config struct {
+	Server     Server     `json:"server"`
+	Log        Log        `json:"log"`
+	LLM        LLM        `json:"llm"`
+	OCR        OCR        `json:"ocr"`
+	Health     Health     `json:"health"`
+	Guardrails Guardrails `json:"guardrails"`
+	Rules      Rules      `json:"rules"`
+	Quiz       Quiz       `json:"quiz"`
+	Storage    Storage    `json:"storage"`
+	Adaptive   Adaptive   `json:"adaptive"`
+	Standards  Standards  `json:"standards"`
+	Retrieval  Retrieval  `json:"retrieval"`
+	Tutor      Tutor      `json:"tutor"`
+}
+
+// Server configures the HTTP listener.
+type Server struct {
+	Addr            string   `json:"addr"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	TLSCertFile     string   `json:"tls_cert_file"`
+	TLSKeyFile      string   `json:"tls_key_file"`
+	// DrainDelay is how long /readyz reports draining before the listener
+	// closes, so load balancers see it fail and stop routing first. It
+	// should cover at least one readiness probe period.
+	DrainDelay Duration `json:"drain_delay"`
+	// AdminToken protects /admin endpoints; when empty they are open.
+	AdminToken Secret `json:"admin_token"`
+	// TeacherToken protects teacher endpoints such as question bank edits.
+	// The admin token is accepted there too.
+	TeacherToken Secret `json:"teacher_token"`
+}
+
+// Log configures structured logging.
+type Log struct {
+	Level string `json:"level"` // debug, info, warn, error
+}
+
+// LLM configures the chat-completions provider.
+type LLM struct {
+	Provider    string   `json:"provider"` // label used in metrics and traces
+	BaseURL     string   `json:"base_url"` // OpenAI-compatible API root
+	APIKey      Secret   `json:"api_key"`
+	Model       string   `json:"model"`
+	Temperature float64  `json:"temperature"`
+	Timeout     Duration `json:"timeout"`
+}
+
+// OCR configures the Vision API used for text extraction.
+type OCR struct {
+	BaseURL string   `json:"base_url"`
+	APIKey  Secret   `json:"api_key"`
+	Timeout Duration `json:"timeout"`
+}
+
+// Health configures the readiness probe.
+type Health struct {
+	CheckTimeout Duration `json:"check_timeout"`
+	CacheTTL     Duration `json:"cache_ttl"`
+}
+
+// Guardrails holds the hard limits that make the agent refuse a study plan.
+type Guardrails struct {
+	MaxDailyHours float64 `json:"max_daily_hours"`
+}
+
+// Rules holds the thresholds used to flag risks in a study plan.
+type Rules struct {
+	BurnoutDailyHours           float64 `json:"burnout_daily_hours"`
+	HighDifficultyMinDailyHours float64 `json:"high_difficulty_min_daily_hours"`
+}
+
+// Quiz holds quiz generation limits and timed-quiz enforcement defaults.
+type Quiz struct {
+	MaxQuestions int `json:"max_questions"`
+	// GracePeriod is how long after a timed quiz's limit submissions still
+	// count as on time.
+	GracePeriod Duration `json:"grace_period"`
+	// LatePolicy is "flag" to grade late submissions and mark them late, or
+	// "reject" to refuse them.
+	LatePolicy string `json:"late_policy"`
+}
+
+// Storage configures where quizzes, attempts and progress are kept.
+type Storage struct {
+	// Dir holds one JSON file per collection; empty keeps data in memory.
+	Dir string `json:"dir"`
+}
+
+// Standards configures the curriculum standards catalog.
+type Standards struct {
+	// Files are JSON or CSV catalog files imported at startup; entries
+	// replace stored standards with the same code.
+	Files []string `json:"files"`
+}
+
+// Retrieval configures how teacher-provided course materials are chunked,
+// embedded and retrieved to ground generated content.
+type Retrieval struct {
+	// EmbeddingBaseURL is the root of an OpenAI-compatible embeddings API,
+	// such as a local model server; empty ranks passages with BM25 instead.
+	EmbeddingBaseURL string   `json:"embedding_base_url"`
+	EmbeddingAPIKey  Secret   `json:"embedding_api_key"`
+	EmbeddingModel   string   `json:"embedding_model"`
+	Timeout          Duration `json:"timeout"`
+	// ChunkWords and ChunkOverlap size the passages materials are split
+	// into, in words.
+	ChunkWords   int `json:"chunk_words"`
+	ChunkOverlap int `json:"chunk_overlap"`
+	// TopK caps how many passages ground one answer.
+	TopK int `json:"top_k"`
+}
+
+// Tutor bounds the tool-using tutor agent.
+type Tutor struct {
+	// MaxSteps caps the model turns in one tutor run; the last turn is
+	// offered no tools, so the model has to answer.
+	MaxSteps int `json:"max_steps"`
+	// MaxToolCalls caps the tools one run may call across all its steps.
+	MaxToolCalls int `json:"max_tool_calls"`
+}
+
+// Adaptive tunes how quizzes are tailored to a student's history.
+type Adaptive struct {
+	// TargetSuccess is the probability of a correct answer the chosen
+	// difficulty aims for.
+	TargetSuccess float64 `json:"target_success"`
+	// HalfLife is the number of attempts after which an answer counts half.
+	HalfLife float64 `json:"half_life"`
+	// MaxTopics caps how many topics an adaptive quiz mixes.
+	MaxTopics int `json:"max_topics"`
+	// SessionTargetSE ends a test session once the ability estimate's
+	// standard error drops to this value.
+	SessionTargetSE float64 `json:"session_target_se"`
+	// SessionMinQuestions and SessionMaxQuestions bound a session's length.
+	SessionMinQuestions int `json:"session_min_questions"`
+	SessionMaxQuestions int `json:"session_max_questions"`
+}
+
+// Default returns the built-in configuration.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(120 * time.Second),
+			IdleTimeout:     Duration(60 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+			DrainDelay:      Duration(10 * time.Second),
+		},
+		Log: Log{Level: "info"},
+		LLM: LLM{
+			Provider:    "groq",
+			BaseURL:     "https://api.groq.com/openai/v1",
+			Model:       "llama-3.1-8b-instant",
+			Temperature: 0.3, // low randomness = safer explanations
+			Timeout:     Duration(10 * time.Second),
+		},
+		OCR: OCR{
+			BaseURL: "https://vision.googleapis.com/v1",
+			Timeout: Duration(30 * time.Second),
+		},
+		Health: Health{
+			CheckTimeout: Duration(3 * time.Second),
+			CacheTTL:     Duration(10 * time.Second),
+		},
+		Guardrails: Guardrails{MaxDailyHours: 16},
+		Rules: Rules{
+			BurnoutDailyHours:           8,
+			HighDifficultyMinDailyHours: 2,
+		},
+		Quiz: Quiz{MaxQuestions: 20, GracePeriod: Duration(30 * time.Second), LatePolicy: "flag"},
+		Retrieval: Retrieval{
+			Timeout:      Duration(10 * time.Second),
+			ChunkWords:   200,
+			ChunkOverlap: 40,
+			TopK:         4,
+		},
+		Tutor: Tutor{MaxSteps: 6, MaxToolCalls: 8},
+		Adaptive: Adaptive{
+			TargetSuccess: 0.7,
+			HalfLife:      10,
+			MaxTopics:     3,
+
+			SessionTargetSE:     0.6,
+			SessionMinQuestions: 5,
+			SessionMaxQuestions: 20,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be greater than zero")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be greater than zero")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be greater than zero")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be greater than zero")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "log.level must be one of debug, info, warn, error (got %q)", c.Log.Level)
+	}
+
+	check(c.LLM.BaseURL != "", "llm.base_url is required")
+	check(c.LLM.Model != "", "llm.model is required")
+	check(c.LLM.Temperature >= 0 && c.LLM.Temperature <= 2, "llm.temperature must be between 0 and 2")
+	check(c.LLM.Timeout > 0, "llm.timeout must be greater than zero")
+
+	check(c.OCR.BaseURL != "", "ocr.base_url is required")
+	check(c.OCR.Timeout > 0, "ocr.timeout must be greater than zero")
+
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be greater than zero")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl must not be negative")
+
+	check(c.Guardrails.MaxDailyHours > 0 && c.Guardrails.MaxDailyHours <= 24, "guardrails.max_daily_hours must be in (0, 24]")
+	check(c.Rules.BurnoutDailyHours > 0, "rules.burnout_daily_hours must be greater than zero")
+	check(c.Rules.HighDifficultyMinDailyHours >= 0, "rules.high_difficulty_min_daily_hours must not be negative")
+	check(c.Quiz.MaxQuestions >= 1, "quiz.max_questions must be at least 1")
+	check(c.Quiz.GracePeriod >= 0, "quiz.grace_period must not be negative")
+	check(c.Quiz.LatePolicy == "flag" || c.Quiz.LatePolicy == "reject", "quiz.late_policy must be flag or reject")
+	check(c.Adaptive.TargetSuccess > 0 && c.Adaptive.TargetSuccess < 1, "adaptive.target_success must be in (0, 1)")
+	check(c.Adaptive.HalfLife > 0, "adaptive.half_life must be greater than zero")
+	check(c.Adaptive.MaxTopics >= 1, "adaptive.max_topics must be at least 1")
+	check(c.Adaptive.SessionTargetSE > 0 && c.Adaptive.SessionTargetSE < 1, "adaptive.session_target_se must be in (0, 1)")
+	check(c.Adaptive.SessionMinQuestions >= 1, "adaptive.session_min_questions must be at least 1")
+	check(c.Adaptive.SessionMaxQuestions >= c.Adaptive.SessionMinQuestions, "adaptive.session_max_questions must not be less than adaptive.session_min_questions")
+	check((c.Retrieval.EmbeddingBaseURL == "") == (c.Retrieval.EmbeddingModel == ""), "retrieval.embedding_base_url and retrieval.embedding_model must be set together")
+	check(c.Retrieval.Timeout > 0, "retrieval.timeout must be greater than zero")
+	check(c.Retrieval.ChunkWords >= 20, "retrieval.chunk_words must be at least 20")
+	check(c.Retrieval.ChunkOverlap >= 0 && c.Retrieval.ChunkOverlap < c.Retrieval.ChunkWords, "retrieval.chunk_overlap must be in [0, retrieval.chunk_words)")
+	check(c.Retrieval.TopK >= 1, "retrieval.top_k must be at least 1")
+	check(c.Tutor.MaxSteps >= 1, "tutor.max_steps must be at least 1")
+	check(c.Tutor.MaxToolCalls >= 1, "tutor.max_tool_calls must be at least 1")
+	for _, f := range c.Standards.Files {
+		ext := strings.ToLower(filepath.Ext(f))
+		check(ext == ".json" || ext == ".csv", "standards.files must be .json or .csv files (got %q)", f)
+	}
+
+	return errors.Join(errs...)
+}
+
+// LogValue lets the whole configuration be logged; secrets redact themselves.
+func (c Config) LogValue() slog.Value {
+	type plain Config // drops this method so the value is not resolved again
+	return slog.AnyValue(plain(c))
+}
+
+// Duration is a time.Duration that reads and writes JSON as "15s" style strings.
+type Duration time.Duration
+
+// Std returns the value as a time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+const redacted = "[REDACTED]"
+
+// Secret is a string that never reveals itself when printed, logged or
+// marshalled. Use Value to obtain the underlying string.
+type Secret string
+
+// Value returns the secret itself.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Secret) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Secret(v)
+	return nil
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds one configuration value to its flag and environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func settings() []setting {
+	return []setting{
+		{"addr", "SERVER_ADDR", "listen address", setString(func(c *Config) *string { return &c.Server.Addr })},
+		{"read-timeout", "SERVER_READ_TIMEOUT", "maximum duration for reading a request", setDuration(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+		{"write-timeout", "SERVER_WRITE_TIMEOUT", "maximum duration before timing out a response; must cover chained LLM calls", setDuration(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+		{"idle-timeout", "SERVER_IDLE_TIMEOUT", "keep-alive idle timeout", setDuration(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+		{"shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT", "grace period for draining in-flight requests", setDuration(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+		{"drain-delay", "SERVER_DRAIN_DELAY", "how long readiness fails before the listener closes on shutdown", setDuration(func(c *Config) *Duration { return &c.Server.DrainDelay })},
+		{"tls-cert", "TLS_CERT_FILE", "TLS certificate file", setString(func(c *Config) *string { return &c.Server.TLSCertFile })},
+		{"tls-key", "TLS_KEY_FILE", "TLS private key file", setString(func(c *Config) *string { return &c.Server.TLSKeyFile })},
+		{"admin-token", "ADMIN_TOKEN", "bearer token required by /admin endpoints", setSecret(func(c *Config) *Secret { return &c.Server.AdminToken })},
+		{"teacher-token", "TEACHER_TOKEN", "bearer token required by teacher endpoints (the admin token also works)", setSecret(func(c *Config) *Secret { return &c.Server.TeacherToken })},
+
+		{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error (debug also emits trace spans)", setString(func(c *Config) *string { return &c.Log.Level })},
+
+		{"llm-provider", "LLM_PROVIDER", "LLM provider label used in metrics", setString(func(c *Config) *string { return &c.LLM.Provider })},
+		{"llm-base-url", "LLM_BASE_URL", "OpenAI-compatible API root", setString(func(c *Config) *string { return &c.LLM.BaseURL })},
+		{"llm-api-key", "GROQ_API_KEY", "LLM API key", setSecret(func(c *Config) *Secret { return &c.LLM.APIKey })},
+		{"llm-model", "LLM_MODEL", "LLM model name", setString(func(c *Config) *string { return &c.LLM.Model })},
+		{"llm-temperature", "LLM_TEMPERATURE", "LLM sampling temperature", setFloat(func(c *Config) *float64 { return &c.LLM.Temperature })},
+		{"llm-timeout", "LLM_TIMEOUT", "timeout for a single LLM call", setDuration(func(c *Config) *Duration { return &c.LLM.Timeout })},
+
+		{"ocr-base-url", "OCR_BASE_URL", "Vision API root", setString(func(c *Config) *string { return &c.OCR.BaseURL })},
+		{"ocr-api-key", "GEMINI_API_KEY", "Vision API key", setSecret(func(c *Config) *Secret { return &c.OCR.APIKey })},
+		{"ocr-timeout", "OCR_TIMEOUT", "timeout for a single OCR call", setDuration(func(c *Config) *Duration { return &c.OCR.Timeout })},
+
+		{"health-check-timeout", "HEALTH_CHECK_TIMEOUT", "timeout for each readiness check", setDuration(func(c *Config) *Duration { return &c.Health.CheckTimeout })},
+		{"health-cache-ttl", "HEALTH_CACHE_TTL", "how long readiness results are reused", setDuration(func(c *Config) *Duration { return &c.Health.CacheTTL })},
+
+		{"max-daily-hours", "GUARDRAILS_MAX_DAILY_HOURS", "daily study hours above which plans are refused", setFloat(func(c *Config) *float64 { return &c.Guardrails.MaxDailyHours })},
+		{"burnout-daily-hours", "RULES_BURNOUT_DAILY_HOURS", "daily study hours flagged as a burnout risk", setFloat(func(c *Config) *float64 { return &c.Rules.BurnoutDailyHours })},
+		{"high-difficulty-min-daily-hours", "RULES_HIGH_DIFFICULTY_MIN_DAILY_HOURS", "minimum daily hours for high difficulty material", setFloat(func(c *Config) *float64 { return &c.Rules.HighDifficultyMinDailyHours })},
+
+		{"quiz-max-questions", "QUIZ_MAX_QUESTIONS", "maximum questions per generated quiz", setInt(func(c *Config) *int { return &c.Quiz.MaxQuestions })},
+		{"quiz-grace-period", "QUIZ_GRACE_PERIOD", "time after a timed quiz's limit during which submissions are on time", setDuration(func(c *Config) *Duration { return &c.Quiz.GracePeriod })},
+		{"quiz-late-policy", "QUIZ_LATE_POLICY", "late timed-quiz submissions: flag (grade and mark late) or reject", setString(func(c *Config) *string { return &c.Quiz.LatePolicy })},
+
+		{"storage-dir", "STORAGE_DIR", "directory for persisted data; empty keeps it in memory", setString(func(c *Config) *string { return &c.Storage.Dir })},
+
+		{"standards-files", "STANDARDS_FILES", "comma-separated JSON or CSV standards catalog files imported at startup", setList(func(c *Config) *[]string { return &c.Standards.Files })},
+
+		{"embedding-base-url", "RETRIEVAL_EMBEDDING_BASE_URL", "OpenAI-compatible embeddings API root, such as a local model server; empty uses BM25 ranking", setString(func(c *Config) *string { return &c.Retrieval.EmbeddingBaseURL })},
+		{"embedding-api-key", "RETRIEVAL_EMBEDDING_API_KEY", "embeddings API key, if the server needs one", setSecret(func(c *Config) *Secret { return &c.Retrieval.EmbeddingAPIKey })},
+		{"embedding-model", "RETRIEVAL_EMBEDDING_MODEL", "embedding model name", setString(func(c *Config) *string { return &c.Retrieval.EmbeddingModel })},
+		{"retrieval-timeout", "RETRIEVAL_TIMEOUT", "timeout for a single embeddings call", setDuration(func(c *Config) *Duration { return &c.Retrieval.Timeout })},
+		{"chunk-words", "RETRIEVAL_CHUNK_WORDS", "words per course material passage", setInt(func(c *Config) *int { return &c.Retrieval.ChunkWords })},
+		{"chunk-overlap", "RETRIEVAL_CHUNK_OVERLAP", "words shared by consecutive passages", setInt(func(c *Config) *int { return &c.Retrieval.ChunkOverlap })},
+		{"retrieval-top-k", "RETRIEVAL_TOP_K", "maximum passages retrieved to ground one answer", setInt(func(c *Config) *int { return &c.Retrieval.TopK })},
+
+		{"tutor-max-steps", "TUTOR_MAX_STEPS", "maximum model turns in one tutor run", setInt(func(c *Config) *int { return &c.Tutor.MaxSteps })},
+		{"tutor-max-tool-calls", "TUTOR_MAX_TOOL_CALLS", "maximum tool calls in one tutor run", setInt(func(c *Config) *int { return &c.Tutor.MaxToolCalls })},
+
+		{"adaptive-target-success", "ADAPTIVE_TARGET_SUCCESS", "probability of a correct answer adaptive quizzes aim for", setFloat(func(c *Config) *float64 { return &c.Adaptive.TargetSuccess })},
+		{"adaptive-half-life", "ADAPTIVE_HALF_LIFE", "attempts after which past answers count half in ability estimates", setFloat(func(c *Config) *float64 { return &c.Adaptive.HalfLife })},
+		{"adaptive-max-topics", "ADAPTIVE_MAX_TOPICS", "maximum topics mixed into one adaptive quiz", setInt(func(c *Config) *int { return &c.Adaptive.MaxTopics })},
+		{"session-target-se", "ADAPTIVE_SESSION_TARGET_SE", "ability standard error at which a test session stops", setFloat(func(c *Config) *float64 { return &c.Adaptive.SessionTargetSE })},
+		{"session-min-questions", "ADAPTIVE_SESSION_MIN_QUESTIONS", "questions asked before a test session may stop", setInt(func(c *Config) *int { return &c.Adaptive.SessionMinQuestions })},
+		{"session-max-questions", "ADAPTIVE_SESSION_MAX_QUESTIONS", "maximum questions in a test session", setInt(func(c *Config) *int { return &c.Adaptive.SessionMaxQuestions })},
+	}
+}
+
+// Load builds the configuration from defaults, the JSON file named by -config
+// (or STUDYAI_CONFIG), environment variables and finally command-line flags,
+// then validates it. getenv is usually os.Getenv.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	all := settings()
+
+	fs := flag.NewFlagSet("studyai", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	path := fs.String("config", getenv("STUDYAI_CONFIG"), "path to a JSON configuration file (STUDYAI_CONFIG)")
+	flagValues := map[string]string{}
+	for _, s := range all {
+		fs.Func(s.flag, fmt.Sprintf("%s (%s)", s.usage, s.env), func(v string) error {
+			flagValues[s.flag] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, s := range all {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(&cfg, v); err != nil {
+				return cfg, fmt.Errorf("environment variable %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range all {
+		if v, ok := flagValues[s.flag]; ok {
+			if err := s.set(&cfg, v); err != nil {
+				return cfg, fmt.Errorf("flag -%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setList(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+func setSecret(field func(*Config) *Secret) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = Secret(v)
+		return nil
+	}
+}
+
+func setDuration(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = Duration(d)
+		return nil
+	}
+}
+
+func setFloat(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
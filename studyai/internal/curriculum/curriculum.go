@@ -0,0 +1,260 @@
+// Package curriculum models topics as a graph with prerequisite edges and
+// tracks a student's mastery of each topic from graded quiz answers. It
+// explains gaps by walking back to the unmastered prerequisites behind a
+// struggling topic, and recommends what to study next.
+package curriculum
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// Mastery levels.
+const (
+	NotStarted = "not_started"
+	Struggling = "struggling"
+	Developing = "developing"
+	Mastered   = "mastered"
+)
+
+const (
+	// learningRate is the weight of each new answer once a topic has enough
+	// history; earlier answers are averaged evenly with a neutral prior.
+	learningRate = 0.2
+	// prior is the mastery assumed before the first answer.
+	prior = 0.5
+	// strugglingBelow and masteredAt bound the developing level.
+	strugglingBelow = 0.5
+	masteredAt      = 0.8
+	// minEvidence is how many answers it takes to count as mastered.
+	minEvidence = 3
+)
+
+// Graph is a validated curriculum with its topics in prerequisite order.
+type Graph struct {
+	topics []models.CurriculumTopic
+	index  map[string]int    // topic ID to position in topics
+	names  map[string]string // normalized ID, name or alias to topic ID
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug derives a topic ID from its name.
+func slug(name string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// nameKey normalizes a topic name for lookups.
+func nameKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// New tidies and checks a curriculum: topic IDs default to a slug of the
+// name and must be unique, prerequisites must name known topics, and the
+// prerequisite edges must not form a cycle. It returns every problem found.
+func New(c models.Curriculum) (*Graph, []apperr.FieldError) {
+	var fields []apperr.FieldError
+	fail := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+
+	topics := slices.Clone(c.Topics)
+	index := map[string]int{}
+	names := map[string]string{}
+	for i := range topics {
+		t := &topics[i]
+		t.Name = strings.TrimSpace(t.Name)
+		t.Subject = strings.TrimSpace(t.Subject)
+		if t.Name == "" {
+			fail(fmt.Sprintf("topics[%d].name", i), "must not be empty")
+			continue
+		}
+		if t.ID = strings.TrimSpace(t.ID); t.ID == "" {
+			t.ID = slug(t.Name)
+		}
+		if t.ID == "" {
+			fail(fmt.Sprintf("topics[%d].id", i), "must not be empty")
+			continue
+		}
+		if _, dup := index[t.ID]; dup {
+			fail(fmt.Sprintf("topics[%d].id", i), fmt.Sprintf("%q is used by another topic", t.ID))
+			continue
+		}
+		index[t.ID] = i
+
+		var aliases []string
+		for _, a := range t.Aliases {
+			if a = strings.TrimSpace(a); a != "" && !slices.Contains(aliases, a) {
+				aliases = append(aliases, a)
+			}
+		}
+		t.Aliases = aliases
+		t.Standards = Codes(t.Standards)
+		for _, n := range append([]string{t.ID, t.Name}, aliases...) {
+			key := nameKey(n)
+			if other, ok := names[key]; ok && other != t.ID {
+				fail(fmt.Sprintf("topics[%d].aliases", i), fmt.Sprintf("%q also names topic %q", n, other))
+				continue
+			}
+			names[key] = t.ID
+		}
+	}
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	for i := range topics {
+		t := &topics[i]
+		var prereqs []string
+		for j, p := range t.Prerequisites {
+			p = strings.TrimSpace(p)
+			switch _, ok := index[p]; {
+			case !ok:
+				fail(fmt.Sprintf("topics[%d].prerequisites[%d]", i, j), fmt.Sprintf("unknown topic %q", p))
+			case p == t.ID:
+				fail(fmt.Sprintf("topics[%d].prerequisites[%d]", i, j), "a topic cannot be its own prerequisite")
+			case !slices.Contains(prereqs, p):
+				prereqs = append(prereqs, p)
+			}
+		}
+		t.Prerequisites = prereqs
+	}
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	order, cycle := sortTopics(topics, index)
+	if len(cycle) > 0 {
+		return nil, []apperr.FieldError{{Field: "topics", Message: "prerequisites form a cycle through " + strings.Join(cycle, ", ")}}
+	}
+	g := &Graph{index: map[string]int{}, names: names}
+	for _, i := range order {
+		g.index[topics[i].ID] = len(g.topics)
+		g.topics = append(g.topics, topics[i])
+	}
+	return g, nil
+}
+
+// sortTopics orders topics so each comes after its prerequisites, keeping
+// the given order otherwise. When the edges have a cycle it returns the IDs
+// of the topics that could not be placed instead.
+func sortTopics(topics []models.CurriculumTopic, index map[string]int) ([]int, []string) {
+	placed := make([]bool, len(topics))
+	var order []int
+	for len(order) < len(topics) {
+		progress := false
+		for i, t := range topics {
+			if placed[i] || slices.ContainsFunc(t.Prerequisites, func(p string) bool { return !placed[index[p]] }) {
+				continue
+			}
+			placed[i] = true
+			order = append(order, i)
+			progress = true
+		}
+		if !progress {
+			var stuck []string
+			for i, t := range topics {
+				if !placed[i] {
+					stuck = append(stuck, t.ID)
+				}
+			}
+			return nil, stuck
+		}
+	}
+	return order, nil
+}
+
+// Curriculum returns the graph's topics in prerequisite order.
+func (g *Graph) Curriculum() models.Curriculum {
+	return models.Curriculum{Topics: slices.Clone(g.topics)}
+}
+
+// Resolve returns the ID of the topic a quiz topic refers to, matching IDs,
+// names and aliases regardless of case and spacing.
+func (g *Graph) Resolve(topic string) (string, bool) {
+	id, ok := g.names[nameKey(topic)]
+	return id, ok
+}
+
+// Key is the name a quiz topic's mastery is tracked under: its curriculum
+// topic ID, or the trimmed topic itself when it is not in the curriculum.
+func (g *Graph) Key(topic string) string {
+	if id, ok := g.Resolve(topic); ok {
+		return id
+	}
+	return strings.TrimSpace(topic)
+}
+
+// Level classifies a mastery estimate based on the given number of answers.
+func Level(mastery float64, answered int) string {
+	switch {
+	case answered == 0:
+		return NotStarted
+	case mastery < strugglingBelow:
+		return Struggling
+	case mastery >= masteredAt && answered >= minEvidence:
+		return Mastered
+	default:
+		return Developing
+	}
+}
+
+// Mastery folds a student's attempts, oldest first, into a mastery estimate
+// per topic.
+func Mastery(g *Graph, attempts []models.QuizAttempt) []models.TopicMastery {
+	return fold(attempts, func(a models.QuizAttempt, item models.ItemOutcome) []string {
+		topic := item.Topic
+		if topic == "" {
+			topic = a.Topic
+		}
+		if key := g.Key(topic); key != "" {
+			return []string{key}
+		}
+		return nil
+	})
+}
+
+// fold estimates mastery of every key that keys assigns to the answers in
+// attempts, oldest first. Each answer moves the estimate towards the credit
+// it earned: the first few answers are averaged evenly with a neutral prior,
+// later ones with a fixed learning rate, so recent answers count most.
+func fold(attempts []models.QuizAttempt, keys func(models.QuizAttempt, models.ItemOutcome) []string) []models.TopicMastery {
+	byKey := map[string]*models.TopicMastery{}
+	var order []string
+	for _, a := range attempts {
+		for _, item := range a.Items {
+			for _, key := range keys(a, item) {
+				m, ok := byKey[key]
+				if !ok {
+					m = &models.TopicMastery{Topic: key, Mastery: prior}
+					byKey[key] = m
+					order = append(order, key)
+				}
+				credit := item.Credit
+				if item.Correct {
+					credit = 1 // some older attempts recorded no credit
+				}
+				m.Answered++
+				rate := math.Max(learningRate, 1/float64(m.Answered+1))
+				m.Mastery += rate * (credit - m.Mastery)
+				m.UpdatedAt = a.SubmittedAt
+			}
+		}
+	}
+
+	slices.Sort(order)
+	out := make([]models.TopicMastery, 0, len(order))
+	for _, k := range order {
+		m := *byKey[k]
+		m.Mastery = math.Round(m.Mastery*100) / 100
+		m.Level = Level(m.Mastery, m.Answered)
+		out = append(out, m)
+	}
+	return out
+}
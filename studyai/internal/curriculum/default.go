@@ -0,0 +1,20 @@
+package curriculum
+
+import "studyai/internal/models"
+
+// Default returns the built-in curriculum, used until a teacher replaces it.
+// Its aliases cover the sample quiz topics.
+func Default() models.Curriculum {
+	return models.Curriculum{Topics: []models.CurriculumTopic{
+		{ID: "arithmetic", Name: "Arithmetic", Subject: "Mathematics"},
+		{ID: "fractions", Name: "Fractions", Subject: "Mathematics", Prerequisites: []string{"arithmetic"}},
+		{ID: "exponents", Name: "Exponents", Subject: "Mathematics", Prerequisites: []string{"arithmetic"}},
+		{ID: "algebra", Name: "Algebraic expressions", Subject: "Mathematics", Aliases: []string{"Algebra", "Mathematics - Algebra"}, Prerequisites: []string{"fractions"}},
+		{ID: "linear-equations", Name: "Linear equations", Subject: "Mathematics", Prerequisites: []string{"algebra"}},
+		{ID: "factoring", Name: "Factoring", Subject: "Mathematics", Aliases: []string{"Factorisation", "Factorization"}, Prerequisites: []string{"algebra", "exponents"}},
+		{ID: "quadratics", Name: "Quadratic equations", Subject: "Mathematics", Aliases: []string{"Quadratics"}, Prerequisites: []string{"factoring", "linear-equations"}},
+		{ID: "cells", Name: "Cell biology", Subject: "Science", Aliases: []string{"Cells", "Science - Biology"}},
+		{ID: "photosynthesis", Name: "Photosynthesis", Subject: "Science", Prerequisites: []string{"cells"}},
+		{ID: "genetics", Name: "Genetics", Subject: "Science", Prerequisites: []string{"cells"}},
+	}}
+}
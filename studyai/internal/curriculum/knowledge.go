@@ -0,0 +1,170 @@
+package curriculum
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"studyai/internal/models"
+)
+
+// Map lays a student's mastery over the graph: each topic with its level and
+// whether its prerequisites are in place, the gaps behind struggling topics,
+// and the topic to study next. When subject is set only that subject's topics
+// are listed, though gaps may lead to prerequisites outside it.
+func (g *Graph) Map(studentID string, mastery []models.TopicMastery, subject string) models.KnowledgeMap {
+	byID := map[string]models.TopicMastery{}
+	km := models.KnowledgeMap{StudentID: studentID, Topics: []models.TopicNode{}, Gaps: []models.KnowledgeGap{}}
+	for _, m := range mastery {
+		if _, ok := g.index[m.Topic]; ok {
+			byID[m.Topic] = m
+		} else {
+			km.Unmapped = append(km.Unmapped, m)
+		}
+	}
+
+	for _, t := range g.topics {
+		if subject != "" && !strings.EqualFold(t.Subject, subject) {
+			continue
+		}
+		m := g.mastery(byID, t.ID)
+		km.Topics = append(km.Topics, models.TopicNode{
+			ID:            t.ID,
+			Name:          t.Name,
+			Subject:       t.Subject,
+			Prerequisites: append([]string{}, t.Prerequisites...),
+			Mastery:       m.Mastery,
+			Level:         m.Level,
+			Answered:      m.Answered,
+			Ready:         g.ready(byID, t),
+		})
+		if m.Level == Struggling {
+			km.Gaps = append(km.Gaps, g.gap(byID, t, m))
+		}
+	}
+	km.Next = g.recommend(byID, km)
+	return km
+}
+
+// mastery returns the student's mastery of a topic; topics never answered
+// are not started.
+func (g *Graph) mastery(byID map[string]models.TopicMastery, id string) models.TopicMastery {
+	if m, ok := byID[id]; ok {
+		return m
+	}
+	return models.TopicMastery{Topic: id, Level: NotStarted}
+}
+
+// ready reports whether every prerequisite of t is mastered.
+func (g *Graph) ready(byID map[string]models.TopicMastery, t models.CurriculumTopic) bool {
+	return !slices.ContainsFunc(t.Prerequisites, func(p string) bool { return g.mastery(byID, p).Level != Mastered })
+}
+
+// gap collects the unmastered prerequisites behind a struggling topic,
+// following each unmastered prerequisite back to its own, and explains them.
+func (g *Graph) gap(byID map[string]models.TopicMastery, t models.CurriculumTopic, m models.TopicMastery) models.KnowledgeGap {
+	seen := map[string]bool{}
+	var walk func(models.CurriculumTopic)
+	walk = func(t models.CurriculumTopic) {
+		for _, p := range t.Prerequisites {
+			if seen[p] || g.mastery(byID, p).Level == Mastered {
+				continue
+			}
+			seen[p] = true
+			walk(g.topics[g.index[p]])
+		}
+	}
+	walk(t)
+
+	gap := models.KnowledgeGap{Topic: t.ID, Name: t.Name, Mastery: m.Mastery, Causes: []models.PrerequisiteGap{}}
+	for _, p := range g.topics { // prerequisite order puts the most fundamental first
+		if seen[p.ID] {
+			pm := g.mastery(byID, p.ID)
+			gap.Causes = append(gap.Causes, models.PrerequisiteGap{Topic: p.ID, Name: p.Name, Mastery: pm.Mastery, Level: pm.Level})
+		}
+	}
+
+	if len(gap.Causes) == 0 {
+		gap.Explanation = fmt.Sprintf("Struggling with %s (%s) although its prerequisites are mastered; practise it directly.", t.Name, percent(m.Mastery))
+		return gap
+	}
+	var reasons []string
+	for _, c := range gap.Causes {
+		switch c.Level {
+		case NotStarted:
+			reasons = append(reasons, fmt.Sprintf("%s has not been assessed yet", c.Name))
+		case Struggling:
+			reasons = append(reasons, fmt.Sprintf("%s mastery is low (%s)", c.Name, percent(c.Mastery)))
+		default:
+			reasons = append(reasons, fmt.Sprintf("%s is not yet mastered (%s)", c.Name, percent(c.Mastery)))
+		}
+	}
+	gap.Explanation = fmt.Sprintf("Struggling with %s (%s) because %s.", t.Name, percent(m.Mastery), joinAnd(reasons))
+	return gap
+}
+
+// recommend picks the topic to study next. The most fundamental gap comes
+// first: its deepest unmastered prerequisite, or the topic itself when its
+// prerequisites are mastered. Without gaps it is the first unmastered topic
+// whose prerequisites are mastered, preferring topics already under way.
+func (g *Graph) recommend(byID map[string]models.TopicMastery, km models.KnowledgeMap) *models.TopicRecommendation {
+	rec := func(id, reason string) *models.TopicRecommendation {
+		m := g.mastery(byID, id)
+		return &models.TopicRecommendation{Topic: id, Name: g.topics[g.index[id]].Name, Mastery: m.Mastery, Level: m.Level, Reason: reason}
+	}
+
+	if len(km.Gaps) > 0 {
+		gap := km.Gaps[0]
+		if len(gap.Causes) == 0 {
+			return rec(gap.Topic, "Practise "+gap.Name+" next. "+gap.Explanation)
+		}
+		for _, c := range gap.Causes {
+			if g.ready(byID, g.topics[g.index[c.Topic]]) {
+				return rec(c.Topic, "Study "+c.Name+" first. "+gap.Explanation)
+			}
+		}
+	}
+
+	var next *models.TopicNode
+	for i, n := range km.Topics {
+		if n.Level == Mastered || !n.Ready {
+			continue
+		}
+		if n.Level != NotStarted {
+			next = &km.Topics[i]
+			break
+		}
+		if next == nil {
+			next = &km.Topics[i]
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	switch {
+	case next.Level != NotStarted:
+		return rec(next.ID, fmt.Sprintf("Keep practising %s: mastery is %s, and %s over at least %d answers counts as mastered.", next.Name, percent(next.Mastery), percent(masteredAt), minEvidence))
+	case len(next.Prerequisites) == 0:
+		return rec(next.ID, fmt.Sprintf("Start %s: it has no prerequisites.", next.Name))
+	default:
+		var names []string
+		for _, p := range next.Prerequisites {
+			names = append(names, g.topics[g.index[p]].Name)
+		}
+		return rec(next.ID, fmt.Sprintf("Start %s: its prerequisites (%s) are mastered.", next.Name, joinAnd(names)))
+	}
+}
+
+// percent formats a 0-1 share as a whole percentage.
+func percent(x float64) string {
+	return fmt.Sprintf("%d%%", int(math.Round(x*100)))
+}
+
+// joinAnd joins items as "a, b and c".
+func joinAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
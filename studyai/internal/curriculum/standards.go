@@ -0,0 +1,225 @@
+package curriculum
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// Standards catalog file formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// StandardFormats lists the supported standards catalog formats.
+var StandardFormats = []string{FormatJSON, FormatCSV}
+
+// FormatOf returns the catalog format of a file from its extension.
+func FormatOf(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("%s: unsupported standards file extension %q (want .json or .csv)", path, ext)
+	}
+}
+
+// StandardItem is one decoded standard, or the reason it could not be
+// decoded.
+type StandardItem struct {
+	Standard models.Standard
+	Err      error
+}
+
+// DecodeStandards parses a standards catalog file. JSON files hold an array
+// of standards, or an object with a "framework" applied to every entry that
+// has none and a "standards" array. CSV files have a header row naming the
+// code, framework, subject, grade and description columns. The error is
+// non-nil only when the file as a whole cannot be read; problems with
+// individual standards are set on items.
+func DecodeStandards(format string, data []byte) ([]StandardItem, error) {
+	var list []models.Standard
+	var err error
+	switch format {
+	case FormatJSON:
+		list, err = decodeStandardsJSON(data)
+	case FormatCSV:
+		list, err = decodeStandardsCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]StandardItem, len(list))
+	for i, st := range list {
+		items[i].Standard = st
+		if fields := NormalizeStandard(&items[i].Standard); len(fields) > 0 {
+			var msgs []string
+			for _, f := range fields {
+				msgs = append(msgs, f.Field+" "+f.Message)
+			}
+			items[i].Err = fmt.Errorf("%s", strings.Join(msgs, "; "))
+		}
+	}
+	return items, nil
+}
+
+func decodeStandardsJSON(data []byte) ([]models.Standard, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var list []models.Standard
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return list, nil
+	}
+	var doc struct {
+		Framework string            `json:"framework"`
+		Standards []models.Standard `json:"standards"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for i := range doc.Standards {
+		if strings.TrimSpace(doc.Standards[i].Framework) == "" {
+			doc.Standards[i].Framework = doc.Framework
+		}
+	}
+	return doc.Standards, nil
+}
+
+func decodeStandardsCSV(data []byte) ([]models.Standard, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid CSV: missing header row")
+	}
+
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	for _, required := range []string{"code", "description"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV: missing %q column", required)
+		}
+	}
+
+	var list []models.Standard
+	for _, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		list = append(list, models.Standard{
+			Code:        get("code"),
+			Framework:   get("framework"),
+			Subject:     get("subject"),
+			Grade:       get("grade"),
+			Description: get("description"),
+		})
+	}
+	return list, nil
+}
+
+// NormalizeStandard tidies st in place and returns every problem that makes
+// it unusable.
+func NormalizeStandard(st *models.Standard) []apperr.FieldError {
+	st.Code = strings.TrimSpace(st.Code)
+	st.Framework = strings.TrimSpace(st.Framework)
+	st.Subject = strings.TrimSpace(st.Subject)
+	st.Grade = strings.TrimSpace(st.Grade)
+	st.Description = strings.TrimSpace(st.Description)
+
+	var fields []apperr.FieldError
+	switch {
+	case st.Code == "":
+		fields = append(fields, apperr.FieldError{Field: "code", Message: "must not be empty"})
+	case strings.ContainsFunc(st.Code, func(r rune) bool { return r == ' ' || r == ';' || r == ',' }):
+		fields = append(fields, apperr.FieldError{Field: "code", Message: "must not contain spaces, commas or semicolons"})
+	}
+	if st.Framework == "" {
+		fields = append(fields, apperr.FieldError{Field: "framework", Message: "must not be empty"})
+	}
+	if st.Description == "" {
+		fields = append(fields, apperr.FieldError{Field: "description", Message: "must not be empty"})
+	}
+	return fields
+}
+
+// Codes trims a list of standard codes and drops blanks and repeats.
+func Codes(codes []string) []string {
+	var out []string
+	for _, c := range codes {
+		if c = strings.TrimSpace(c); c != "" && !slices.Contains(out, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// TopicStandards returns the standard codes of the curriculum topic a quiz
+// topic refers to.
+func (g *Graph) TopicStandards(topic string) []string {
+	if id, ok := g.Resolve(topic); ok {
+		return g.topics[g.index[id]].Standards
+	}
+	return nil
+}
+
+// StandardMastery estimates a student's mastery of each catalog standard
+// from their attempts, oldest first. An answer counts towards the standards
+// its question was tagged with and those of the curriculum topic it belongs
+// to. Codes that are not in catalog are skipped.
+func (g *Graph) StandardMastery(catalog map[string]models.Standard, attempts []models.QuizAttempt) []models.StandardMastery {
+	tallies := fold(attempts, func(a models.QuizAttempt, item models.ItemOutcome) []string {
+		topic := item.Topic
+		if topic == "" {
+			topic = a.Topic
+		}
+		var codes []string
+		for _, c := range Codes(append(slices.Clone(item.Standards), g.TopicStandards(topic)...)) {
+			if _, ok := catalog[c]; ok {
+				codes = append(codes, c)
+			}
+		}
+		return codes
+	})
+
+	out := make([]models.StandardMastery, 0, len(tallies))
+	for _, t := range tallies {
+		st := catalog[t.Topic]
+		out = append(out, models.StandardMastery{
+			Code:        st.Code,
+			Framework:   st.Framework,
+			Description: st.Description,
+			Mastery:     t.Mastery,
+			Level:       t.Level,
+			Answered:    t.Answered,
+			UpdatedAt:   t.UpdatedAt,
+		})
+	}
+	return out
+}
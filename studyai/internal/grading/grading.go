@@ -0,0 +1,361 @@
+// Package grading checks quiz answer keys and scores responses for every
+// question type. Single-answer types are all or nothing; multi-select,
+// ordering and matching earn partial credit, and free-response answers are
+// scored against a rubric.
+package grading
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"studyai/internal/mathexpr"
+	"studyai/internal/models"
+)
+
+// Question types.
+const (
+	SingleChoice = "single_choice"
+	MultiSelect  = "multi_select"
+	TrueFalse    = "true_false"
+	Numeric      = "numeric"
+	ShortText    = "short_text"
+	Ordering     = "ordering"
+	Matching     = "matching"
+	FreeResponse = "free_response"
+)
+
+// Types lists every supported question type.
+var Types = []string{SingleChoice, MultiSelect, TrueFalse, Numeric, ShortText, Ordering, Matching, FreeResponse}
+
+// epsilon absorbs floating-point noise when comparing numeric answers.
+const epsilon = 1e-9
+
+// TypeOf returns q's type; questions without one are single choice.
+func TypeOf(q models.QuizQuestion) string {
+	if q.Type == "" {
+		return SingleChoice
+	}
+	return q.Type
+}
+
+// Check fills in defaults (the type, and True/False options) and reports the
+// first problem with q's answer key.
+func Check(q *models.QuizQuestion) error {
+	q.Type = TypeOf(*q)
+	if q.Options == nil {
+		q.Options = []string{}
+	}
+	if strings.TrimSpace(q.Question) == "" {
+		return fmt.Errorf("question text is empty")
+	}
+	switch q.Type {
+	case SingleChoice:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("needs at least 2 options")
+		}
+		if q.CorrectAnswer < 0 || q.CorrectAnswer >= len(q.Options) {
+			return fmt.Errorf("correct_answer %d is out of range", q.CorrectAnswer)
+		}
+	case TrueFalse:
+		if len(q.Options) == 0 {
+			q.Options = []string{"True", "False"}
+		}
+		if len(q.Options) != 2 {
+			return fmt.Errorf("true_false needs exactly 2 options")
+		}
+		if q.CorrectAnswer != 0 && q.CorrectAnswer != 1 {
+			return fmt.Errorf("correct_answer must be 0 (true) or 1 (false)")
+		}
+	case MultiSelect:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("needs at least 2 options")
+		}
+		if len(q.CorrectAnswers) == 0 {
+			return fmt.Errorf("correct_answers is empty")
+		}
+		if !indicesValid(q.CorrectAnswers, len(q.Options)) {
+			return fmt.Errorf("correct_answers must be distinct option indices")
+		}
+	case Numeric:
+		if q.NumericAnswer == nil || math.IsNaN(*q.NumericAnswer) || math.IsInf(*q.NumericAnswer, 0) {
+			return fmt.Errorf("numeric_answer is missing")
+		}
+		if q.Tolerance < 0 {
+			return fmt.Errorf("tolerance must not be negative")
+		}
+	case ShortText:
+		if !slices.ContainsFunc(q.AcceptedAnswers, func(a string) bool { return normalizeText(a) != "" }) {
+			return fmt.Errorf("accepted_answers is empty")
+		}
+	case Ordering:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("needs at least 2 options")
+		}
+		if len(q.CorrectOrder) != len(q.Options) || !indicesValid(q.CorrectOrder, len(q.Options)) {
+			return fmt.Errorf("correct_order must list every option index once")
+		}
+	case Matching:
+		if len(q.Options) < 2 || len(q.MatchTargets) < 2 {
+			return fmt.Errorf("needs at least 2 options and 2 match_targets")
+		}
+		if len(q.CorrectMatches) != len(q.Options) {
+			return fmt.Errorf("correct_matches needs one target per option")
+		}
+		for _, m := range q.CorrectMatches {
+			if m < 0 || m >= len(q.MatchTargets) {
+				return fmt.Errorf("correct_matches index %d is out of range", m)
+			}
+		}
+	case FreeResponse:
+		if err := CheckRubric(q.Rubric); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", q.Type)
+	}
+	return nil
+}
+
+// indicesValid reports whether idx holds distinct indices below n.
+func indicesValid(idx []int, n int) bool {
+	seen := make(map[int]bool, len(idx))
+	for _, i := range idx {
+		if i < 0 || i >= n || seen[i] {
+			return false
+		}
+		seen[i] = true
+	}
+	return true
+}
+
+// Credit returns the share of q earned by a, from 0 to 1. Multi-select loses
+// one correct choice's worth for each wrong choice; ordering and matching
+// earn the share of items placed correctly. Free-response answers get the
+// deterministic FallbackGrade; callers with an LLM grade use GradeCredit.
+func Credit(q models.QuizQuestion, a models.QuizAnswer) float64 {
+	switch TypeOf(q) {
+	case SingleChoice, TrueFalse:
+		if len(a.Choices) == 1 && a.Choices[0] == q.CorrectAnswer {
+			return 1
+		}
+	case MultiSelect:
+		if len(q.CorrectAnswers) == 0 {
+			return 0
+		}
+		hits, misses := 0, 0
+		seen := map[int]bool{}
+		for _, c := range a.Choices {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			if slices.Contains(q.CorrectAnswers, c) {
+				hits++
+			} else {
+				misses++
+			}
+		}
+		return max(0, float64(hits-misses)/float64(len(q.CorrectAnswers)))
+	case Numeric:
+		n, ok := number(a)
+		if ok && q.NumericAnswer != nil && math.Abs(n-*q.NumericAnswer) <= q.Tolerance+epsilon {
+			return 1
+		}
+	case ShortText:
+		got := normalizeText(a.Text)
+		if got != "" && slices.ContainsFunc(q.AcceptedAnswers, func(s string) bool { return normalizeText(s) == got }) {
+			return 1
+		}
+		if got, ok := mathAnswer(a.Text); ok && slices.ContainsFunc(q.AcceptedAnswers, func(s string) bool {
+			want, ok := mathAnswer(s)
+			return ok && mathexpr.Same(got, want)
+		}) {
+			return 1
+		}
+	case Ordering:
+		return share(q.CorrectOrder, a.Choices)
+	case Matching:
+		return share(q.CorrectMatches, a.Choices)
+	case FreeResponse:
+		return GradeCredit(FallbackGrade(q, a.Text))
+	}
+	return 0
+}
+
+// Misconceptions returns the labels of the misconceptions revealed by the
+// wrong options chosen in a, for choice questions whose options carry them.
+func Misconceptions(q models.QuizQuestion, a models.QuizAnswer) []string {
+	t := TypeOf(q)
+	if (t != SingleChoice && t != TrueFalse && t != MultiSelect) || len(q.Misconceptions) != len(q.Options) {
+		return nil
+	}
+	var labels []string
+	for _, c := range a.Choices {
+		correct := c == q.CorrectAnswer
+		if t == MultiSelect {
+			correct = slices.Contains(q.CorrectAnswers, c)
+		}
+		if correct || c < 0 || c >= len(q.Options) {
+			continue
+		}
+		if m := q.Misconceptions[c]; m != "" && !slices.Contains(labels, m) {
+			labels = append(labels, m)
+		}
+	}
+	return labels
+}
+
+// share is the fraction of positions where got agrees with want.
+func share(want, got []int) float64 {
+	if len(want) == 0 {
+		return 0
+	}
+	right := 0
+	for i := range min(len(want), len(got)) {
+		if want[i] == got[i] {
+			right++
+		}
+	}
+	return float64(right) / float64(len(want))
+}
+
+// number reads a numeric response, accepting one typed into Text, where it
+// may be worked out, as in "3/4" or "x = 2^3".
+func number(a models.QuizAnswer) (float64, bool) {
+	if a.Number != nil {
+		return *a.Number, true
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(a.Text), ",", ""), 64)
+	if err != nil {
+		if ans, ok := mathAnswer(a.Text); ok && len(ans.Values) == 1 {
+			return ans.Values[0].Expr.Constant()
+		}
+	}
+	return n, err == nil
+}
+
+// normalizeText folds case, whitespace and trailing punctuation so "Paris."
+// matches "paris".
+func normalizeText(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.Trim(s, ` .!?"'`)
+}
+
+// Similarity is the overlap of two texts' content words, from 0 (nothing in
+// common) to 1 (the same words), ignoring case, punctuation and stop words.
+func Similarity(a, b string) float64 {
+	set := func(s string) map[string]bool {
+		out := map[string]bool{}
+		for _, w := range Words(s) {
+			if !stopWords[w] {
+				out[w] = true
+			}
+		}
+		return out
+	}
+	wa, wb := set(a), set(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+// AnswerText describes q's correct answer for reviews and flashcards.
+func AnswerText(q models.QuizQuestion) string {
+	switch TypeOf(q) {
+	case MultiSelect:
+		return strings.Join(pick(q.Options, q.CorrectAnswers), "; ")
+	case Numeric:
+		if q.NumericAnswer == nil {
+			return ""
+		}
+		if q.Tolerance > 0 {
+			return formatNumber(*q.NumericAnswer) + " (±" + formatNumber(q.Tolerance) + ")"
+		}
+		return formatNumber(*q.NumericAnswer)
+	case ShortText:
+		if len(q.AcceptedAnswers) > 0 {
+			return q.AcceptedAnswers[0]
+		}
+		return ""
+	case Ordering:
+		return strings.Join(pick(q.Options, q.CorrectOrder), " → ")
+	case Matching:
+		return matches(q, q.CorrectMatches)
+	case FreeResponse:
+		if q.ModelAnswer != "" {
+			return q.ModelAnswer
+		}
+		var parts []string
+		for _, c := range q.Rubric {
+			parts = append(parts, c.Description)
+		}
+		return strings.Join(parts, "; ")
+	default:
+		if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) {
+			return q.Options[q.CorrectAnswer]
+		}
+		return ""
+	}
+}
+
+// ResponseText describes a student's response to q.
+func ResponseText(q models.QuizQuestion, a models.QuizAnswer) string {
+	switch TypeOf(q) {
+	case Numeric:
+		if n, ok := number(a); ok {
+			return formatNumber(n)
+		}
+		return "(no answer)"
+	case ShortText, FreeResponse:
+		if a.Text == "" {
+			return "(no answer)"
+		}
+		return a.Text
+	case Ordering:
+		return strings.Join(pick(q.Options, a.Choices), " → ")
+	case Matching:
+		return matches(q, a.Choices)
+	default:
+		if len(a.Choices) == 0 {
+			return "(no answer)"
+		}
+		return strings.Join(pick(q.Options, a.Choices), "; ")
+	}
+}
+
+// pick returns the options at idx, skipping out-of-range indices.
+func pick(options []string, idx []int) []string {
+	var out []string
+	for _, i := range idx {
+		if i >= 0 && i < len(options) {
+			out = append(out, options[i])
+		}
+	}
+	return out
+}
+
+func matches(q models.QuizQuestion, targets []int) string {
+	var pairs []string
+	for i, opt := range q.Options {
+		target := "?"
+		if i < len(targets) && targets[i] >= 0 && targets[i] < len(q.MatchTargets) {
+			target = q.MatchTargets[targets[i]]
+		}
+		pairs = append(pairs, opt+" → "+target)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
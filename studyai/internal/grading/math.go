@@ -0,0 +1,221 @@
+package grading
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"studyai/internal/mathexpr"
+	"studyai/internal/models"
+)
+
+// CheckMath works out q's answer with mathexpr when its text is arithmetic
+// or simple algebra it can read, and reports an error when the answer key or
+// a worked step of the explanation is wrong. checked is false when q cannot
+// be checked this way; its key is then left to other checks.
+func CheckMath(q models.QuizQuestion) (checked bool, err error) {
+	p, ok := mathexpr.Read(q.Question)
+	if !ok {
+		return false, nil
+	}
+	var solution []float64
+	switch TypeOf(q) {
+	case SingleChoice, MultiSelect:
+		correct, answers, ok := correctOptions(p, q.Options)
+		if !ok {
+			return false, nil
+		}
+		if err := checkChoices(q, correct); err != nil {
+			return true, err
+		}
+		if TypeOf(q) == SingleChoice {
+			solution = values(answers[q.CorrectAnswer])
+		}
+	case TrueFalse:
+		holds, ok := p.Claim()
+		if !ok {
+			return false, nil
+		}
+		if want := map[bool]int{true: 0, false: 1}[holds]; q.CorrectAnswer != want {
+			return true, fmt.Errorf("%q is %s", p.Equation.String(), strings.ToLower(q.Options[want]))
+		}
+	case Numeric:
+		if q.NumericAnswer == nil {
+			return false, nil
+		}
+		a := mathexpr.Answer{Values: []mathexpr.Value{{Expr: mathexpr.Number(*q.NumericAnswer), Tol: q.Tolerance}}}
+		if right, err := p.Check(a); err != nil {
+			return false, nil
+		} else if !right {
+			return true, fmt.Errorf("%v is not the answer to %s", *q.NumericAnswer, problemText(p))
+		}
+		solution = []float64{*q.NumericAnswer}
+	case ShortText:
+		read := false
+		for _, s := range q.AcceptedAnswers {
+			a, err := mathexpr.ParseAnswer(s)
+			if err != nil {
+				continue
+			}
+			right, err := p.Check(a)
+			if err != nil {
+				continue
+			}
+			if !right {
+				return true, fmt.Errorf("accepted answer %q is not the answer to %s", s, problemText(p))
+			}
+			if !read {
+				solution = values(a)
+			}
+			read = true
+		}
+		if !read {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+	return true, checkSteps(p, q, solution)
+}
+
+// correctOptions reads every option as an answer to p and returns the
+// indices of the correct ones. For an equation, options giving fewer of its
+// solutions than another correct option are not counted, so "x = 2 or
+// x = 3" beats "x = 2" for x² - 5x + 6 = 0. ok is false when an option
+// cannot be read.
+func correctOptions(p mathexpr.Problem, options []string) ([]int, []mathexpr.Answer, bool) {
+	answers := make([]mathexpr.Answer, len(options))
+	var correct []int
+	for i, opt := range options {
+		a, err := mathexpr.ParseAnswer(opt)
+		if err != nil {
+			return nil, nil, false
+		}
+		right, err := p.Check(a)
+		if err != nil {
+			return nil, nil, false
+		}
+		answers[i] = a
+		if right {
+			correct = append(correct, i)
+		}
+	}
+	if p.Equation != nil {
+		most := 0
+		for _, i := range correct {
+			most = max(most, len(answers[i].Values))
+		}
+		correct = slices.DeleteFunc(correct, func(i int) bool { return len(answers[i].Values) < most })
+	}
+	return correct, answers, true
+}
+
+// checkChoices compares a choice question's key with the options found
+// correct.
+func checkChoices(q models.QuizQuestion, correct []int) error {
+	quoted := func(idx []int) string {
+		out := make([]string, len(idx))
+		for i, j := range idx {
+			out[i] = fmt.Sprintf("%q", q.Options[j])
+		}
+		return strings.Join(out, ", ")
+	}
+	switch {
+	case len(correct) == 0:
+		return fmt.Errorf("none of the options is correct")
+	case TypeOf(q) == MultiSelect:
+		key := slices.Sorted(slices.Values(q.CorrectAnswers))
+		if !slices.Equal(key, correct) {
+			return fmt.Errorf("the correct options are %s, not %s", quoted(correct), quoted(key))
+		}
+	case len(correct) > 1:
+		return fmt.Errorf("options %s are all correct", quoted(correct))
+	case correct[0] != q.CorrectAnswer:
+		return fmt.Errorf("the correct option is %q, not %q", q.Options[correct[0]], q.Options[q.CorrectAnswer])
+	}
+	return nil
+}
+
+// checkSteps checks the equations in q's explanation. Steps must hold with
+// the question's given values and the solution of its equation; steps in the
+// variables of an expression must hold for every value. Steps that restate a
+// wrong option, as when explaining a distractor, and steps in other
+// variables are skipped.
+func checkSteps(p mathexpr.Problem, q models.QuizQuestion, solution []float64) error {
+	if p.Equation != nil && p.Var != "" && len(solution) != 1 {
+		return nil
+	}
+	env := map[string]float64{}
+	for k, v := range p.Given {
+		env[k] = v
+	}
+	if p.Equation != nil && p.Var != "" {
+		env[p.Var] = solution[0]
+	}
+	for _, r := range mathexpr.Relations(q.Explanation) {
+		var holds bool
+		if p.Equation == nil {
+			exprVars := p.Expr.Vars()
+			if slices.ContainsFunc(r.Vars(), func(v string) bool { _, given := env[v]; return !given && !slices.Contains(exprVars, v) }) {
+				continue
+			}
+			holds = r.Identity(env)
+		} else {
+			if slices.ContainsFunc(r.Vars(), func(v string) bool { _, ok := env[v]; return !ok }) {
+				continue
+			}
+			h, err := r.Holds(env)
+			if err != nil {
+				continue
+			}
+			holds = h
+		}
+		if !holds && !restatesOption(r, q) {
+			return fmt.Errorf("the explanation states %q, which is false", r.String())
+		}
+	}
+	return nil
+}
+
+// restatesOption reports whether a step ends in the value of one of q's
+// options, such as "x = 17" when explaining why that option is wrong.
+func restatesOption(r mathexpr.Relation, q models.QuizQuestion) bool {
+	last, ok := r.Sides[len(r.Sides)-1].Constant()
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(q.Options, func(opt string) bool {
+		a, err := mathexpr.ParseAnswer(opt)
+		return err == nil && slices.ContainsFunc(values(a), func(v float64) bool { return mathexpr.Close(v, last) })
+	})
+}
+
+// values returns an answer's constant values.
+func values(a mathexpr.Answer) []float64 {
+	var out []float64
+	for _, v := range a.Values {
+		if x, ok := v.Expr.Constant(); ok {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// problemText names the equation or expression a problem is about.
+func problemText(p mathexpr.Problem) string {
+	if p.Equation != nil {
+		return p.Equation.String()
+	}
+	return p.Expr.String()
+}
+
+// mathAnswer reads a response or accepted answer as math when it is a
+// number, or uses an operator: "7", "3/4", "x = 7" or "5x - 5", but not a
+// formula such as "H2O".
+func mathAnswer(s string) (mathexpr.Answer, bool) {
+	a, err := mathexpr.ParseAnswer(s)
+	if err != nil || !strings.ContainsAny(s, "+-*/^=×÷−") && len(values(a)) != len(a.Values) {
+		return mathexpr.Answer{}, false
+	}
+	return a, true
+}
@@ -0,0 +1,178 @@
+package grading
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"studyai/internal/models"
+)
+
+// Grading methods recorded on a RubricGrade.
+const (
+	MethodLLM      = "llm"
+	MethodFallback = "fallback"
+	MethodTeacher  = "teacher"
+)
+
+// CheckRubric validates a free-response rubric and numbers criteria that
+// have no ID.
+func CheckRubric(rubric []models.RubricCriterion) error {
+	if len(rubric) == 0 {
+		return fmt.Errorf("rubric is empty")
+	}
+	seen := map[string]bool{}
+	for i := range rubric {
+		c := &rubric[i]
+		c.ID = strings.TrimSpace(c.ID)
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("c%d", i+1)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("rubric criterion %q is listed twice", c.ID)
+		}
+		seen[c.ID] = true
+		if strings.TrimSpace(c.Description) == "" {
+			return fmt.Errorf("rubric criterion %q has no description", c.ID)
+		}
+		if c.Points <= 0 || math.IsInf(c.Points, 0) || math.IsNaN(c.Points) {
+			return fmt.Errorf("rubric criterion %q must be worth more than 0 points", c.ID)
+		}
+	}
+	return nil
+}
+
+// MaxPoints is the total of a rubric's criteria.
+func MaxPoints(rubric []models.RubricCriterion) float64 {
+	total := 0.0
+	for _, c := range rubric {
+		total += c.Points
+	}
+	return total
+}
+
+// GradeCredit is the share of a rubric grade's points that were earned.
+func GradeCredit(g models.RubricGrade) float64 {
+	if g.MaxPoints <= 0 {
+		return 0
+	}
+	return min(1, max(0, g.Points/g.MaxPoints))
+}
+
+// Total recomputes a grade's points from its criteria.
+func Total(g *models.RubricGrade) {
+	g.Points, g.MaxPoints = 0, 0
+	for _, c := range g.Criteria {
+		g.Points += c.Points
+		g.MaxPoints += c.MaxPoints
+	}
+	g.Points = round2(g.Points)
+	g.MaxPoints = round2(g.MaxPoints)
+}
+
+// FallbackGrade scores a free-response answer without an LLM. Each criterion
+// earns the share of its keywords the answer mentions; criteria without
+// keywords are compared against the model answer's content words. The grade
+// is flagged for teacher review.
+func FallbackGrade(q models.QuizQuestion, response string) models.RubricGrade {
+	g := models.RubricGrade{
+		QuestionID:  q.ID,
+		Response:    response,
+		Method:      MethodFallback,
+		NeedsReview: true,
+	}
+	answer := Words(response)
+	for _, c := range q.Rubric {
+		score := models.CriterionScore{CriterionID: c.ID, MaxPoints: c.Points}
+		switch {
+		case strings.TrimSpace(response) == "":
+			score.Justification = "No answer was given."
+		case len(c.Keywords) > 0:
+			var found []string
+			for _, k := range c.Keywords {
+				if ContainsPhrase(answer, Words(k)) {
+					found = append(found, k)
+				}
+			}
+			score.Points = round2(c.Points * float64(len(found)) / float64(len(c.Keywords)))
+			score.Justification = fmt.Sprintf("Mentions %d of %d key terms", len(found), len(c.Keywords))
+			if len(found) > 0 {
+				score.Justification += ": " + strings.Join(found, ", ")
+			}
+			score.Justification += "."
+		case q.ModelAnswer != "":
+			overlap := coverage(answer, Words(q.ModelAnswer))
+			score.Points = round2(c.Points * overlap)
+			score.Justification = fmt.Sprintf("Covers %.0f%% of the model answer's key words.", overlap*100)
+		default:
+			score.Justification = "No keywords or model answer to grade against automatically."
+		}
+		g.Criteria = append(g.Criteria, score)
+	}
+	Total(&g)
+	return g
+}
+
+// stopWords are ignored when comparing answers with the model answer.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "were": true,
+	"which": true, "with": true,
+}
+
+// Words lower-cases s and splits it into letter/digit runs.
+func Words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ContainsPhrase reports whether phrase occurs as consecutive words of text.
+func ContainsPhrase(text, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(text); i++ {
+		match := true
+		for j, w := range phrase {
+			if text[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// coverage is the share of the reference's content words found in text.
+func coverage(text, reference []string) float64 {
+	have := map[string]bool{}
+	for _, w := range text {
+		have[w] = true
+	}
+	total, found := 0, 0
+	seen := map[string]bool{}
+	for _, w := range reference {
+		if stopWords[w] || len(w) < 3 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		total++
+		if have[w] {
+			found++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -1,25 +1,68 @@
 package guardrails
 
 import (
-    "errors"
+    "studyai/internal/apperr"
+    "studyai/internal/config"
     "studyai/internal/models"
 )
 
-func Check(req models.StudyRequest) error {
+func Check(cfg config.Guardrails, req models.StudyRequest) error {
     // Treat AvailableHours as total hours across the duration and validate per-day limits.
     if req.DurationDays <= 0 {
-        return errors.New("duration days must be greater than zero")
+        return &apperr.Error{
+            Code:    apperr.CodeValidationFailed,
+            Message: "duration days must be greater than zero",
+            Fields:  []apperr.FieldError{{Field: "duration_days", Message: "must be greater than zero"}},
+        }
     }
     hoursPerDay := float64(req.AvailableHours) / float64(req.DurationDays)
-    if hoursPerDay > 16 {
-        return errors.New("unrealistic daily study hours")
+    if hoursPerDay > cfg.MaxDailyHours {
+        return apperr.New(apperr.CodeGuardrailRefused, "unrealistic daily study hours")
     }
 
     if req.Difficulty != "low" &&
         req.Difficulty != "medium" &&
         req.Difficulty != "high" {
-        return errors.New("invalid difficulty level")
+        return &apperr.Error{
+            Code:    apperr.CodeValidationFailed,
+            Message: "invalid difficulty level",
+            Fields:  []apperr.FieldError{{Field: "difficulty", Message: "must be low, medium or high"}},
+        }
     }
 
     return nil
 }
+
+// Worked-solution levels, from least to most revealing.
+const (
+    HelpNudge    = "nudge"
+    HelpHint     = "hint"
+    HelpSolution = "solution"
+)
+
+// HelpLevels lists the worked-solution levels in the order they are revealed.
+var HelpLevels = []string{HelpNudge, HelpHint, HelpSolution}
+
+// SolutionHelp returns the most revealing worked-solution level a student
+// may see. While they sit a flagged assessment, questions from it get no
+// help at all and other questions get a nudge only, so help never hands over
+// answers mid-assessment.
+func SolutionHelp(inAssessment, fromAssessment bool) (string, error) {
+    switch {
+    case inAssessment && fromAssessment:
+        return "", apperr.New(apperr.CodeGuardrailRefused, "this question is part of an assessment in progress; help is available once it is submitted")
+    case inAssessment:
+        return HelpNudge, nil
+    default:
+        return HelpSolution, nil
+    }
+}
+
+// Tutor refuses the tutor agent to a student sitting a flagged assessment,
+// since its tools could hand them quizzes and study help mid-assessment.
+func Tutor(inAssessment bool) error {
+    if inAssessment {
+        return apperr.New(apperr.CodeGuardrailRefused, "the tutor is unavailable during an assessment in progress; it is available once the assessment is submitted")
+    }
+    return nil
+}
@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status values reported by checks and by the overall report.
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Check probes a single dependency. Critical checks make the service
+// unready when they fail; non-critical ones (e.g. the LLM provider, for which
+// deterministic fallbacks exist) only degrade it.
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report aggregates all check results.
+type Report struct {
+	Status    string    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Ready reports whether the service should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+// Checker runs dependency checks with a per-check timeout and caches the
+// report briefly so frequent probes do not hammer external APIs.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	last     Report
+	lastAt   time.Time
+	draining atomic.Bool
+}
+
+// NewChecker creates a Checker. timeout bounds each probe; results are reused
+// for ttl.
+func NewChecker(timeout, ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, ttl: ttl}
+}
+
+// SetDraining marks the service as shutting down; subsequent reports are
+// unavailable so load balancers stop routing new requests here.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Check returns the current readiness report.
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{
+			Status:    StatusUnavailable,
+			Checks:    []Result{{Name: "server", Status: StatusUnavailable, Critical: true, Error: "shutting down"}},
+			CheckedAt: time.Now(),
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.lastAt.IsZero() && time.Since(c.lastAt) < c.ttl {
+		return c.last
+	}
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results, CheckedAt: time.Now()}
+	for _, r := range results {
+		if r.Status == StatusOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = StatusUnavailable
+			break
+		}
+		report.Status = StatusDegraded
+	}
+
+	c.last = report
+	c.lastAt = time.Now()
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,541 @@
+// Package mathexpr parses and evaluates arithmetic and simple algebra:
+// numbers, single-letter variables, + - * / ^, parentheses, implicit
+// multiplication ("2x", "3(x + 1)") and sqrt. Expressions with variables are
+// compared by evaluating them at fixed sample points, which settles the
+// polynomial and rational expressions of school algebra without rewriting
+// them symbolically.
+package mathexpr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tolerance is the relative difference below which two values are equal.
+const tolerance = 1e-9
+
+// samples are the values variables take when expressions are compared.
+// They avoid small integers, where unrelated expressions often coincide.
+var samples = []float64{0.7071, 1.3, 2.17, -1.9, 3.41, -0.53, 5.9, -2.77}
+
+// maxDepth bounds how deeply an expression may nest, through parentheses,
+// signs, powers and sqrt, so that hostile input cannot exhaust the stack.
+const maxDepth = 100
+
+// minSamples is how many sample points must be defined for two expressions
+// to be judged equivalent.
+const minSamples = 4
+
+// errUndefined is returned when an expression has no real value, such as
+// after a division by zero.
+var errUndefined = errors.New("value is undefined")
+
+type tokenKind int
+
+const (
+	tokNum tokenKind = iota
+	tokVar
+	tokFunc
+	tokOp
+	tokLParen
+	tokRParen
+	tokEq
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+// replacer maps typeset symbols to the ASCII operators the lexer reads.
+var replacer = strings.NewReplacer(
+	"×", "*", "·", "*", "∙", "*", "÷", "/", "−", "-", "–", "-", "—", "-",
+	"²", "^2", "³", "^3", "√", "sqrt", "π", "pi",
+)
+
+// lex splits s into tokens. Words longer than one letter other than sqrt and
+// pi are not math, so they are an error.
+func lex(s string) ([]token, error) {
+	runes := []rune(replacer.Replace(s))
+	var toks []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(string(runes[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", string(runes[i:j]))
+			}
+			toks = append(toks, token{kind: tokNum, text: string(runes[i:j]), num: n})
+			i = j
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			switch word := string(runes[i:j]); {
+			case word == "sqrt":
+				toks = append(toks, token{kind: tokFunc, text: word})
+			case word == "pi":
+				toks = append(toks, token{kind: tokNum, text: word, num: math.Pi})
+			case j-i == 1:
+				toks = append(toks, token{kind: tokVar, text: word})
+			default:
+				return nil, fmt.Errorf("%q is not math", word)
+			}
+			i = j
+		case strings.ContainsRune("+-*/^", r):
+			toks = append(toks, token{kind: tokOp, text: string(r)})
+			i++
+		case r == '(':
+			toks = append(toks, token{kind: tokLParen, text: "("})
+			i++
+		case r == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")"})
+			i++
+		case r == '=':
+			toks = append(toks, token{kind: tokEq, text: "="})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(r))
+		}
+	}
+	return toks, nil
+}
+
+// node is one operation in a parsed expression: 'n' a number, 'v' a
+// variable, 's' a square root, '~' a negation, or a binary operator.
+type node struct {
+	op   byte
+	num  float64
+	name string
+	l, r *node
+}
+
+func (n *node) eval(vars map[string]float64) (float64, error) {
+	switch n.op {
+	case 'n':
+		return n.num, nil
+	case 'v':
+		v, ok := vars[n.name]
+		if !ok {
+			return 0, fmt.Errorf("no value for %s", n.name)
+		}
+		return v, nil
+	}
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	var v float64
+	switch n.op {
+	case '~':
+		v = -l
+	case 's':
+		v = math.Sqrt(l)
+	default:
+		r, err := n.r.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		switch n.op {
+		case '+':
+			v = l + r
+		case '-':
+			v = l - r
+		case '*':
+			v = l * r
+		case '/':
+			v = l / r
+		case '^':
+			v = math.Pow(l, r)
+		}
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errUndefined
+	}
+	return v, nil
+}
+
+func (n *node) vars(out []string) []string {
+	if n == nil {
+		return out
+	}
+	if n.op == 'v' && !slices.Contains(out, n.name) {
+		out = append(out, n.name)
+	}
+	return n.r.vars(n.l.vars(out))
+}
+
+// parser is a recursive-descent parser over tokens, lowest precedence first:
+// sums, products (including implicit ones), signs, then powers. Every
+// recursion passes through signed or primary, which bound the depth.
+type parser struct {
+	toks  []token
+	pos   int
+	depth int
+}
+
+// enter counts one more level of nesting, failing past maxDepth. Callers
+// undo it with leave.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errors.New("expression is nested too deeply")
+	}
+	return nil
+}
+
+func (p *parser) leave() { p.depth-- }
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *parser) sum() (*node, error) {
+	l, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp || (t.text != "+" && t.text != "-") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l = &node{op: t.text[0], l: l, r: r}
+	}
+}
+
+func (p *parser) product() (*node, error) {
+	l, err := p.signed()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		var op byte
+		switch {
+		case !ok:
+			return l, nil
+		case t.kind == tokOp && (t.text == "*" || t.text == "/"):
+			op = t.text[0]
+			p.pos++
+		case t.kind == tokNum || t.kind == tokVar || t.kind == tokFunc || t.kind == tokLParen:
+			op = '*' // implicit, as in 2x or 3(x + 1)
+		default:
+			return l, nil
+		}
+		r, err := p.signed()
+		if err != nil {
+			return nil, err
+		}
+		l = &node{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) signed() (*node, error) {
+	defer p.leave()
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok && t.kind == tokOp && (t.text == "-" || t.text == "+") {
+		p.pos++
+		n, err := p.signed()
+		if err != nil || t.text == "+" {
+			return n, err
+		}
+		return &node{op: '~', l: n}, nil
+	}
+	return p.power()
+}
+
+func (p *parser) power() (*node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok && t.kind == tokOp && t.text == "^" {
+		p.pos++
+		exp, err := p.signed() // right-associative: 2^3^2 is 2^(3^2)
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: '^', l: base, r: exp}, nil
+	}
+	return base, nil
+}
+
+func (p *parser) primary() (*node, error) {
+	defer p.leave()
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	t, ok := p.peek()
+	if !ok {
+		return nil, errors.New("unexpected end of expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokNum:
+		return &node{op: 'n', num: t.num}, nil
+	case tokVar:
+		return &node{op: 'v', name: t.text}, nil
+	case tokFunc:
+		arg, err := p.power() // sqrt 9 and sqrt(9)
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: 's', l: arg}, nil
+	case tokLParen:
+		n, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokRParen {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return n, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// Expr is a parsed arithmetic or algebraic expression.
+type Expr struct {
+	root *node
+	src  string
+}
+
+// String returns the text the expression was parsed from.
+func (e Expr) String() string { return e.src }
+
+// Vars lists the expression's variables in order of appearance.
+func (e Expr) Vars() []string { return e.root.vars(nil) }
+
+// Eval evaluates the expression with the given variable values.
+func (e Expr) Eval(vars map[string]float64) (float64, error) { return e.root.eval(vars) }
+
+// Constant returns the expression's value when it has no variables.
+func (e Expr) Constant() (float64, bool) {
+	if len(e.Vars()) > 0 {
+		return 0, false
+	}
+	v, err := e.Eval(nil)
+	return v, err == nil
+}
+
+// hasOperator reports whether the expression does more than name a number
+// or a variable.
+func (e Expr) hasOperator() bool { return e.root.op != 'n' && e.root.op != 'v' }
+
+// Number returns the expression for a single number.
+func Number(x float64) Expr {
+	return Expr{root: &node{op: 'n', num: x}, src: strconv.FormatFloat(x, 'g', -1, 64)}
+}
+
+// Parse parses an expression such as "2x^2 - 3(x + 1)".
+func Parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return Expr{}, err
+	}
+	return parseTokens(toks, s)
+}
+
+func parseTokens(toks []token, src string) (Expr, error) {
+	if len(toks) == 0 {
+		return Expr{}, errors.New("empty expression")
+	}
+	p := &parser{toks: toks}
+	root, err := p.sum()
+	if err != nil {
+		return Expr{}, err
+	}
+	if t, ok := p.peek(); ok {
+		return Expr{}, fmt.Errorf("unexpected %q", t.text)
+	}
+	return Expr{root: root, src: strings.TrimSpace(src)}, nil
+}
+
+// Relation is a chain of expressions stated to be equal, such as an
+// equation "2x + 3 = 11" or a worked step "x = 12 - 5 = 7".
+type Relation struct {
+	Sides []Expr
+}
+
+// ParseRelation parses two or more expressions separated by "=".
+func ParseRelation(s string) (Relation, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return Relation{}, err
+	}
+	var r Relation
+	for part := range strings.SplitSeq(replacer.Replace(s), "=") {
+		n := slices.IndexFunc(toks, func(t token) bool { return t.kind == tokEq })
+		if n < 0 {
+			n = len(toks)
+		}
+		side, err := parseTokens(toks[:n], part)
+		if err != nil {
+			return Relation{}, err
+		}
+		r.Sides = append(r.Sides, side)
+		toks = toks[min(n+1, len(toks)):]
+	}
+	if len(r.Sides) < 2 {
+		return Relation{}, errors.New("not an equation")
+	}
+	return r, nil
+}
+
+// String returns the relation as text.
+func (r Relation) String() string {
+	sides := make([]string, len(r.Sides))
+	for i, s := range r.Sides {
+		sides[i] = s.String()
+	}
+	return strings.Join(sides, " = ")
+}
+
+// Vars lists the relation's variables in order of appearance.
+func (r Relation) Vars() []string {
+	var vars []string
+	for _, s := range r.Sides {
+		for _, v := range s.Vars() {
+			if !slices.Contains(vars, v) {
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars
+}
+
+// Holds reports whether every side has the same value with the given
+// variable values.
+func (r Relation) Holds(vars map[string]float64) (bool, error) {
+	first, err := r.Sides[0].Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range r.Sides[1:] {
+		v, err := s.Eval(vars)
+		if err != nil {
+			return false, err
+		}
+		if !Close(first, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Identity reports whether the relation holds for every value of the
+// variables not given, such as "2(x + 3) = 2x + 6".
+func (r Relation) Identity(given map[string]float64) bool {
+	for _, s := range r.Sides[1:] {
+		if !Equivalent(r.Sides[0], s, given) {
+			return false
+		}
+	}
+	return true
+}
+
+// SolvedBy reports whether setting variable to x satisfies a two-sided
+// equation. With a positive tol, x is accepted when a solution lies within
+// tol of it, so rounded answers such as 0.33 for 3x = 1 pass.
+func (r Relation) SolvedBy(variable string, x, tol float64, given map[string]float64) bool {
+	if len(r.Sides) != 2 {
+		return false
+	}
+	env := map[string]float64{}
+	for k, v := range given {
+		env[k] = v
+	}
+	f := func(t float64) (float64, error) {
+		env[variable] = t
+		l, err := r.Sides[0].Eval(env)
+		if err != nil {
+			return 0, err
+		}
+		rv, err := r.Sides[1].Eval(env)
+		if err != nil {
+			return 0, err
+		}
+		if Close(l, rv) {
+			return 0, nil
+		}
+		return l - rv, nil
+	}
+	if v, err := f(x); err == nil && v == 0 {
+		return true
+	}
+	if tol <= 0 {
+		return false
+	}
+	lo, err1 := f(x - tol)
+	hi, err2 := f(x + tol)
+	return err1 == nil && err2 == nil && (lo == 0 || hi == 0 || (lo < 0) != (hi < 0))
+}
+
+// Close reports whether a and b are equal up to floating-point noise.
+func Close(a, b float64) bool {
+	return math.Abs(a-b) <= tolerance*max(1, math.Abs(a), math.Abs(b))
+}
+
+// Equivalent reports whether a and b have the same value for every value of
+// their variables, given values for some of them. The free variables are set
+// to each sample point in turn; points where either side is undefined are
+// skipped, and at least minSamples must remain.
+func Equivalent(a, b Expr, given map[string]float64) bool {
+	var free []string
+	for _, v := range append(a.Vars(), b.Vars()...) {
+		if _, ok := given[v]; !ok && !slices.Contains(free, v) {
+			free = append(free, v)
+		}
+	}
+	env := map[string]float64{}
+	for k, v := range given {
+		env[k] = v
+	}
+	if len(free) == 0 {
+		x, err1 := a.Eval(env)
+		y, err2 := b.Eval(env)
+		return err1 == nil && err2 == nil && Close(x, y)
+	}
+	defined := 0
+	for k := range samples {
+		for i, v := range free {
+			env[v] = samples[(k+3*i)%len(samples)]
+		}
+		x, err1 := a.Eval(env)
+		y, err2 := b.Eval(env)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if !Close(x, y) {
+			return false
+		}
+		defined++
+	}
+	return defined >= minSamples
+}
@@ -0,0 +1,225 @@
+package mathexpr
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{"x": 3, "y": 2}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		// precedence and associativity
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"2 + 3^2", 11},
+		{"2^3^2", 512},
+		{"2 * 3^2", 18},
+		// unary minus
+		{"-3", -3},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^-1", 0.5},
+		{"4 - -2", 6},
+		{"--3", 3},
+		{"+3", 3},
+		{"-x + y", -1},
+		{"2 * -x", -6},
+		// implicit multiplication
+		{"2x", 6},
+		{"3(x + 1)", 12},
+		{"(x + 1)(x - 1)", 8},
+		{"x y", 6},
+		{"2x y^2", 24},
+		{"1/2x", 1.5},
+		{"2 sqrt 9", 6},
+		{"sqrt(16)x", 12},
+		// typeset symbols and constants
+		{"3 × 4 ÷ 2", 6},
+		{"x² − 1", 8},
+		{"√9", 3},
+		{"2π", 6.283185307179586},
+		{".5 + 1.25", 1.75},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if !Close(got, tt.want) {
+			t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalUndefined(t *testing.T) {
+	for _, s := range []string{"1/0", "0/0", "1/(x - 3)", "sqrt(-1)", "(x - 4)^0.5", "0^-1"} {
+		e, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		if _, err := e.Eval(map[string]float64{"x": 3}); !errors.Is(err, errUndefined) {
+			t.Errorf("Eval(%q) error = %v, want %v", s, err, errUndefined)
+		}
+	}
+	e, _ := Parse("x + y")
+	if _, err := e.Eval(map[string]float64{"x": 1}); err == nil {
+		t.Error("Eval without a value for y succeeded")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"   ",
+		"2 +",
+		"* 2",
+		"2 ** 3",
+		"(2 + 3",
+		"2 + 3)",
+		"()",
+		"sqrt",
+		"1..2",
+		"2 $ 3",
+		"two + 3",
+		"2xy",
+		"x = 3",
+		"2^",
+	} {
+		if e, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", s, e)
+		}
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	if _, err := Parse(strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20)); err != nil {
+		t.Errorf("Parse of 20 nested parentheses: %v", err)
+	}
+	for _, s := range []string{
+		strings.Repeat("(", 100000),
+		strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000),
+		strings.Repeat("-", 1000) + "1",
+		strings.Repeat("sqrt ", 1000) + "4",
+		strings.Repeat("2^", 1000) + "2",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse accepted %.20q... nested %d deep", s, len(s))
+		}
+	}
+}
+
+func TestVarsAndConstant(t *testing.T) {
+	e, err := Parse("2x + y(x - z)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.Vars(), []string{"x", "y", "z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars = %q, want %q", got, want)
+	}
+	if _, ok := e.Constant(); ok {
+		t.Error("Constant succeeded for an expression with variables")
+	}
+	c, _ := Parse("12 - 5")
+	if v, ok := c.Constant(); !ok || v != 7 {
+		t.Errorf("Constant = %v, %v; want 7, true", v, ok)
+	}
+	u, _ := Parse("1/0")
+	if _, ok := u.Constant(); ok {
+		t.Error("Constant succeeded for an undefined expression")
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		given map[string]float64
+		want  bool
+	}{
+		{"2(x + 3)", "2x + 6", nil, true},
+		{"(x + 1)^2", "x^2 + 2x + 1", nil, true},
+		{"x/x", "1", nil, true},
+		{"(x^2 - 1)/(x - 1)", "x + 1", nil, true},
+		{"x y", "y(x)", nil, true},
+		{"2x + 1", "2x - 1", nil, false},
+		{"x^2", "2x", nil, false},
+		{"x + y", "5", map[string]float64{"x": 2, "y": 3}, true},
+		{"x + y", "y + 2", map[string]float64{"x": 2}, true},
+		{"1/0", "1/0", nil, false},
+		{"sqrt(x - 100)", "sqrt(x - 100)", nil, false},
+	}
+	for _, tt := range tests {
+		a, err1 := Parse(tt.a)
+		b, err2 := Parse(tt.b)
+		if err1 != nil || err2 != nil {
+			t.Errorf("Parse(%q, %q): %v, %v", tt.a, tt.b, err1, err2)
+			continue
+		}
+		if got := Equivalent(a, b, tt.given); got != tt.want {
+			t.Errorf("Equivalent(%q, %q, %v) = %v, want %v", tt.a, tt.b, tt.given, got, tt.want)
+		}
+	}
+}
+
+func TestParseRelation(t *testing.T) {
+	r, err := ParseRelation("x = 12 - 5 = 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Sides) != 3 || r.String() != "x = 12 - 5 = 7" {
+		t.Errorf("ParseRelation = %d sides %q", len(r.Sides), r)
+	}
+	if ok, err := r.Holds(map[string]float64{"x": 7}); !ok || err != nil {
+		t.Errorf("Holds(x=7) = %v, %v; want true", ok, err)
+	}
+	if ok, _ := r.Holds(map[string]float64{"x": 6}); ok {
+		t.Error("Holds(x=6) = true")
+	}
+
+	for _, s := range []string{"2x + 3", "= 4", "x =", "x = (1", "x == 2"} {
+		if _, err := ParseRelation(s); err == nil {
+			t.Errorf("ParseRelation(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSolvedBy(t *testing.T) {
+	tests := []struct {
+		eq   string
+		x    float64
+		tol  float64
+		want bool
+	}{
+		{"2x + 3 = 11", 4, 0, true},
+		{"2x + 3 = 11", 5, 0, false},
+		{"3x = 1", 0.33, 0.005, true},
+		{"3x = 1", 0.33, 0, false},
+		{"3x = 1", 0.3, 0.005, false},
+		{"x^2 = 4", -2, 0, true},
+		{"1/x = 0", 0, 0, false},
+		{"x = 1 = 1", 1, 0, false},
+	}
+	for _, tt := range tests {
+		r, err := ParseRelation(tt.eq)
+		if err != nil {
+			t.Errorf("ParseRelation(%q): %v", tt.eq, err)
+			continue
+		}
+		if got := r.SolvedBy("x", tt.x, tt.tol, nil); got != tt.want {
+			t.Errorf("%q SolvedBy(%v, tol %v) = %v, want %v", tt.eq, tt.x, tt.tol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package mathexpr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// outOfScope matches wording that changes what a question asks of its math,
+// such as a derivative or a count of solutions. Questions using it are not
+// read, so they are never judged against the wrong question.
+var outOfScope = regexp.MustCompile(`(?i)\b(derivative|differentiat\w*|integra(l|te|tion)|slope|gradient|intercepts?|vertex|` +
+	`how many|degree|coefficients?|remainder|quotient|reciprocal|inverse|absolute|percent(age)?|mean|median|mode|average|` +
+	`probability|digits?|primes?|multiples?|factors of|sum of|product of|difference of|round(ed)?|estimate|approximate(ly)?|nearest|` +
+	`decimal places?|significant|greater|less|larger|smaller|compare|inequalit(y|ies)|not|except|incorrect|domain|range|` +
+	`graph|area|perimeter|volume|angle|log|ln|sin|cos|tan|mod)\b|n't|[%<>≤≥≠|!±≈]`)
+
+// Problem is the math a question asks about: an equation to solve, a claim
+// to judge, or an expression to evaluate or rewrite, with any values the
+// question gives for its variables ("If x = 3, what is 2x + 1?").
+type Problem struct {
+	Equation *Relation          // the equation or claim; nil for an expression
+	Var      string             // the variable to solve for; empty for a claim
+	Expr     Expr               // the expression, when Equation is nil
+	Given    map[string]float64 // values the question gives
+}
+
+// Read finds the math in a question. It succeeds only when the question
+// holds exactly one equation or expression besides any given values, and
+// uses none of the wording in outOfScope.
+func Read(text string) (Problem, bool) {
+	if outOfScope.MatchString(text) {
+		return Problem{}, false
+	}
+
+	p := Problem{Given: map[string]float64{}}
+	var relations, assignments []Relation
+	var exprs []Expr
+	for _, seg := range segments(text) {
+		if r, err := ParseRelation(seg); err == nil {
+			if v, x, ok := assignment(r); ok {
+				p.Given[v] = x
+				assignments = append(assignments, r)
+				continue
+			}
+			relations = append(relations, r)
+		} else if e, err := Parse(seg); err == nil && e.hasOperator() {
+			exprs = append(exprs, e)
+		}
+	}
+	if len(relations)+len(exprs) == 0 && len(assignments) == 1 {
+		// "Solve x = 12 - 5": the only equation already names its variable.
+		relations, p.Given = assignments, map[string]float64{}
+	}
+	if len(relations)+len(exprs) != 1 {
+		return Problem{}, false
+	}
+
+	if len(exprs) == 1 {
+		p.Expr = exprs[0]
+		return p, true
+	}
+	r := relations[0]
+	var free []string
+	for _, v := range r.Vars() {
+		if _, ok := p.Given[v]; !ok {
+			free = append(free, v)
+		}
+	}
+	if len(free) > 1 {
+		return Problem{}, false
+	}
+	p.Equation = &r
+	if len(free) == 1 {
+		p.Var = free[0]
+	}
+	return p, true
+}
+
+// assignment reads a relation such as "x = 3" as a variable's value.
+func assignment(r Relation) (string, float64, bool) {
+	if len(r.Sides) != 2 {
+		return "", 0, false
+	}
+	for _, sides := range [][2]Expr{{r.Sides[0], r.Sides[1]}, {r.Sides[1], r.Sides[0]}} {
+		if sides[0].root.op == 'v' {
+			if x, ok := sides[1].Constant(); ok {
+				return sides[0].root.name, x, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// Claim reports whether a problem with no unknowns, such as "3 × 4 = 12",
+// is true. ok is false for other problems.
+func (p Problem) Claim() (holds, ok bool) {
+	if p.Equation == nil || p.Var != "" {
+		return false, false
+	}
+	holds, err := p.Equation.Holds(p.Given)
+	return holds, err == nil
+}
+
+// Value is one value of an answer, with the tolerance its precision implies:
+// 0.005 for a value written as 0.33, and none for 1/3 or 7.
+type Value struct {
+	Expr Expr
+	Tol  float64
+}
+
+// Answer is a stated answer: values for a variable ("x = 7", "x = 2 or
+// x = -2") or a single expression ("7", "5x - 5").
+type Answer struct {
+	Var    string
+	Values []Value
+}
+
+// maxAnswerRunes bounds the length of an answer read as math. Answers are
+// typed by students, and school math answers are short.
+const maxAnswerRunes = 200
+
+var answerSep = regexp.MustCompile(`(?i)\s*(?:,|;|\bor\b|\band\b)\s*`)
+
+// ParseAnswer reads an answer such as "x = 2 or x = -2", "7" or "5x - 5".
+func ParseAnswer(s string) (Answer, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), ".")
+	if utf8.RuneCountInString(s) > maxAnswerRunes {
+		return Answer{}, fmt.Errorf("answer is longer than %d characters", maxAnswerRunes)
+	}
+	var a Answer
+	for _, part := range answerSep.Split(s, -1) {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		value := part
+		if name, rhs, ok := strings.Cut(part, "="); ok {
+			name = strings.TrimSpace(name)
+			if len([]rune(name)) != 1 || !unicode.IsLetter([]rune(name)[0]) || (a.Var != "" && a.Var != name) {
+				return Answer{}, fmt.Errorf("%q does not give a variable's value", part)
+			}
+			a.Var, value = name, rhs
+		}
+		e, err := Parse(value)
+		if err != nil {
+			return Answer{}, err
+		}
+		a.Values = append(a.Values, Value{Expr: e, Tol: precision(value)})
+	}
+	if len(a.Values) == 0 {
+		return Answer{}, errors.New("empty answer")
+	}
+	return a, nil
+}
+
+var decimal = regexp.MustCompile(`^\s*-?\d*\.(\d+)\s*$`)
+
+// precision is half a unit in the last place of a decimal literal.
+func precision(s string) float64 {
+	m := decimal.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	return 0.5 * math.Pow(10, -float64(len(m[1])))
+}
+
+// Check reports whether a is a correct answer to p. For an equation every
+// value must solve it; for an expression the answer must be equivalent to
+// it. An error means a does not answer this kind of problem.
+func (p Problem) Check(a Answer) (bool, error) {
+	switch {
+	case p.Equation != nil && p.Var == "":
+		return false, errors.New("the question is a claim, not a problem to answer")
+	case p.Equation != nil:
+		if a.Var != "" && a.Var != p.Var {
+			return false, fmt.Errorf("the answer gives %s, the equation is in %s", a.Var, p.Var)
+		}
+		for _, v := range a.Values {
+			x, ok := v.Expr.Constant()
+			if !ok || !p.Equation.SolvedBy(p.Var, x, v.Tol, p.Given) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	if a.Var != "" || len(a.Values) != 1 {
+		return false, errors.New("the answer is not a single expression")
+	}
+	v := a.Values[0]
+	if want, err := p.Expr.Eval(p.Given); err == nil && v.Tol > 0 {
+		got, ok := v.Expr.Constant()
+		return ok && math.Abs(got-want) <= v.Tol, nil
+	}
+	return Equivalent(p.Expr, v.Expr, p.Given), nil
+}
+
+// Same reports whether two answers are the same: values for the same
+// variable, or none, with every value of each equivalent to one of the other.
+func Same(a, b Answer) bool {
+	if a.Var != b.Var && a.Var != "" && b.Var != "" {
+		return false
+	}
+	covers := func(x, y Answer) bool {
+		return !slices.ContainsFunc(x.Values, func(v Value) bool {
+			return !slices.ContainsFunc(y.Values, func(w Value) bool { return Equivalent(v.Expr, w.Expr, nil) })
+		})
+	}
+	return covers(a, b) && covers(b, a)
+}
+
+// Relations finds the equations and worked steps in text, such as
+// "x = 12 - 5 = 7" in an explanation.
+func Relations(text string) []Relation {
+	var out []Relation
+	for _, seg := range segments(text) {
+		if r, err := ParseRelation(seg); err == nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// segments splits text into runs that could be math: numbers, operators,
+// parentheses, "=" and single-letter variables. Words, commas, colons and
+// question marks end a run, and sentence punctuation is trimmed from its
+// ends.
+func segments(text string) []string {
+	runes := []rune(replacer.Replace(text))
+	var out []string
+	var cur strings.Builder
+	flush := func() {
+		seg := strings.Trim(cur.String(), " .=")
+		if strings.ContainsFunc(seg, func(r rune) bool { return unicode.IsDigit(r) || unicode.IsLetter(r) }) {
+			out = append(out, seg)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			if j-i == 1 || word == "sqrt" || word == "pi" {
+				cur.WriteString(word)
+			} else {
+				flush()
+			}
+			i = j - 1
+		case unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune("+-*/^()=.", r):
+			cur.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return out
+}
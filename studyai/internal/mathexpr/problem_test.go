@@ -0,0 +1,104 @@
+package mathexpr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadAndCheck(t *testing.T) {
+	tests := []struct {
+		question string
+		answer   string
+		want     bool
+	}{
+		{"Solve 2x + 3 = 11.", "x = 4", true},
+		{"Solve 2x + 3 = 11.", "4", true},
+		{"Solve 2x + 3 = 11.", "x = 5", false},
+		{"Solve x^2 = 4", "x = 2 or x = -2", true},
+		{"Solve x^2 = 4", "x = 2 or x = 3", false},
+		{"Solve 3x = 1", "0.33", true},
+		{"Solve 3x = 1", "1/3", true},
+		{"Simplify 2(x + 3)", "2x + 6", true},
+		{"Simplify 2(x + 3)", "2x + 3", false},
+		{"If x = 3, what is 2x + 1?", "7", true},
+		{"If x = 3, what is 2x + 1?", "6", false},
+		{"What is 12 ÷ 4 × 3?", "9", true},
+		{"Solve x = 12 - 5", "x = 7", true},
+	}
+	for _, tt := range tests {
+		p, ok := Read(tt.question)
+		if !ok {
+			t.Errorf("Read(%q) failed", tt.question)
+			continue
+		}
+		a, err := ParseAnswer(tt.answer)
+		if err != nil {
+			t.Errorf("ParseAnswer(%q): %v", tt.answer, err)
+			continue
+		}
+		got, err := p.Check(a)
+		if err != nil {
+			t.Errorf("Check(%q, %q): %v", tt.question, tt.answer, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Check(%q, %q) = %v, want %v", tt.question, tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestReadSkips(t *testing.T) {
+	for _, q := range []string{
+		"What is the derivative of x^2?",
+		"How many solutions does x^2 = 4 have?",
+		"Which is not equal to 2x?",
+		"Solve 2x + y = 3",
+		"Solve x + 1 = 2 and x + 2 = 3",
+		"Name the capital of France.",
+	} {
+		if p, ok := Read(q); ok {
+			t.Errorf("Read(%q) = %+v, want no problem", q, p)
+		}
+	}
+}
+
+func TestClaim(t *testing.T) {
+	tests := []struct {
+		question  string
+		holds, ok bool
+	}{
+		{"True or false: 3 × 4 = 12", true, true},
+		{"True or false: 3 × 4 = 13", false, true},
+		{"Solve 2x = 4", false, false},
+	}
+	for _, tt := range tests {
+		p, ok := Read(tt.question)
+		if !ok {
+			t.Errorf("Read(%q) failed", tt.question)
+			continue
+		}
+		if holds, ok := p.Claim(); holds != tt.holds || ok != tt.ok {
+			t.Errorf("Claim(%q) = %v, %v; want %v, %v", tt.question, holds, ok, tt.holds, tt.ok)
+		}
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	for _, s := range []string{"", "x = 2 or y = 3", "xy = 2", "seven", "2 +", strings.Repeat("1+", 150) + "1"} {
+		if a, err := ParseAnswer(s); err == nil {
+			t.Errorf("ParseAnswer(%q) = %+v, want an error", s, a)
+		}
+	}
+	a, err := ParseAnswer("x = 2, x = -2.")
+	if err != nil || a.Var != "x" || len(a.Values) != 2 {
+		t.Fatalf("ParseAnswer = %+v, %v", a, err)
+	}
+	b, _ := ParseAnswer("-2 or 2")
+	if !Same(a, b) {
+		t.Error("Same(x = 2, x = -2; -2 or 2) = false")
+	}
+	c, _ := ParseAnswer("x = 2")
+	if Same(a, c) {
+		t.Error("Same(x = 2, x = -2; x = 2) = true")
+	}
+}
@@ -1,18 +1,31 @@
 package media
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"studyai/internal/ai"
+	"slices"
+	"strings"
+
 	"studyai/internal/models"
+	"studyai/internal/telemetry"
 )
 
 // AnalyzeEducationalContent analyzes extracted text from images/PDFs and generates educational outputs
-func AnalyzeEducationalContent(extractedText string, req models.ImageAnalysisRequest) (models.ImageAnalysisResponse, error) {
+func (s *Service) AnalyzeEducationalContent(ctx context.Context, extractedText string, req models.ImageAnalysisRequest) (models.ImageAnalysisResponse, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.AnalyzeEducationalContent")
+	defer span.End()
+
 	response := models.ImageAnalysisResponse{
 		Disclaimer: "AI-generated recommendations are advisory only. Always verify content with certified educators.",
 	}
 
+	// Ground the revision questions, study plan and tips in the course
+	// material passages most relevant to the content
+	citations, _ := s.retrieve(ctx, extractedText)
+	response.Citations = citations
+	grounding := groundingPrompt(citations, "Use these course materials where they cover the content, and cite them as [1], [2] in the text they support.")
+
 	// Extract questions from the content
 	questionsPrompt := fmt.Sprintf(`
 From the following text extracted from an image or document, identify and list ALL questions or problems present:
@@ -24,7 +37,7 @@ Return ONLY a JSON array of strings with the questions. Example format:
 ["What is photosynthesis?", "Define mitochondria", "Solve: 2x + 5 = 15"]
 `, extractedText)
 
-	questionsJSON, err := ai.CallLLMJSON(questionsPrompt)
+	questionsJSON, err := s.llm.CallJSON(ctx, "analyze.questions", questionsPrompt)
 	if err == nil {
 		var questions []string
 		if err := json.Unmarshal([]byte(questionsJSON), &questions); err == nil {
@@ -43,11 +56,11 @@ STUDENT CONTEXT:
 Grade: %d
 Age: %d
 Weak Areas: %s
-
+%s
 Return ONLY a JSON array of strings with well-structured revision questions in increasing difficulty.
-`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas)
+`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas, grounding)
 
-	revisionJSON, err := ai.CallLLMJSON(revisionPrompt)
+	revisionJSON, err := s.llm.CallJSON(ctx, "analyze.revision", revisionPrompt)
 	if err == nil {
 		var revisions []string
 		if err := json.Unmarshal([]byte(revisionJSON), &revisions); err == nil {
@@ -55,8 +68,18 @@ Return ONLY a JSON array of strings with well-structured revision questions in i
 		}
 	}
 
-	// Recommend learning materials
-	materialsPrompt := fmt.Sprintf(`
+	// Recommend learning materials: catalog entries when any fit, otherwise
+	// LLM suggestions labeled unverified
+	var topics []string
+	for _, t := range append([]string{req.QuizTopic}, strings.Split(req.WeakAreas, ",")...) {
+		if t = strings.TrimSpace(t); t != "" {
+			topics = append(topics, t)
+		}
+	}
+	response.LearningMaterials = s.recommendResources(resourceQuery{topics: topics, text: extractedText})
+	if len(response.LearningMaterials) == 0 {
+		telemetry.FallbackActivations.Inc("analyze.materials", "no_catalog_match")
+		materialsPrompt := fmt.Sprintf(`
 Based on the educational content below, recommend 4-6 supplementary learning resources (videos, articles, textbooks, interactive tools) that would help a student master this topic.
 
 CONTENT:
@@ -75,11 +98,12 @@ For each resource, provide in JSON format:
 ]
 `, extractedText, req.StudentGrade, req.StudentAge)
 
-	materialsJSON, err := ai.CallLLMJSON(materialsPrompt)
-	if err == nil {
-		var materials []models.LearningMaterial
-		if err := json.Unmarshal([]byte(materialsJSON), &materials); err == nil {
-			response.LearningMaterials = materials
+		materialsJSON, err := s.llm.CallJSON(ctx, "analyze.materials", materialsPrompt)
+		if err == nil {
+			var materials []models.LearningMaterial
+			if err := json.Unmarshal([]byte(materialsJSON), &materials); err == nil {
+				response.LearningMaterials = unverifiedMaterials(materials)
+			}
 		}
 	}
 
@@ -91,7 +115,7 @@ TOPIC CONTENT:
 %s
 
 STUDENT: Grade %d, Age %d, Weak Areas: %s
-
+%s
 Generate a study plan in JSON format:
 {
   "timeline_weeks": <number>,
@@ -100,9 +124,9 @@ Generate a study plan in JSON format:
   "milestone_weeks": ["week 1: learn basics", "week 2: practice problems"],
   "estimated_readiness": "Ready for assessment in X weeks"
 }
-`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas)
+`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas, grounding)
 
-	planJSON, err := ai.CallLLMJSON(planPrompt)
+	planJSON, err := s.llm.CallJSON(ctx, "analyze.study_plan", planPrompt)
 	if err == nil {
 		var plan models.StudyPlanRecommendation
 		if err := json.Unmarshal([]byte(planJSON), &plan); err == nil {
@@ -118,11 +142,11 @@ CONTENT:
 %s
 
 STUDENT: Grade %d, Age %d, Struggling with: %s
-
+%s
 Return as JSON array of strings with actionable tips.
-`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas)
+`, extractedText, req.StudentGrade, req.StudentAge, req.WeakAreas, grounding)
 
-	tipsJSON, err := ai.CallLLMJSON(tipsPrompt)
+	tipsJSON, err := s.llm.CallJSON(ctx, "analyze.tips", tipsPrompt)
 	if err == nil {
 		var tips []string
 		if err := json.Unmarshal([]byte(tipsJSON), &tips); err == nil {
@@ -142,10 +166,25 @@ WEAK AREAS: %s
 Provide a brief assessment (1-2 sentences) indicating if this is appropriate for the student level and any prerequisite knowledge needed.
 `, req.StudentGrade, req.StudentAge, extractedText, req.WeakAreas)
 
-	difficulty, err := ai.CallLLM(difficultyPrompt)
+	difficulty, err := s.llm.Call(ctx, "analyze.difficulty", difficultyPrompt)
 	if err == nil {
 		response.DifficultyAssessment = difficulty
 	}
 
+	// Turn the worksheet's questions into a playable quiz
+	if req.CreateQuiz {
+		quiz, err := s.WorksheetQuiz(ctx, models.WorksheetQuizRequest{
+			Questions: append(slices.Clone(response.ExtractedQuestions), response.RevisionQuestions...),
+			Content:   extractedText,
+			Topic:     req.QuizTopic,
+			StudentID: req.StudentID,
+		})
+		if err != nil {
+			response.QuizError = err.Error()
+		} else {
+			response.Quiz = &quiz
+		}
+	}
+
 	return response, nil
 }
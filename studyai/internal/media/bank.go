@@ -0,0 +1,202 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/bank"
+	"studyai/internal/grading"
+	"studyai/internal/models"
+)
+
+// BankFilter selects question bank entries; empty fields match everything.
+type BankFilter struct {
+	Topic      string
+	Tag        string
+	Difficulty string
+	Source     string
+}
+
+func (f BankFilter) matches(q models.BankQuestion) bool {
+	return (f.Topic == "" || strings.EqualFold(q.Topic, f.Topic)) &&
+		(f.Tag == "" || slices.ContainsFunc(q.Tags, func(t string) bool { return strings.EqualFold(t, f.Tag) })) &&
+		(f.Difficulty == "" || q.Difficulty == f.Difficulty) &&
+		(f.Source == "" || strings.EqualFold(q.Source, f.Source))
+}
+
+// ListBankQuestions returns the bank entries matching f, ordered by topic.
+func (s *Service) ListBankQuestions(f BankFilter) models.BankQuestionList {
+	qs := s.questionBank.Filter(f.matches)
+	sort.SliceStable(qs, func(i, j int) bool { return qs[i].Topic < qs[j].Topic })
+	return models.BankQuestionList{Total: len(qs), Questions: qs}
+}
+
+// GetBankQuestion returns one bank entry.
+func (s *Service) GetBankQuestion(id string) (models.BankQuestion, error) {
+	q, ok := s.questionBank.Get(id)
+	if !ok {
+		return q, apperr.New(apperr.CodeNotFound, "question not found")
+	}
+	return q, nil
+}
+
+// CreateBankQuestion validates q and adds it to the bank under a new ID.
+func (s *Service) CreateBankQuestion(q models.BankQuestion) (models.BankQuestion, error) {
+	fields := bank.Normalize(&q)
+	if fields = append(fields, s.checkStandards("standards", q.Standards)...); len(fields) > 0 {
+		return q, apperr.Validation(fields...)
+	}
+	q.ID = newID("bq")
+	q.CreatedAt = time.Now().UTC()
+	q.UpdatedAt = q.CreatedAt
+	if err := s.questionBank.Put(q.ID, q); err != nil {
+		return q, apperr.Wrap(apperr.CodeInternal, "failed to save question", err)
+	}
+	return q, nil
+}
+
+// UpdateBankQuestion replaces an existing bank entry.
+func (s *Service) UpdateBankQuestion(id string, q models.BankQuestion) (models.BankQuestion, error) {
+	fields := bank.Normalize(&q)
+	if fields = append(fields, s.checkStandards("standards", q.Standards)...); len(fields) > 0 {
+		return q, apperr.Validation(fields...)
+	}
+	updated, err := s.questionBank.Update(id, func(cur models.BankQuestion, exists bool) (models.BankQuestion, error) {
+		if !exists {
+			return cur, apperr.New(apperr.CodeNotFound, "question not found")
+		}
+		q.ID, q.CreatedAt, q.UpdatedAt = id, cur.CreatedAt, time.Now().UTC()
+		return q, nil
+	})
+	if err != nil && !apperr.Is(err, apperr.CodeNotFound) {
+		return q, apperr.Wrap(apperr.CodeInternal, "failed to save question", err)
+	}
+	return updated, err
+}
+
+// DeleteBankQuestion removes a bank entry.
+func (s *Service) DeleteBankQuestion(id string) error {
+	if _, ok := s.questionBank.Get(id); !ok {
+		return apperr.New(apperr.CodeNotFound, "question not found")
+	}
+	if err := s.questionBank.Delete(id); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to delete question", err)
+	}
+	return nil
+}
+
+// ImportBankQuestions adds the questions in a file to the bank. Entries whose
+// ID already exists are updated; invalid entries are skipped and reported.
+func (s *Service) ImportBankQuestions(format string, data []byte) (models.BankImportResult, error) {
+	result := models.BankImportResult{Format: format, Rejected: []models.BankImportError{}}
+	items, err := bank.Decode(format, data)
+	if err != nil {
+		return result, apperr.Wrap(apperr.CodeInvalidRequest, "could not read "+format+" file: "+err.Error(), err)
+	}
+
+	// Accepted entries are saved with a single write, so a failure saves
+	// none of them.
+	now := time.Now().UTC()
+	var accepted []models.BankQuestion
+	seen := map[string]int{}
+	for i, item := range items {
+		if item.Err != nil {
+			result.Rejected = append(result.Rejected, models.BankImportError{Item: i + 1, Message: item.Err.Error()})
+			continue
+		}
+		q := item.Question
+		if fields := s.checkStandards("standards", q.Standards); len(fields) > 0 {
+			result.Rejected = append(result.Rejected, models.BankImportError{Item: i + 1, Message: fields[0].Field + " " + fields[0].Message})
+			continue
+		}
+		if q.ID == "" {
+			q.ID = newID("bq")
+		} else if first, ok := seen[q.ID]; ok {
+			result.Rejected = append(result.Rejected, models.BankImportError{Item: i + 1, Message: fmt.Sprintf("id %q repeats item %d", q.ID, first)})
+			continue
+		}
+		seen[q.ID] = i + 1
+		accepted = append(accepted, q)
+	}
+
+	ids := make([]string, len(accepted))
+	for i, q := range accepted {
+		ids[i] = q.ID
+	}
+	var created, updated, next int
+	_, err = s.questionBank.UpdateMany(ids, func(_ string, cur models.BankQuestion, exists bool) (models.BankQuestion, error) {
+		q := accepted[next]
+		next++
+		q.CreatedAt, q.UpdatedAt = now, now
+		if exists {
+			q.CreatedAt = cur.CreatedAt
+			updated++
+		} else {
+			created++
+		}
+		return q, nil
+	})
+	if err != nil {
+		return result, apperr.Wrap(apperr.CodeInternal, "failed to save imported questions; none were saved", err)
+	}
+	result.Created, result.Updated = created, updated
+	return result, nil
+}
+
+// ExportBankQuestions encodes the bank entries matching f in format.
+func (s *Service) ExportBankQuestions(format string, f BankFilter) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := bank.Encode(format, &buf, s.ListBankQuestions(f).Questions); err != nil {
+		return nil, apperr.Wrap(apperr.CodeInternal, "failed to export questions", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawFromBank picks up to n random bank questions on topic that carry every
+// tag and are not in exclude. Questions at the requested difficulty come
+// first; others make up any shortfall.
+func (s *Service) drawFromBank(topic, difficulty string, tags []string, n int, exclude []string) []models.QuizQuestion {
+	if n <= 0 {
+		return nil
+	}
+	candidates := s.questionBank.Filter(func(q models.BankQuestion) bool {
+		if !strings.EqualFold(q.Topic, topic) || slices.Contains(exclude, questionKey(q.Question)) {
+			return false
+		}
+		for _, t := range tags {
+			if !slices.ContainsFunc(q.Tags, func(qt string) bool { return strings.EqualFold(qt, t) }) {
+				return false
+			}
+		}
+		return true
+	})
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Difficulty == difficulty && candidates[j].Difficulty != difficulty
+	})
+
+	var out []models.QuizQuestion
+	for _, q := range candidates[:min(n, len(candidates))] {
+		out = append(out, models.QuizQuestion{
+			ID:             fmt.Sprintf("q_%d", len(out)+1),
+			Type:           grading.SingleChoice,
+			Question:       q.Question,
+			Options:        slices.Clone(q.Options),
+			CorrectAnswer:  q.CorrectAnswer,
+			Explanation:    q.Explanation,
+			Topic:          q.Topic,
+			Difficulty:     q.Difficulty,
+			Tags:           slices.Clone(q.Tags),
+			Standards:      slices.Clone(q.Standards),
+			Misconceptions: slices.Clone(q.Misconceptions),
+			Source:         "bank",
+		})
+	}
+	return out
+}
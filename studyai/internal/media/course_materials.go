@@ -0,0 +1,340 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+	"studyai/internal/retrieval"
+	"studyai/internal/telemetry"
+)
+
+const (
+	// embedBatch bounds how many passages one embeddings call covers.
+	embedBatch = 32
+	// maxQueryChars bounds how much of a query is embedded, such as the text
+	// of a whole worksheet.
+	maxQueryChars = 2000
+	// minSimilarity is the cosine similarity below which an embedded passage
+	// is not relevant.
+	minSimilarity = 0.3
+)
+
+// Retrieval methods, as reported by SearchPassages.
+const (
+	methodEmbedding = "embedding"
+	methodBM25      = "bm25"
+)
+
+// PassageRecord is one passage of a course material, with its embedding when
+// one was made.
+type PassageRecord struct {
+	ID         string    `json:"id"`
+	MaterialID string    `json:"material_id"`
+	Title      string    `json:"title"`
+	Topic      string    `json:"topic,omitempty"`
+	Index      int       `json:"index"` // from 1
+	Text       string    `json:"text"`
+	Vector     []float64 `json:"vector,omitempty"`
+}
+
+// ListCourseMaterials returns the course materials, newest first, without
+// their text.
+func (s *Service) ListCourseMaterials() models.CourseMaterialList {
+	list := s.materials.List()
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	for i := range list {
+		list[i].Text = ""
+	}
+	return models.CourseMaterialList{Total: len(list), Materials: list}
+}
+
+// GetCourseMaterial returns one course material with its text.
+func (s *Service) GetCourseMaterial(id string) (models.CourseMaterial, error) {
+	m, ok := s.materials.Get(id)
+	if !ok {
+		return m, apperr.New(apperr.CodeNotFound, "course material not found")
+	}
+	return m, nil
+}
+
+// CreateCourseMaterial splits a material into passages and stores them. With
+// an embedding model configured the passages are embedded too; if that
+// fails they are stored without embeddings and ranked with BM25.
+func (s *Service) CreateCourseMaterial(ctx context.Context, m models.CourseMaterial) (models.CourseMaterial, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.CreateCourseMaterial")
+	defer span.End()
+
+	m.Title, m.Topic, m.Text = strings.TrimSpace(m.Title), strings.TrimSpace(m.Topic), strings.TrimSpace(m.Text)
+	var fields []apperr.FieldError
+	if m.Title == "" {
+		fields = append(fields, apperr.FieldError{Field: "title", Message: "must not be empty"})
+	}
+	chunks := retrieval.Chunk(m.Text, s.retrievalCfg.ChunkWords, s.retrievalCfg.ChunkOverlap)
+	if len(chunks) == 0 {
+		fields = append(fields, apperr.FieldError{Field: "text", Message: "must not be empty"})
+	}
+	if len(fields) > 0 {
+		return m, apperr.Validation(fields...)
+	}
+
+	m.ID = newID("material")
+	m.CreatedAt = time.Now().UTC()
+	m.Passages = len(chunks)
+	records := make([]PassageRecord, len(chunks))
+	for i, c := range chunks {
+		records[i] = PassageRecord{ID: fmt.Sprintf("%s_p%d", m.ID, i+1), MaterialID: m.ID, Title: m.Title, Topic: m.Topic, Index: i + 1, Text: c}
+	}
+	if s.embedder.Enabled() {
+		if err := s.embedPassages(ctx, records); err != nil {
+			telemetry.FallbackActivations.Inc("retrieval.embed", "embedding_error")
+			slog.WarnContext(ctx, "could not embed course material; its passages will be ranked with BM25", "material", m.ID, "err", err)
+			for i := range records {
+				records[i].Vector = nil
+			}
+		} else {
+			m.Embedded = true
+		}
+	}
+
+	// The passages are written in one batch, then the material; if the
+	// material cannot be saved its passages are removed again.
+	batch := make(map[string]PassageRecord, len(records))
+	ids := make([]string, len(records))
+	for i, r := range records {
+		batch[r.ID] = r
+		ids[i] = r.ID
+	}
+	if err := s.passages.PutMany(batch); err != nil {
+		return m, apperr.Wrap(apperr.CodeInternal, "failed to save course material", err)
+	}
+	if err := s.materials.Put(m.ID, m); err != nil {
+		if _, derr := s.passages.DeleteMany(ids); derr != nil {
+			slog.ErrorContext(ctx, "could not remove the passages of an unsaved course material", "material", m.ID, "err", derr)
+		}
+		return m, apperr.Wrap(apperr.CodeInternal, "failed to save course material", err)
+	}
+	slog.InfoContext(ctx, "stored course material", "material", m.ID, "passages", m.Passages, "embedded", m.Embedded)
+	return m, nil
+}
+
+// DeleteCourseMaterial removes a course material and its passages.
+func (s *Service) DeleteCourseMaterial(id string) error {
+	if _, ok := s.materials.Get(id); !ok {
+		return apperr.New(apperr.CodeNotFound, "course material not found")
+	}
+	var ids []string
+	for _, r := range s.passages.Filter(func(r PassageRecord) bool { return r.MaterialID == id }) {
+		ids = append(ids, r.ID)
+	}
+	removed, err := s.passages.DeleteMany(ids)
+	if err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to delete course material", err)
+	}
+	if err := s.materials.Delete(id); err != nil {
+		// Restore the passages so the material is not left without them.
+		if perr := s.passages.PutMany(removed); perr != nil {
+			slog.Error("could not restore the passages of a course material", "material", id, "err", perr)
+		}
+		return apperr.Wrap(apperr.CodeInternal, "failed to delete course material", err)
+	}
+	return nil
+}
+
+// SearchPassages returns the course material passages most relevant to
+// query, as they would be retrieved to ground an answer.
+func (s *Service) SearchPassages(ctx context.Context, query string) models.PassageSearch {
+	passages, method := s.retrieve(ctx, query)
+	if passages == nil {
+		passages = []models.Citation{}
+	}
+	return models.PassageSearch{Query: query, Method: method, Passages: passages}
+}
+
+// embedPassages embeds the passages' text, prefixed with their material's
+// title, in batches.
+func (s *Service) embedPassages(ctx context.Context, records []PassageRecord) error {
+	for start := 0; start < len(records); start += embedBatch {
+		batch := records[start:min(start+embedBatch, len(records))]
+		texts := make([]string, len(batch))
+		for i, r := range batch {
+			texts[i] = r.Title + "\n" + r.Text
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			batch[i].Vector = vectors[i]
+		}
+	}
+	return nil
+}
+
+// retrieve finds the course material passages most relevant to query, at
+// most the configured top_k, numbered from 1 in order of relevance. Passages
+// are ranked by the cosine similarity of their embeddings when every passage
+// has one and the query can be embedded, and with BM25 otherwise. A BM25
+// match needs two of the query's terms, or its only term, so that one common
+// word does not pull in an unrelated passage. The method used is returned
+// with the passages; both are empty when nothing is stored.
+func (s *Service) retrieve(ctx context.Context, query string) ([]models.Citation, string) {
+	records := s.passages.List()
+	if len(records) == 0 || strings.TrimSpace(query) == "" {
+		return nil, ""
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].MaterialID != records[j].MaterialID {
+			return records[i].MaterialID < records[j].MaterialID
+		}
+		return records[i].Index < records[j].Index
+	})
+
+	scores, method := s.embeddingScores(ctx, records, query)
+	if scores == nil {
+		method = methodBM25
+		docs := make([][]string, len(records))
+		for i, r := range records {
+			docs[i] = retrieval.Terms(r.Title + " " + r.Topic + " " + r.Text)
+		}
+		terms := retrieval.Terms(query)
+		scores = retrieval.BM25(docs, terms)
+		need := min(2, len(distinct(terms)))
+		for i, d := range docs {
+			if matched(d, terms) < need {
+				scores[i] = 0
+			}
+		}
+	}
+
+	var ranked []int
+	for i, score := range scores {
+		if score > 0 {
+			ranked = append(ranked, i)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i]] > scores[ranked[j]] })
+	var citations []models.Citation
+	for n, i := range ranked[:min(len(ranked), s.retrievalCfg.TopK)] {
+		r := records[i]
+		citations = append(citations, models.Citation{
+			Ref:        n + 1,
+			MaterialID: r.MaterialID,
+			Title:      r.Title,
+			Passage:    r.Index,
+			Text:       r.Text,
+			Score:      math.Round(scores[i]*1000) / 1000,
+		})
+	}
+	return citations, method
+}
+
+// embeddingScores scores every passage by the cosine similarity of its
+// embedding with the query's, zeroing those below minSimilarity. It returns
+// nil when embeddings cannot be used: none are configured, a passage has
+// none, or the query cannot be embedded.
+func (s *Service) embeddingScores(ctx context.Context, records []PassageRecord, query string) ([]float64, string) {
+	if !s.embedder.Enabled() || slices.ContainsFunc(records, func(r PassageRecord) bool { return len(r.Vector) == 0 }) {
+		return nil, ""
+	}
+	if len(query) > maxQueryChars {
+		query = strings.ToValidUTF8(query[:maxQueryChars], "")
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		telemetry.FallbackActivations.Inc("retrieval.search", "embedding_error")
+		slog.WarnContext(ctx, "could not embed query; ranking passages with BM25", "err", err)
+		return nil, ""
+	}
+	scores := make([]float64, len(records))
+	for i, r := range records {
+		if sim := retrieval.Cosine(vectors[0], r.Vector); sim >= minSimilarity {
+			scores[i] = sim
+		}
+	}
+	return scores, methodEmbedding
+}
+
+// groundingPrompt lists retrieved passages for a prompt, numbered as they
+// are cited, followed by the instruction to use them. It is empty without
+// passages.
+func groundingPrompt(citations []models.Citation, instruction string) string {
+	if len(citations) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nCOURSE MATERIALS provided by the teacher:\n")
+	for _, c := range citations {
+		fmt.Fprintf(&b, "[%d] %s (passage %d):\n%s\n\n", c.Ref, c.Title, c.Passage, c.Text)
+	}
+	b.WriteString(instruction + "\n")
+	return b.String()
+}
+
+// citationsFor returns the citations with the given refs, ignoring refs that
+// name no passage.
+func citationsFor(citations []models.Citation, refs []int) []models.Citation {
+	var out []models.Citation
+	for _, c := range citations {
+		if slices.Contains(refs, c.Ref) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Chat replies to a student's message. When course material passages are
+// relevant to it, the reply is grounded in them and cites them; otherwise it
+// comes from the LLM's general knowledge.
+func (s *Service) Chat(ctx context.Context, message string) (models.ChatResponse, error) {
+	citations, _ := s.retrieve(ctx, message)
+	if len(citations) == 0 {
+		reply, err := s.llm.Chat(ctx, message)
+		return models.ChatResponse{Reply: reply}, err
+	}
+
+	prompt := "User: " + message + "\n" + groundingPrompt(citations,
+		"Answer from these course materials where they cover the question, citing them as [1], [2] after the sentences they support. "+
+			"If they do not cover it, say so before answering from general knowledge.") +
+		"\nRespond concisely. Mention uncertainty and do not guarantee outcomes."
+	reply, err := s.llm.Call(ctx, "chat", prompt)
+	if err != nil {
+		return models.ChatResponse{}, err
+	}
+	return models.ChatResponse{Reply: reply, Citations: citations}, nil
+}
+
+// distinct returns terms without repeats.
+func distinct(terms []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// matched counts the distinct query terms found in a passage.
+func matched(doc, query []string) int {
+	have := map[string]bool{}
+	for _, t := range doc {
+		have[t] = true
+	}
+	n := 0
+	for _, t := range distinct(query) {
+		if have[t] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+package media
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/grading"
+	"studyai/internal/models"
+	"studyai/internal/srs"
+)
+
+// flashcardID derives a stable card ID from the student and question text, so
+// missing the same question again updates the existing card.
+func flashcardID(studentID, question string) string {
+	sum := sha1.Sum([]byte(studentID + "\x00" + questionKey(question)))
+	return "card_" + hex.EncodeToString(sum[:8])
+}
+
+// addFlashcards turns the questions a student did not get fully right into
+// flashcards. Cards that already exist lapse and become due again.
+func (s *Service) addFlashcards(studentID, quizID string, questions []models.QuizQuestion, scores []itemScore, reviews []models.QuestionReview) error {
+	steps := map[string][]string{}
+	for _, r := range reviews {
+		steps[r.QuestionID] = r.SuggestedNextSteps
+	}
+
+	now := time.Now().UTC()
+	for i, q := range questions {
+		if scores[i].Credit == 1 {
+			continue
+		}
+		back := grading.AnswerText(q)
+
+		_, err := s.flashcards.Update(flashcardID(studentID, q.Question), func(c models.Flashcard, exists bool) (models.Flashcard, error) {
+			st := srs.New()
+			if exists {
+				st = srs.Lapse(cardState(c))
+			} else {
+				c = models.Flashcard{
+					ID:        flashcardID(studentID, q.Question),
+					StudentID: studentID,
+					CreatedAt: now,
+				}
+			}
+			c.QuizID, c.QuestionID, c.Topic = quizID, q.ID, q.Topic
+			c.Front, c.Back, c.Explanation = q.Question, back, q.Explanation
+			if next := steps[q.ID]; len(next) > 0 {
+				c.SuggestedNextSteps = next
+			}
+			setCardState(&c, st)
+			c.DueAt = now
+			return c, nil
+		})
+		if err != nil {
+			return apperr.Wrap(apperr.CodeInternal, "failed to save flashcard", err)
+		}
+	}
+	return nil
+}
+
+// DueFlashcards returns the student's cards due by the end of today, most
+// overdue first, capped at limit.
+func (s *Service) DueFlashcards(studentID string, limit int) models.DueFlashcards {
+	now := time.Now().UTC()
+	endOfDay := srs.StartOfDay(now).AddDate(0, 0, 1)
+	cards := s.flashcards.Filter(func(c models.Flashcard) bool {
+		return c.StudentID == studentID && c.DueAt.Before(endOfDay)
+	})
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].DueAt.Before(cards[j].DueAt) })
+
+	due := models.DueFlashcards{StudentID: studentID, AsOf: now, Total: len(cards), Cards: cards}
+	if limit > 0 && len(cards) > limit {
+		due.Cards = cards[:limit]
+	}
+	return due
+}
+
+// ReviewFlashcard records a recall grade and reschedules the card.
+func (s *Service) ReviewFlashcard(studentID, cardID string, grade int) (models.Flashcard, error) {
+	now := time.Now().UTC()
+	card, err := s.flashcards.Update(cardID, func(c models.Flashcard, exists bool) (models.Flashcard, error) {
+		if !exists || c.StudentID != studentID {
+			return c, apperr.New(apperr.CodeNotFound, "flashcard not found")
+		}
+		st := srs.Review(cardState(c), grade)
+		setCardState(&c, st)
+		c.LastReviewedAt = &now
+		c.DueAt = srs.NextDue(now, st)
+		return c, nil
+	})
+	if err != nil {
+		if apperr.Is(err, apperr.CodeNotFound) {
+			return models.Flashcard{}, err
+		}
+		return models.Flashcard{}, apperr.Wrap(apperr.CodeInternal, "failed to save flashcard", err)
+	}
+	return card, nil
+}
+
+// ReviewForecast counts the student's cards falling due on each of the next
+// days, starting today.
+func (s *Service) ReviewForecast(studentID string, days int) models.ReviewForecast {
+	today := srs.StartOfDay(time.Now())
+	forecast := models.ReviewForecast{StudentID: studentID, Days: make([]models.ForecastDay, days)}
+	for i := range forecast.Days {
+		forecast.Days[i].Date = today.AddDate(0, 0, i).Format(time.DateOnly)
+	}
+
+	for _, c := range s.flashcards.Filter(func(c models.Flashcard) bool { return c.StudentID == studentID }) {
+		forecast.TotalCards++
+		if c.DueAt.Before(today) {
+			forecast.Overdue++
+			continue
+		}
+		if day := int(c.DueAt.Sub(today) / (24 * time.Hour)); day < days {
+			forecast.Days[day].Due++
+		}
+	}
+	return forecast
+}
+
+func cardState(c models.Flashcard) srs.State {
+	return srs.State{Ease: c.EaseFactor, Interval: c.IntervalDays, Repetitions: c.Repetitions, Lapses: c.Lapses}
+}
+
+func setCardState(c *models.Flashcard, st srs.State) {
+	c.EaseFactor, c.IntervalDays, c.Repetitions, c.Lapses = round2(st.Ease), st.Interval, st.Repetitions, st.Lapses
+}
@@ -0,0 +1,104 @@
+package media
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// AttemptFilter selects a student's attempts. An empty topic, a zero time
+// and a zero limit match everything; the score bounds are inclusive.
+type AttemptFilter struct {
+	Topic    string
+	From     time.Time // submitted at or after
+	To       time.Time // submitted at or before
+	MinScore int
+	MaxScore int
+	Limit    int
+}
+
+func (f AttemptFilter) matches(a models.QuizAttempt) bool {
+	return (f.Topic == "" || strings.EqualFold(a.Topic, f.Topic)) &&
+		(f.From.IsZero() || !a.SubmittedAt.Before(f.From)) &&
+		(f.To.IsZero() || !a.SubmittedAt.After(f.To)) &&
+		a.Score >= f.MinScore && a.Score <= f.MaxScore
+}
+
+// ListAttempts returns a student's attempts matching f, newest first.
+func (s *Service) ListAttempts(studentID string, f AttemptFilter) models.AttemptList {
+	attempts := s.attempts.Filter(func(a models.QuizAttempt) bool { return a.StudentID == studentID && f.matches(a) })
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].SubmittedAt.After(attempts[j].SubmittedAt) })
+
+	list := models.AttemptList{Total: len(attempts), Attempts: []models.AttemptSummary{}}
+	if f.Limit > 0 && len(attempts) > f.Limit {
+		attempts = attempts[:f.Limit]
+	}
+	for _, a := range attempts {
+		list.Attempts = append(list.Attempts, models.AttemptSummary{
+			ID:          a.ID,
+			QuizID:      a.QuizID,
+			Topic:       a.Topic,
+			Difficulty:  a.Difficulty,
+			Score:       a.Score,
+			Correct:     a.Correct,
+			Total:       a.Total,
+			SubmittedAt: a.SubmittedAt,
+			TimeSpent:   a.TimeSpent,
+			Late:        a.Late,
+		})
+	}
+	return list
+}
+
+// GetAttempt returns one recorded attempt with its per-question outcomes and
+// the result returned on submission.
+func (s *Service) GetAttempt(id string) (models.QuizAttempt, error) {
+	a, ok := s.attempts.Get(id)
+	if !ok {
+		return a, apperr.New(apperr.CodeNotFound, "attempt not found")
+	}
+	return a, nil
+}
+
+// RetakeQuiz stores a fresh copy of a previous quiz under a new ID, with the
+// same questions and time limit but a new seed, so options (and, for
+// shuffle_questions quizzes, questions) come in a new order. The student's
+// timer starts at once. Without a student ID the original quiz's student is
+// used.
+func (s *Service) RetakeQuiz(quizID string, req models.RetakeRequest) (models.StudentQuiz, error) {
+	rec, ok := s.quizzes.Get(quizID)
+	if !ok {
+		return models.StudentQuiz{}, apperr.New(apperr.CodeNotFound, "quiz not found")
+	}
+	studentID := req.StudentID
+	if studentID == "" {
+		studentID = rec.Quiz.StudentID
+	}
+
+	quiz := rec.Quiz
+	quiz.QuizID = newID("quiz")
+	quiz.StudentID = studentID
+	quiz.Seed = newSeed()
+	quiz.RetakeOf = quizID
+	quiz.Questions = make([]models.QuizQuestion, len(rec.Quiz.Questions))
+	for i, q := range rec.Quiz.Questions {
+		q.Options = slices.Clone(q.Options)
+		q.CorrectAnswers = slices.Clone(q.CorrectAnswers)
+		quiz.Questions[i] = q
+	}
+	shuffleOptions(quiz.Questions, quiz.Seed)
+
+	if err := s.saveQuiz(quiz); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	if studentID != "" {
+		if _, err := s.startTimer(quiz, studentID, time.Now().UTC()); err != nil {
+			return models.StudentQuiz{}, err
+		}
+	}
+	return studentQuiz(forStudent(quiz, studentID)), nil
+}
@@ -0,0 +1,83 @@
+package media
+
+import (
+	"fmt"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/curriculum"
+	"studyai/internal/models"
+)
+
+// curriculumKey is the single entry of the curriculum collection.
+const curriculumKey = "current"
+
+// loadCurriculum builds the curriculum graph from the stored curriculum, or
+// the built-in one when no teacher has replaced it.
+func (s *Service) loadCurriculum() error {
+	c, ok := s.curricula.Get(curriculumKey)
+	if !ok {
+		c = curriculum.Default()
+	}
+	g, fields := curriculum.New(c)
+	if len(fields) > 0 {
+		return fmt.Errorf("invalid curriculum: %s: %s", fields[0].Field, fields[0].Message)
+	}
+	s.graph.Store(g)
+	return nil
+}
+
+// GetCurriculum returns the curriculum graph with its topics in prerequisite
+// order.
+func (s *Service) GetCurriculum() models.Curriculum {
+	c := s.graph.Load().Curriculum()
+	if stored, ok := s.curricula.Get(curriculumKey); ok {
+		c.UpdatedAt = stored.UpdatedAt
+	}
+	return c
+}
+
+// SetCurriculum replaces the curriculum graph. Every student's mastery is
+// recomputed, since quiz topics may now map to different curriculum topics.
+func (s *Service) SetCurriculum(c models.Curriculum) (models.Curriculum, error) {
+	g, fields := curriculum.New(c)
+	for i, t := range c.Topics {
+		fields = append(fields, s.checkStandards(fmt.Sprintf("topics[%d].standards", i), curriculum.Codes(t.Standards))...)
+	}
+	if len(fields) > 0 {
+		return c, apperr.Validation(fields...)
+	}
+	c = g.Curriculum()
+	now := time.Now().UTC()
+	c.UpdatedAt = &now
+	if err := s.curricula.Put(curriculumKey, c); err != nil {
+		return c, apperr.Wrap(apperr.CodeInternal, "failed to save curriculum", err)
+	}
+	s.graph.Store(g)
+
+	for _, p := range s.progress.List() {
+		if err := s.refreshProgress(p.StudentID); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// refreshProgress recomputes a student's topic mastery and misconceptions
+// from their graded attempts.
+func (s *Service) refreshProgress(studentID string) error {
+	attempts := s.StudentAttempts(studentID)
+	mastery := curriculum.Mastery(s.graph.Load(), attempts)
+	trends := misconceptionTrends(attempts)
+	if err := s.updateProfile(studentID, func(p *models.ProgressProfile) { p.Mastery, p.Misconceptions = mastery, trends }); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to update progress", err)
+	}
+	return nil
+}
+
+// KnowledgeMap lays a student's mastery over the curriculum, explains the
+// gaps behind struggling topics and recommends the topic to study next.
+func (s *Service) KnowledgeMap(studentID, subject string) models.KnowledgeMap {
+	p, _ := s.progress.Get(studentID)
+	return s.graph.Load().Map(studentID, p.Mastery, subject)
+}
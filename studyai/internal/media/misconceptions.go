@@ -0,0 +1,59 @@
+package media
+
+import (
+	"slices"
+	"sort"
+
+	"studyai/internal/models"
+)
+
+// misconceptionTrends tallies the misconceptions revealed by a student's
+// answers across their attempts, oldest first. Labels that differ only in
+// case or spacing are counted together under the first spelling seen.
+// Recurring misconceptions come first, then the most frequent.
+func misconceptionTrends(attempts []models.QuizAttempt) []models.MisconceptionTrend {
+	byKey := map[string]*models.MisconceptionTrend{}
+	var order []string
+	for _, a := range attempts {
+		seen := map[string]bool{}
+		for _, item := range a.Items {
+			for _, label := range item.Misconceptions {
+				key := questionKey(label)
+				if key == "" {
+					continue
+				}
+				t, ok := byKey[key]
+				if !ok {
+					t = &models.MisconceptionTrend{Label: label, Topics: []string{}}
+					byKey[key] = t
+					order = append(order, key)
+				}
+				t.Count++
+				if !seen[key] {
+					seen[key] = true
+					t.Attempts++
+				}
+				if item.Topic != "" && !slices.Contains(t.Topics, item.Topic) {
+					t.Topics = append(t.Topics, item.Topic)
+				}
+				t.LastSeen = a.SubmittedAt
+			}
+		}
+	}
+
+	trends := make([]models.MisconceptionTrend, len(order))
+	for i, key := range order {
+		trends[i] = *byKey[key]
+		trends[i].Recurring = trends[i].Attempts > 1
+	}
+	sort.SliceStable(trends, func(i, j int) bool {
+		if trends[i].Attempts != trends[j].Attempts {
+			return trends[i].Attempts > trends[j].Attempts
+		}
+		if trends[i].Count != trends[j].Count {
+			return trends[i].Count > trends[j].Count
+		}
+		return trends[i].LastSeen.After(trends[j].LastSeen)
+	})
+	return trends
+}
@@ -0,0 +1,85 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"studyai/internal/adaptive"
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// NextQuiz builds the quiz best suited to a student's current level. The
+// difficulty of each topic targets the configured success rate for the
+// student's estimated ability, and when no topic is given the questions are
+// spread over the student's topics in favour of the weakest.
+func (s *Service) NextQuiz(ctx context.Context, req models.NextQuizRequest) (models.StudentQuiz, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.NextQuiz",
+		slog.String("student.id", req.StudentID),
+		slog.String("quiz.topic", req.TopicName),
+		slog.Int("quiz.num_questions", req.NumQuestions),
+	)
+	defer span.End()
+
+	if err := checkQuestionTypes(req.QuestionTypes); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	profile, err := s.GetStudentProgress(req.StudentID)
+	if err != nil {
+		return models.StudentQuiz{}, err
+	}
+	plan, err := adaptive.Plan(s.adaptiveCfg, adaptive.Input{
+		Profile:      profile,
+		Attempts:     s.StudentAttempts(req.StudentID),
+		Topic:        req.TopicName,
+		NumQuestions: req.NumQuestions,
+	})
+	if errors.Is(err, adaptive.ErrNoTopics) {
+		return models.StudentQuiz{}, apperr.Validation(apperr.FieldError{Field: "topic_name", Message: "is required until the student has quiz history or topics in their profile"})
+	}
+	if err != nil {
+		return models.StudentQuiz{}, err
+	}
+	span.SetAttributes(
+		slog.Float64("adaptive.ability", plan.Ability),
+		slog.String("adaptive.difficulty", plan.Difficulty),
+	)
+
+	quiz := models.QuizResponse{
+		QuizID:     newID("quiz"),
+		TimeLimit:  timeLimit(req.TimedMinutes),
+		Difficulty: plan.Difficulty,
+		StudentID:  req.StudentID,
+		Adaptive:   &plan,
+		Seed:       newSeed(),
+	}
+	var topics []string
+	for _, t := range plan.Topics {
+		questions, fallback, err := s.composeQuestions(ctx, req.Source, t.Topic, t.Difficulty, nil, req.QuestionTypes, t.Questions)
+		if err != nil {
+			return models.StudentQuiz{}, err
+		}
+		quiz.Questions = append(quiz.Questions, questions...)
+		quiz.IsDevFallback = quiz.IsDevFallback || fallback
+		topics = append(topics, t.Topic)
+	}
+	for i := range quiz.Questions {
+		quiz.Questions[i].ID = fmt.Sprintf("q_%d", i+1)
+	}
+	shuffleOptions(quiz.Questions, quiz.Seed)
+	quiz.Topic = strings.Join(topics, ", ")
+
+	s.applyLateRules(&quiz, "", nil)
+	if err := s.saveQuiz(quiz); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	if _, err := s.startTimer(quiz, req.StudentID, time.Now().UTC()); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	return studentQuiz(quiz), nil
+}
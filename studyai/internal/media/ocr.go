@@ -2,29 +2,90 @@ package media
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"os"
+	"strings"
 	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/config"
+	"studyai/internal/telemetry"
 )
 
 // OCRService extracts text from images using a Vision API (Gemini/Google)
 type OCRService struct {
-	apiKey string
+	cfg  config.OCR
+	http *http.Client
 }
 
-func NewOCRService() *OCRService {
+func NewOCRService(cfg config.OCR) *OCRService {
 	return &OCRService{
-		apiKey: os.Getenv("GEMINI_API_KEY"),
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout.Std()},
 	}
 }
 
+// newRequest builds an annotate request. The key travels in a header rather
+// than the query string so it never appears in logged URLs or errors.
+func (o *OCRService) newRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	url := strings.TrimRight(o.cfg.BaseURL, "/") + "/images:annotate"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Goog-Api-Key", o.cfg.APIKey.Value())
+	return httpReq, nil
+}
+
+// Ping checks that the Vision API is configured and accepts the API key by
+// sending an empty annotate batch.
+func (o *OCRService) Ping(ctx context.Context) error {
+	if o.cfg.APIKey == "" {
+		return errors.New("OCR API key not set")
+	}
+
+	httpReq, err := o.newRequest(ctx, []byte(`{"requests":[]}`))
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.http.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("vision API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // ExtractTextFromImage uses the Google Vision REST API to extract text from an image.
 // The incoming `imageData` should be a base64-encoded image string (no data: prefix).
-func (o *OCRService) ExtractTextFromImage(imageData string) (string, error) {
-	if o.apiKey == "" {
-		return "", errors.New("GEMINI_API_KEY not set")
+// Failures are *apperr.Error values coded ocr_failed.
+func (o *OCRService) ExtractTextFromImage(ctx context.Context, imageData string) (string, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.ExtractTextFromImage")
+	defer span.End()
+
+	start := time.Now()
+	text, err := o.extractText(ctx, imageData)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		span.RecordError(err)
+	}
+	telemetry.OCRRequestDuration.Observe(time.Since(start).Seconds(), outcome)
+	return text, err
+}
+
+func (o *OCRService) extractText(ctx context.Context, imageData string) (string, error) {
+	if o.cfg.APIKey == "" {
+		return "", &apperr.Error{Code: apperr.CodeOCRFailed, Message: "OCR engine is not configured", Status: http.StatusServiceUnavailable}
 	}
 
 	// Build request for Google Vision API (DOCUMENT_TEXT_DETECTION)
@@ -44,17 +105,24 @@ func (o *OCRService) ExtractTextFromImage(imageData string) (string, error) {
 	bodyBytes, _ := json.Marshal(reqBody)
 
 	// Use the Vision REST endpoint with the provided API key.
-	url := "https://vision.googleapis.com/v1/images:annotate?key=" + o.apiKey
-	httpReq, _ := http.NewRequest("POST", url, bytes.NewBuffer(bodyBytes))
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
+	httpReq, err := o.newRequest(ctx, bodyBytes)
 	if err != nil {
 		return "", err
 	}
+
+	resp, err := o.http.Do(httpReq)
+	if err != nil {
+		return "", apperr.Wrap(apperr.CodeOCRFailed, "OCR engine unreachable", err)
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", apperr.New(apperr.CodeQuotaExceeded, "OCR quota exceeded; try again later")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", apperr.Wrap(apperr.CodeOCRFailed, "OCR engine returned an error", fmt.Errorf("status %d", resp.StatusCode))
+	}
+
 	var result struct {
 		Responses []struct {
 			FullTextAnnotation struct {
@@ -67,11 +135,11 @@ func (o *OCRService) ExtractTextFromImage(imageData string) (string, error) {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return "", apperr.Wrap(apperr.CodeOCRFailed, "OCR engine returned an invalid response", err)
 	}
 
 	if len(result.Responses) == 0 {
-		return "", errors.New("no text extracted from image")
+		return "", errNoText
 	}
 
 	// Prefer fullTextAnnotation if available, otherwise fallback to first textAnnotation
@@ -82,5 +150,8 @@ func (o *OCRService) ExtractTextFromImage(imageData string) (string, error) {
 		return result.Responses[0].TextAnnotations[0].Description, nil
 	}
 
-	return "", errors.New("no text extracted from image")
+	return "", errNoText
 }
+
+// errNoText means the image was processed but contained no readable text.
+var errNoText = &apperr.Error{Code: apperr.CodeOCRFailed, Message: "no text extracted from image", Status: http.StatusUnprocessableEntity}
@@ -2,142 +2,96 @@ package media
 
 import (
 	"errors"
-	"sync"
-	"time"
+	"slices"
+	"studyai/internal/curriculum"
 	"studyai/internal/models"
+	"time"
 )
 
-// In-memory storage for student progress (replace with database in production)
-var (
-	progressStore = make(map[string]models.ProgressProfile)
-	progressMutex sync.RWMutex
-)
+// newProfile returns an empty profile for a student seen for the first time.
+func newProfile(studentID string) models.ProgressProfile {
+	return models.ProgressProfile{
+		StudentID: studentID,
+		Topics:    []string{},
+		WeakAreas: []string{},
+	}
+}
 
-// GetStudentProgress retrieves a student's progress profile
-func GetStudentProgress(studentID string) (models.ProgressProfile, error) {
-	progressMutex.RLock()
-	defer progressMutex.RUnlock()
+// updateProfile applies fn to the student's profile, creating it if needed.
+func (s *Service) updateProfile(studentID string, fn func(p *models.ProgressProfile)) error {
+	_, err := s.progress.Update(studentID, func(p models.ProgressProfile, exists bool) (models.ProgressProfile, error) {
+		if !exists {
+			p = newProfile(studentID)
+		}
+		fn(&p)
+		p.LastUpdated = time.Now().Format(time.RFC3339)
+		return p, nil
+	})
+	return err
+}
 
-	profile, exists := progressStore[studentID]
+// GetStudentProgress retrieves a student's progress profile
+func (s *Service) GetStudentProgress(studentID string) (models.ProgressProfile, error) {
+	profile, exists := s.progress.Get(studentID)
 	if !exists {
 		// Return empty profile if student doesn't exist yet
-		return models.ProgressProfile{
-			StudentID: studentID,
-			Topics: []string{},
-			WeakAreas: []string{},
-			LastUpdated: time.Now().Format(time.RFC3339),
-		}, nil
+		profile = newProfile(studentID)
+		profile.LastUpdated = time.Now().Format(time.RFC3339)
 	}
-
 	return profile, nil
 }
 
-// UpdateStudentProgress updates or creates a student's progress profile
-func UpdateStudentProgress(profile models.ProgressProfile) error {
+// UpdateStudentProgress updates or creates a student's progress profile.
+// Topic mastery and misconceptions are kept as computed from the student's
+// attempts.
+func (s *Service) UpdateStudentProgress(profile models.ProgressProfile) error {
 	if profile.StudentID == "" {
 		return errors.New("student_id is required")
 	}
-
-	progressMutex.Lock()
-	defer progressMutex.Unlock()
-
+	attempts := s.StudentAttempts(profile.StudentID)
+	profile.Mastery = curriculum.Mastery(s.graph.Load(), attempts)
+	profile.Misconceptions = misconceptionTrends(attempts)
 	profile.LastUpdated = time.Now().Format(time.RFC3339)
-	progressStore[profile.StudentID] = profile
-
-	return nil
+	return s.progress.Put(profile.StudentID, profile)
 }
 
 // RecordQuizAttempt updates student progress after a quiz
-func RecordQuizAttempt(studentID string, score float32, newWeakAreas []string) error {
-	progressMutex.Lock()
-	defer progressMutex.Unlock()
-
-	profile, exists := progressStore[studentID]
-	if !exists {
-		profile = models.ProgressProfile{
-			StudentID: studentID,
-			Topics: []string{},
-			WeakAreas: []string{},
-		}
-	}
-
-	// Update quiz stats
-	profile.QuizzesAttempted++
-	
-	if profile.AverageScore == 0 {
-		profile.AverageScore = score
-	} else {
-		// Update running average
-		profile.AverageScore = (profile.AverageScore + score) / 2
-	}
-
-	// Update weak areas
-	for _, area := range newWeakAreas {
-		found := false
-		for _, existing := range profile.WeakAreas {
-			if existing == area {
-				found = true
-				break
+func (s *Service) RecordQuizAttempt(studentID string, score float32, newWeakAreas []string) error {
+	return s.updateProfile(studentID, func(p *models.ProgressProfile) {
+		// Update quiz stats with a true running mean over all attempts
+		p.QuizzesAttempted++
+		p.AverageScore += (score - p.AverageScore) / float32(p.QuizzesAttempted)
+
+		for _, area := range newWeakAreas {
+			if !slices.Contains(p.WeakAreas, area) {
+				p.WeakAreas = append(p.WeakAreas, area)
 			}
 		}
-		if !found {
-			profile.WeakAreas = append(profile.WeakAreas, area)
-		}
-	}
-
-	profile.LastUpdated = time.Now().Format(time.RFC3339)
-	progressStore[studentID] = profile
-
-	return nil
+	})
 }
 
-// UpdateStudyHours increments the total study hours
-func UpdateStudyHours(studentID string, hours float32) error {
-	progressMutex.Lock()
-	defer progressMutex.Unlock()
-
-	profile, exists := progressStore[studentID]
-	if !exists {
-		profile = models.ProgressProfile{
-			StudentID: studentID,
-			Topics: []string{},
-			WeakAreas: []string{},
+// RegradeQuizAttempt replaces one attempt's score in the student's average
+// after its grade changed.
+func (s *Service) RegradeQuizAttempt(studentID string, oldScore, newScore float32) error {
+	return s.updateProfile(studentID, func(p *models.ProgressProfile) {
+		if p.QuizzesAttempted > 0 {
+			p.AverageScore += (newScore - oldScore) / float32(p.QuizzesAttempted)
 		}
-	}
-
-	profile.StudyHours += hours
-	profile.LastUpdated = time.Now().Format(time.RFC3339)
-	progressStore[studentID] = profile
+	})
+}
 
-	return nil
+// UpdateStudyHours increments the total study hours
+func (s *Service) UpdateStudyHours(studentID string, hours float32) error {
+	return s.updateProfile(studentID, func(p *models.ProgressProfile) {
+		p.StudyHours += hours
+	})
 }
 
 // AddTopic adds a topic to a student's learning list
-func AddTopic(studentID string, topic string) error {
-	progressMutex.Lock()
-	defer progressMutex.Unlock()
-
-	profile, exists := progressStore[studentID]
-	if !exists {
-		profile = models.ProgressProfile{
-			StudentID: studentID,
-			Topics: []string{},
-			WeakAreas: []string{},
-		}
-	}
-
-	// Check if topic already exists
-	for _, t := range profile.Topics {
-		if t == topic {
-			profile.LastUpdated = time.Now().Format(time.RFC3339)
-			progressStore[studentID] = profile
-			return nil
+func (s *Service) AddTopic(studentID string, topic string) error {
+	return s.updateProfile(studentID, func(p *models.ProgressProfile) {
+		if !slices.Contains(p.Topics, topic) {
+			p.Topics = append(p.Topics, topic)
 		}
-	}
-
-	profile.Topics = append(profile.Topics, topic)
-	profile.LastUpdated = time.Now().Format(time.RFC3339)
-	progressStore[studentID] = profile
-
-	return nil
+	})
 }
@@ -1,77 +1,324 @@
 package media
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
 	"strings"
-	"studyai/internal/ai"
+	"studyai/internal/adaptive"
+	"studyai/internal/apperr"
+	"studyai/internal/curriculum"
+	"studyai/internal/grading"
 	"studyai/internal/models"
+	"studyai/internal/telemetry"
 	"time"
 )
 
-// GenerateQuiz creates a quiz with AI-generated questions
-func GenerateQuiz(req models.QuizRequest) (models.QuizResponse, error) {
-	quizID := fmt.Sprintf("quiz_%d", time.Now().Unix())
+// GenerateQuiz creates a quiz with AI-generated questions. Adaptive requests
+// take their difficulty from the student's history instead of req.Difficulty.
+// The quiz is returned without its answer keys.
+func (s *Service) GenerateQuiz(ctx context.Context, req models.QuizRequest) (models.StudentQuiz, error) {
+	if req.Adaptive {
+		if req.StudentID == "" {
+			return models.StudentQuiz{}, apperr.Validation(apperr.FieldError{Field: "student_id", Message: "is required for adaptive quizzes"})
+		}
+		return s.NextQuiz(ctx, models.NextQuizRequest{
+			StudentID:     req.StudentID,
+			TopicName:     req.TopicName,
+			NumQuestions:  req.NumQuestions,
+			TimedMinutes:  req.TimedMinutes,
+			Source:        req.Source,
+			QuestionTypes: req.QuestionTypes,
+		})
+	}
 
-	prompt := fmt.Sprintf(`
-Generate exactly %d multiple-choice quiz questions about "%s" at difficulty level "%s".
+	ctx, span := telemetry.StartSpan(ctx, "media.GenerateQuiz",
+		slog.String("quiz.topic", req.TopicName),
+		slog.Int("quiz.num_questions", req.NumQuestions),
+	)
+	defer span.End()
 
-For each question, provide in JSON format within a JSON array:
-{
-  "question": "Question text?",
-  "options": ["Option A", "Option B", "Option C", "Option D"],
-  "correct_answer": 0,
-  "explanation": "Why this answer is correct"
-}
+	if err := checkQuestionTypes(req.QuestionTypes); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	standards := curriculum.Codes(req.Standards)
+	if fields := s.checkStandards("standards", standards); len(fields) > 0 {
+		return models.StudentQuiz{}, apperr.Validation(fields...)
+	}
+	difficulty := adaptive.NormalizeLevel(req.Difficulty)
+	questions, fallback, err := s.composeQuestions(ctx, req.Source, req.TopicName, difficulty, req.Tags, req.QuestionTypes, req.NumQuestions)
+	if err != nil {
+		return models.StudentQuiz{}, err
+	}
+	for i := range questions {
+		questions[i].Standards = curriculum.Codes(append(questions[i].Standards, standards...))
+	}
 
-Requirements:
-- Questions must be clear and educational
-- Options should be plausible (avoid obvious wrong answers)
-- correct_answer is the 0-indexed position in options array
-- Include detailed explanations for learning
+	seed := req.Seed
+	if seed == 0 {
+		seed = newSeed()
+	}
+	shuffleOptions(questions, seed)
+
+	quiz := models.QuizResponse{
+		QuizID:           newID("quiz"),
+		Questions:        questions,
+		TimeLimit:        timeLimit(req.TimedMinutes),
+		IsDevFallback:    fallback,
+		Topic:            req.TopicName,
+		Difficulty:       difficulty,
+		StudentID:        req.StudentID,
+		Seed:             seed,
+		ShuffleQuestions: req.ShuffleQuestions,
+		Assessment:       req.Assessment,
+	}
+	s.applyLateRules(&quiz, req.LatePolicy, req.GraceSeconds)
+	if err := s.saveQuiz(quiz); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	// A quiz made for a student starts their timer at once.
+	if req.StudentID != "" {
+		if _, err := s.startTimer(quiz, req.StudentID, time.Now().UTC()); err != nil {
+			return models.StudentQuiz{}, err
+		}
+	}
+	return studentQuiz(forStudent(quiz, req.StudentID)), nil
+}
 
-Return ONLY valid JSON array, no additional text.
-`, req.NumQuestions, req.TopicName, req.Difficulty)
+// checkQuestionTypes rejects unknown question types.
+func checkQuestionTypes(types []string) error {
+	for i, t := range types {
+		if !slices.Contains(grading.Types, t) {
+			return apperr.Validation(apperr.FieldError{
+				Field:   fmt.Sprintf("question_types[%d]", i),
+				Message: "must be one of " + strings.Join(grading.Types, ", "),
+			})
+		}
+	}
+	return nil
+}
 
-	questionsJSON, err := ai.CallLLMJSON(prompt)
-	if err != nil {
-		// Fallback: generate sample questions for development/testing
-		questions := generateSampleQuestions(req.TopicName, req.NumQuestions, req.Difficulty)
-		return models.QuizResponse{
-			QuizID:    quizID,
-			Questions: questions,
-			TimeLimit: 0,
-			IsDevFallback: true,
-		}, nil
+// composeQuestions gathers n questions of the given types (single choice when
+// empty) from the source the request asked for: "bank" draws only curated
+// questions, "mixed" takes half from the bank and generates the rest, and
+// anything else generates them all. The bank holds single-choice questions
+// only, so it is skipped when that type is not wanted. Question IDs are
+// numbered from q_1.
+func (s *Service) composeQuestions(ctx context.Context, source, topic, difficulty string, tags, types []string, n int) ([]models.QuizQuestion, bool, error) {
+	if source != "" && len(types) > 0 && !slices.Contains(types, grading.SingleChoice) {
+		if source == "bank" {
+			return nil, false, apperr.Validation(apperr.FieldError{Field: "question_types", Message: "must include single_choice when source is bank"})
+		}
+		source = "llm"
 	}
 
 	var questions []models.QuizQuestion
-	if err := json.Unmarshal([]byte(questionsJSON), &questions); err != nil {
-		// Fallback on parse error
-		questions := generateSampleQuestions(req.TopicName, req.NumQuestions, req.Difficulty)
-		return models.QuizResponse{
-			QuizID:    quizID,
-			Questions: questions,
-			TimeLimit: 0,
-			IsDevFallback: true,
-		}, nil
-	}
-
-	// Assign IDs to questions
+	fallback := false
+	switch source {
+	case "bank":
+		questions = s.drawFromBank(topic, difficulty, tags, n, nil)
+		if len(questions) == 0 {
+			return nil, false, apperr.New(apperr.CodeNotFound, fmt.Sprintf("no question bank entries match topic %q", topic))
+		}
+	case "mixed":
+		questions = s.drawFromBank(topic, difficulty, tags, (n+1)/2, nil)
+		if rest := n - len(questions); rest > 0 {
+			generated, fb, err := s.generateQuestions(ctx, topic, difficulty, types, rest)
+			if err != nil && len(questions) == 0 {
+				return nil, false, err
+			}
+			questions, fallback = append(questions, dedupeQuestions(questions, generated)...), fb
+		}
+	default:
+		var err error
+		if questions, fallback, err = s.generateQuestions(ctx, topic, difficulty, types, n); err != nil {
+			return nil, false, err
+		}
+	}
 	for i := range questions {
 		questions[i].ID = fmt.Sprintf("q_%d", i+1)
 	}
+	return questions, fallback, nil
+}
 
-	timeLimit := 0
-	if req.TimedMinutes > 0 {
-		timeLimit = req.TimedMinutes * 60
+// generateQuestions asks the LLM for n questions on topic at the given
+// difficulty, spread over types (single choice when empty), and keeps the
+// ones that pass generateValidated. When the call or its output fails it
+// falls back to the question bank and then to the built-in samples, which are
+// single choice; if neither covers the topic an llm_unavailable error is
+// returned rather than questions on another subject. The bool result reports
+// whether sample questions were used.
+func (s *Service) generateQuestions(ctx context.Context, topic, difficulty string, types []string, n int) ([]models.QuizQuestion, bool, error) {
+	if len(types) == 0 {
+		types = []string{grading.SingleChoice}
+	}
+	questions, reason, err := s.generateValidated(ctx, topic, difficulty, types, n)
+	if err == nil {
+		for i := range questions {
+			questions[i].ID = fmt.Sprintf("q_%d", i+1)
+			questions[i].Topic = topic
+			questions[i].Difficulty = difficulty
+			questions[i].Source = "llm"
+		}
+		return questions, false, nil
 	}
 
-	return models.QuizResponse{
-		QuizID:    quizID,
-		Questions: questions,
-		TimeLimit: timeLimit,
-	}, nil
+	telemetry.FallbackActivations.Inc("quiz.generate", reason)
+	if questions = s.drawFromBank(topic, difficulty, nil, n, nil); len(questions) > 0 {
+		slog.WarnContext(ctx, "quiz generation fell back to the question bank", "reason", reason, "topic", topic, "err", err)
+		return questions, false, nil
+	}
+	if questions = generateSampleQuestions(topic, n, difficulty); len(questions) > 0 {
+		// Fallback: sample questions for development/testing
+		slog.WarnContext(ctx, "quiz generation fell back to sample questions", "reason", reason, "topic", topic, "err", err)
+		return questions, true, nil
+	}
+	slog.WarnContext(ctx, "no fallback questions for topic", "reason", reason, "topic", topic, "err", err)
+	return nil, false, apperr.Wrap(apperr.CodeLLMUnavailable,
+		fmt.Sprintf("question generation is unavailable and neither the question bank nor the samples cover topic %q", topic), err)
+}
+
+// questionFormats describes the JSON shape of each requested question type
+// for the generation prompt.
+func questionFormats(types []string) string {
+	formats := map[string]string{
+		grading.SingleChoice: `{"type": "single_choice", "question": "Question text?", "options": ["Option A", "Option B", "Option C", "Option D"], "correct_answer": 0, "misconceptions": ["", "misconception behind B", "misconception behind C", "misconception behind D"], "explanation": "Why this answer is correct"}`,
+		grading.MultiSelect:  `{"type": "multi_select", "question": "Select all that apply: ...", "options": ["Option A", "Option B", "Option C", "Option D"], "correct_answers": [0, 2], "misconceptions": ["", "misconception behind B", "", "misconception behind D"], "explanation": "..."}`,
+		grading.TrueFalse:    `{"type": "true_false", "question": "A statement to judge", "options": ["True", "False"], "correct_answer": 0, "misconceptions": ["", "misconception behind answering False"], "explanation": "..."}`,
+		grading.Numeric:      `{"type": "numeric", "question": "A question with a numeric answer", "numeric_answer": 3.5, "tolerance": 0.01, "explanation": "..."}`,
+		grading.ShortText:    `{"type": "short_text", "question": "A question answered in a word or short phrase", "accepted_answers": ["main answer", "accepted variant"], "explanation": "..."}`,
+		grading.Ordering:     `{"type": "ordering", "question": "Put these in order ...", "options": ["first item", "second item", "third item"], "explanation": "..."}`,
+		grading.Matching:     `{"type": "matching", "question": "Match each term to its definition", "options": ["term 1", "term 2", "term 3"], "match_targets": ["definition of term 1", "definition of term 2", "definition of term 3"], "explanation": "..."}`,
+		grading.FreeResponse: `{"type": "free_response", "question": "An open question answered in a few sentences", "model_answer": "A complete model answer", "rubric": [{"description": "What a full-credit answer must explain", "points": 2, "keywords": ["key term"]}], "explanation": "..."}`,
+	}
+
+	var b strings.Builder
+	if len(types) == 1 {
+		b.WriteString("\nEvery question uses this JSON format, within a JSON array:\n")
+	} else {
+		b.WriteString("\nMix these question types, using the matching JSON format for each, within one JSON array:\n")
+	}
+	for _, t := range types {
+		b.WriteString(formats[t] + "\n")
+	}
+	if slices.Contains(types, grading.SingleChoice) || slices.Contains(types, grading.MultiSelect) || slices.Contains(types, grading.TrueFalse) {
+		b.WriteString("\nmisconceptions[i] names, in a few words, the misconception that would lead a student to choose options[i] wrongly; leave it empty for correct options.\n")
+	}
+	if slices.Contains(types, grading.Ordering) {
+		b.WriteString("\nFor ordering questions list the options in the correct order; they are shuffled before the quiz is shown.\n")
+	}
+	if slices.Contains(types, grading.Matching) {
+		b.WriteString("\nFor matching questions match_targets[i] must be the match for options[i]; they are shuffled before the quiz is shown.\n")
+	}
+	if slices.Contains(types, grading.FreeResponse) {
+		b.WriteString("\nFor free_response questions give 2-4 rubric criteria worth whole points, with keywords a correct answer would mention.\n")
+	}
+	return b.String()
+}
+
+// arrangeGenerated shuffles ordering and matching questions, which the LLM
+// writes in answer order, and records the answer key.
+func arrangeGenerated(q *models.QuizQuestion) {
+	switch q.Type {
+	case grading.Ordering:
+		if len(q.CorrectOrder) == len(q.Options) {
+			return
+		}
+		perm := rand.Perm(len(q.Options))
+		shuffled := make([]string, len(q.Options))
+		q.CorrectOrder = make([]int, len(q.Options))
+		for pos, orig := range perm {
+			shuffled[pos] = q.Options[orig]
+			q.CorrectOrder[orig] = pos
+		}
+		q.Options = shuffled
+	case grading.Matching:
+		if len(q.CorrectMatches) == len(q.Options) || len(q.MatchTargets) < len(q.Options) {
+			return
+		}
+		perm := rand.Perm(len(q.MatchTargets))
+		shuffled := make([]string, len(q.MatchTargets))
+		q.CorrectMatches = make([]int, len(q.Options))
+		for pos, orig := range perm {
+			shuffled[pos] = q.MatchTargets[orig]
+			if orig < len(q.Options) {
+				q.CorrectMatches[orig] = pos
+			}
+		}
+		q.MatchTargets = shuffled
+	}
+}
+
+// dedupeQuestions returns the questions in add whose text does not already
+// appear in have.
+func dedupeQuestions(have, add []models.QuizQuestion) []models.QuizQuestion {
+	seen := map[string]bool{}
+	for _, q := range have {
+		seen[questionKey(q.Question)] = true
+	}
+	var out []models.QuizQuestion
+	for _, q := range add {
+		if key := questionKey(q.Question); !seen[key] {
+			seen[key] = true
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// saveQuiz stores a generated quiz so its attempts can be graded later.
+func (s *Service) saveQuiz(quiz models.QuizResponse) error {
+	if err := s.quizzes.Put(quiz.QuizID, QuizRecord{Quiz: quiz, CreatedAt: time.Now().UTC()}); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to save quiz", err)
+	}
+	return nil
+}
+
+// studentQuiz returns quiz as students see it, without its answer keys.
+func studentQuiz(quiz models.QuizResponse) models.StudentQuiz {
+	out := models.StudentQuiz{
+		QuizID:           quiz.QuizID,
+		Questions:        make([]models.StudentQuestion, len(quiz.Questions)),
+		TimeLimit:        quiz.TimeLimit,
+		IsDevFallback:    quiz.IsDevFallback,
+		Topic:            quiz.Topic,
+		Difficulty:       quiz.Difficulty,
+		StudentID:        quiz.StudentID,
+		Adaptive:         quiz.Adaptive,
+		ShuffleQuestions: quiz.ShuffleQuestions,
+		LatePolicy:       quiz.LatePolicy,
+		GraceSeconds:     quiz.GraceSeconds,
+		RetakeOf:         quiz.RetakeOf,
+		Assessment:       quiz.Assessment,
+	}
+	for i, q := range quiz.Questions {
+		out.Questions[i] = models.StudentQuestion{
+			ID:           q.ID,
+			Type:         q.Type,
+			Question:     q.Question,
+			Options:      q.Options,
+			MatchTargets: q.MatchTargets,
+			Topic:        q.Topic,
+			Difficulty:   q.Difficulty,
+			Tags:         q.Tags,
+			Standards:    q.Standards,
+			Source:       q.Source,
+			Citations:    q.Citations,
+		}
+	}
+	return out
+}
+
+func timeLimit(minutes int) int {
+	if minutes > 0 {
+		return minutes * 60
+	}
+	return 0
 }
 
 // generateSampleQuestions creates deterministic sample questions for development
@@ -83,6 +330,7 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 				Question:       "What is the solution to x + 5 = 12?",
 				Options:        []string{"x = 7", "x = 17", "x = 6", "x = 2"},
 				CorrectAnswer:  0,
+				Misconceptions: []string{"", "Adds instead of subtracting to isolate x", "Arithmetic slip when subtracting", "Confuses the constants in the equation"},
 				Explanation:    "Subtract 5 from both sides: x = 12 - 5 = 7",
 			},
 			{
@@ -90,6 +338,7 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 				Question:       "Simplify: 2x + 3x - 5",
 				Options:        []string{"5x - 5", "6x - 5", "5x", "x - 5"},
 				CorrectAnswer:  0,
+				Misconceptions: []string{"", "Multiplies coefficients instead of adding them", "Drops the constant term", "Subtracts coefficients instead of adding them"},
 				Explanation:    "Combine like terms: 2x + 3x = 5x, then subtract 5.",
 			},
 			{
@@ -97,6 +346,7 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 				Question:       "What is the value of y if 2y - 4 = 10?",
 				Options:        []string{"y = 7", "y = 14", "y = 3", "y = 6"},
 				CorrectAnswer:  0,
+				Misconceptions: []string{"", "Stops before dividing by the coefficient", "Subtracts instead of adding to isolate the variable term", "Arithmetic slip when dividing"},
 				Explanation:    "Add 4 to both sides: 2y = 14. Divide by 2: y = 7.",
 			},
 		},
@@ -148,13 +398,18 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 		},
 	}
 
-	// Use provided topic or find a close match
+	// Use provided topic or find a close match ("Algebra" matches
+	// "Mathematics - Algebra"); unrelated topics get no samples.
 	var questionBank []models.QuizQuestion
 	if bank, ok := samples[topic]; ok {
 		questionBank = bank
-	} else {
-		// Default fallback
-		questionBank = samples["Mathematics - Algebra"]
+	} else if want := strings.ToLower(strings.TrimSpace(topic)); want != "" {
+		for name, bank := range samples {
+			if strings.Contains(strings.ToLower(name), want) {
+				questionBank = bank
+				break
+			}
+		}
 	}
 
 	// Return requested number of questions, cycling through the bank if needed
@@ -162,6 +417,10 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 	for i := 0; i < numQuestions && len(questionBank) > 0; i++ {
 		q := questionBank[i%len(questionBank)]
 		q.ID = fmt.Sprintf("q_%d", i+1)
+		q.Type = grading.SingleChoice
+		q.Topic = topic
+		q.Difficulty = difficulty
+		q.Source = "sample"
 		result = append(result, q)
 	}
 
@@ -172,77 +431,118 @@ func generateSampleQuestions(topic string, numQuestions int, difficulty string)
 // EvaluateQuiz scores and provides feedback on quiz answers. If the original
 // questions are provided, the function will also return per-question review
 // suggestions for incorrectly answered questions.
-func EvaluateQuiz(submission models.QuizSubmissionRequest, questions []models.QuizQuestion) (models.QuizResult, error) {
-	// Note: In a real implementation, you'd fetch the quiz from storage
-	// For now, we'll calculate score based on the submission
+func (s *Service) EvaluateQuiz(ctx context.Context, submission models.QuizSubmissionRequest, questions []models.QuizQuestion) (models.QuizResult, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.EvaluateQuiz", slog.String("quiz.id", submission.QuizID))
+	defer span.End()
+
+	// Quizzes generated by this server are graded against the stored answer
+	// key; questions sent by the client are only used for unknown quizzes.
+	var stored *models.QuizResponse
+	if rec, ok := s.quizzes.Get(submission.QuizID); ok {
+		stored = &rec.Quiz
+		if submission.StudentID == "" {
+			submission.StudentID = rec.Quiz.StudentID
+		}
+		questions = forStudent(rec.Quiz, submission.StudentID).Questions
+	}
+
+	if len(submission.Answers) > 0 && len(submission.Responses) > 0 {
+		return models.QuizResult{}, apperr.Validation(apperr.FieldError{Field: "responses", Message: "cannot be combined with answers"})
+	}
+	// An empty submission hands in the answers saved one at a time.
+	if stored != nil && len(submission.Answers) == 0 && len(submission.Responses) == 0 {
+		if t, ok := s.timers.Get(timerKey(stored.QuizID, submission.StudentID)); ok && len(t.Answers) > 0 {
+			submission.Responses = savedResponses(t, questions)
+		}
+	}
+	responses := submissionResponses(submission)
+	submission.Responses = responses
 
 	result := models.QuizResult{
-		TotalQuestions: len(submission.Answers),
+		TotalQuestions:    len(responses),
+		WeakTopics:        []string{},
+		RecommendedReview: []string{},
 	}
 
 	if result.TotalQuestions == 0 {
-		return result, nil
+		return result, apperr.Validation(apperr.FieldError{Field: "answers", Message: "answers or responses is required"})
 	}
-
-	// Validate answer count matches submission
-	if len(submission.Answers) != result.TotalQuestions {
-		return result, fmt.Errorf("answer count mismatch: expected %d, got %d", result.TotalQuestions, len(submission.Answers))
+	// A known quiz is always graded against its key, so a submission must
+	// answer each of its questions; it is rejected before the timer closes so
+	// the student can resubmit.
+	if stored != nil && len(responses) != len(questions) {
+		field := "answers"
+		if len(submission.Answers) == 0 {
+			field = "responses"
+		}
+		return result, apperr.Validation(apperr.FieldError{Field: field, Message: fmt.Sprintf("must have one entry per question (%d), in order", len(questions))})
 	}
 
-	// Build answers info for feedback prompt
-	var answersInfo strings.Builder
-	answersInfo.WriteString("Submitted answers:\n")
-	for i, answer := range submission.Answers {
-		answersInfo.WriteString(fmt.Sprintf("Q%d: Selected option %d\n", i+1, answer))
+	// Started quizzes are timed by the server rather than the client.
+	if stored != nil {
+		timing, err := s.finishTimer(*stored, submission.StudentID, time.Now().UTC())
+		if err != nil {
+			return result, err
+		}
+		if timing != nil {
+			result.Timing = timing
+			submission.TimeSpent = timing.ElapsedSeconds
+		}
 	}
 
-	// Generate AI feedback based on submission patterns
-	feedbackPrompt := fmt.Sprintf(`
-A student submitted answers to a quiz. Provide constructive feedback about their approach and performance.
-
-Total Questions: %d
-Time Spent: %d seconds
-%s
-
-Generate structured feedback in JSON format:
-{
-  "feedback": "Overall performance summary and encouragement (1-2 sentences)",
-  "weak_topics": ["topic1", "topic2"],
-  "recommended_review": ["resource1", "resource2"]
-}
-
-Notes:
-- weak_topics should be general learning areas to focus on
-- recommended_review should be specific study resources or strategies
-- Keep feedback constructive and motivating
-`, result.TotalQuestions, submission.TimeSpent, answersInfo.String())
-
-	feedbackJSON, err := ai.CallLLMJSON(feedbackPrompt)
-	if err == nil {
-		var feedback struct {
-			Feedback           string   `json:"feedback"`
-			WeakTopics         []string `json:"weak_topics"`
-			RecommendedReview  []string `json:"recommended_review"`
+	// Stored quizzes always match here; the heuristic only scores quizzes
+	// with no stored answer key.
+	graded := len(questions) == len(responses)
+
+	var correctCount int
+	var points float64
+	var scores []itemScore
+	if graded {
+		scores = s.scoreResponses(ctx, questions, responses)
+		for _, sc := range scores {
+			points += sc.Credit
+			if sc.Credit == 1 {
+				correctCount++
+			}
+			if sc.Grade != nil {
+				result.Grades = append(result.Grades, *sc.Grade)
+			}
 		}
-
-		if err := json.Unmarshal([]byte(feedbackJSON), &feedback); err == nil {
-			result.Feedback = feedback.Feedback
-			result.WeakTopics = feedback.WeakTopics
-			result.RecommendedReview = feedback.RecommendedReview
+	} else {
+		// Without an answer key fall back to a heuristic based on answer
+		// diversity patterns
+		choices := make([]int, len(responses))
+		for i, r := range responses {
+			choices[i] = firstChoice(r)
 		}
+		correctCount = estimateCorrectAnswers(choices)
+		points = float64(correctCount)
 	}
-
-	// Calculate score based on answer diversity patterns
-	// This is a heuristic since we don't have the correct answers
-	// In a production system, you'd compare with actual correct answers
-	correctCount := estimateCorrectAnswers(submission.Answers)
 	result.CorrectCount = correctCount
-	result.Score = (correctCount * 100) / result.TotalQuestions
+	result.Points = math.Round(points*100) / 100
+	result.Score = int(points*100/float64(result.TotalQuestions) + 1e-9)
 	result.Percentage = float32(result.Score)
 
+	// Weak areas come from the questions actually missed. Without an answer
+	// key there is nothing to ground them in, so none are reported.
+	if graded {
+		topic, difficulty := "", ""
+		if stored != nil {
+			topic, difficulty = stored.Topic, stored.Difficulty
+		} else if len(questions) > 0 {
+			topic = questions[0].Topic
+		}
+		result.WeakAreas = weakAreas(gradedItems(questions, scores, topic))
+		result.WeakTopics = areaNames(result.WeakAreas)
+		result.Resources = s.reviewResources(result.WeakAreas, questions, scores, difficulty)
+		s.quizFeedback(ctx, &result, questions, responses, scores, submission.TimeSpent)
+	} else {
+		result.Feedback = fmt.Sprintf("Estimated score %d%%. Without the answer key the score is an estimate and weak areas cannot be identified.", result.Score)
+	}
+
 	// If questions were provided, generate per-question review suggestions
-	if len(questions) == len(submission.Answers) {
-		if reviews, err := ReviewFailedQuiz(submission, questions); err == nil {
+	if graded {
+		if reviews, err := s.reviewMissed(ctx, questions, responses, scores); err == nil {
 			// Convert media reviews to model reviews where necessary
 			var mr []models.QuestionReview
 			for _, r := range reviews {
@@ -251,7 +551,10 @@ Notes:
 					Question:           r.Question,
 					CorrectAnswer:      r.CorrectAnswer,
 					CorrectOption:      r.CorrectOption,
+					Credit:             r.Credit,
+					Grade:              r.Grade,
 					Explanation:        r.Explanation,
+					Misconceptions:     r.Misconceptions,
 					SuggestedNextSteps: r.SuggestedNextSteps,
 				})
 			}
@@ -259,9 +562,136 @@ Notes:
 		}
 	}
 
+	// Only submissions graded against a stored quiz's key feed the student's
+	// history. Questions sent by the client carry a key the client chose, and
+	// the heuristic estimate has none, so neither may skew ability estimates.
+	if stored != nil && graded && submission.StudentID != "" {
+		attempt, err := s.recordAttempt(submission, questions, stored, result, scores)
+		if err != nil {
+			return result, err
+		}
+		result.AttemptID = attempt.ID
+		if err := s.addFlashcards(submission.StudentID, submission.QuizID, questions, scores, result.Reviews); err != nil {
+			return result, err
+		}
+	}
+
 	return result, nil
 }
 
+// recordAttempt stores the per-question outcomes and the result of a graded
+// submission and updates the student's progress profile.
+func (s *Service) recordAttempt(submission models.QuizSubmissionRequest, questions []models.QuizQuestion, stored *models.QuizResponse, result models.QuizResult, scores []itemScore) (models.QuizAttempt, error) {
+	attempt := models.QuizAttempt{
+		ID:          newID("attempt"),
+		StudentID:   submission.StudentID,
+		QuizID:      submission.QuizID,
+		Correct:     result.CorrectCount,
+		Total:       result.TotalQuestions,
+		Score:       result.Score,
+		SubmittedAt: time.Now().UTC(),
+		TimeSpent:   submission.TimeSpent,
+	}
+	seconds := map[string]float64{}
+	if result.Timing != nil {
+		attempt.Late = result.Timing.Late
+		for _, qt := range result.Timing.Questions {
+			seconds[qt.QuestionID] = qt.Seconds
+		}
+	}
+	if stored != nil {
+		attempt.Topic, attempt.Difficulty = stored.Topic, stored.Difficulty
+	} else if len(questions) > 0 {
+		attempt.Topic, attempt.Difficulty = questions[0].Topic, questions[0].Difficulty
+	}
+
+	responses := submissionResponses(submission)
+	for i, q := range questions {
+		topic, difficulty := q.Topic, q.Difficulty
+		if topic == "" {
+			topic = attempt.Topic
+		}
+		if difficulty == "" {
+			difficulty = attempt.Difficulty
+		}
+		credit := scores[i].Credit
+		selected := -1
+		if t := grading.TypeOf(q); t == grading.SingleChoice || t == grading.TrueFalse {
+			selected = firstChoice(responses[i])
+		}
+		response := responses[i]
+		attempt.Items = append(attempt.Items, models.ItemOutcome{
+			QuestionID:     q.ID,
+			Topic:          topic,
+			Difficulty:     adaptive.NormalizeLevel(difficulty),
+			Selected:       selected,
+			Correct:        credit == 1,
+			Credit:         math.Round(credit*100) / 100,
+			Grade:          scores[i].Grade,
+			Seconds:        seconds[q.ID],
+			Response:       &response,
+			Standards:      q.Standards,
+			Tags:           q.Tags,
+			Misconceptions: grading.Misconceptions(q, response),
+		})
+	}
+	result.AttemptID = attempt.ID
+	attempt.Result = &result
+
+	return attempt, s.saveAttempt(attempt, result.WeakTopics)
+}
+
+// saveAttempt stores a graded attempt and folds it into the student's
+// progress profile, topic mastery and misconceptions.
+func (s *Service) saveAttempt(attempt models.QuizAttempt, weakTopics []string) error {
+	if err := s.attempts.Put(attempt.ID, attempt); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to save quiz attempt", err)
+	}
+	if err := s.RecordQuizAttempt(attempt.StudentID, float32(attempt.Score), weakTopics); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to update progress", err)
+	}
+	var topics []string
+	for _, item := range attempt.Items {
+		if item.Topic != "" && !slices.Contains(topics, item.Topic) {
+			topics = append(topics, item.Topic)
+		}
+	}
+	for _, t := range topics {
+		if err := s.AddTopic(attempt.StudentID, t); err != nil {
+			return apperr.Wrap(apperr.CodeInternal, "failed to update progress", err)
+		}
+	}
+	return s.refreshProgress(attempt.StudentID)
+}
+
+// StudentAttempts returns a student's recorded attempts, oldest first.
+func (s *Service) StudentAttempts(studentID string) []models.QuizAttempt {
+	attempts := s.attempts.Filter(func(a models.QuizAttempt) bool { return a.StudentID == studentID })
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].SubmittedAt.Before(attempts[j].SubmittedAt) })
+	return attempts
+}
+
+// submissionResponses returns the submission's responses, converting the
+// single-choice answers field when that was used instead.
+func submissionResponses(submission models.QuizSubmissionRequest) []models.QuizAnswer {
+	if len(submission.Responses) > 0 {
+		return submission.Responses
+	}
+	responses := make([]models.QuizAnswer, len(submission.Answers))
+	for i, a := range submission.Answers {
+		responses[i] = models.QuizAnswer{Choices: []int{a}}
+	}
+	return responses
+}
+
+// firstChoice returns the first selected option, or -1 when there is none.
+func firstChoice(a models.QuizAnswer) int {
+	if len(a.Choices) == 0 {
+		return -1
+	}
+	return a.Choices[0]
+}
+
 // estimateCorrectAnswers estimates correct answers based on submission patterns
 // In a real implementation, this would compare submitted answers with correct answers
 func estimateCorrectAnswers(answers []int) int {
@@ -300,35 +730,47 @@ func estimateCorrectAnswers(answers []int) int {
 
 // FailedQuestionReview contains insights and next steps for a failed question
 // ReviewFailedQuiz analyzes a submission against the original questions and
-// returns actionable review suggestions for each incorrectly answered question.
-func ReviewFailedQuiz(submission models.QuizSubmissionRequest, questions []models.QuizQuestion) ([]models.QuestionReview, error) {
+// returns actionable review suggestions for each question that did not earn
+// full credit, with the misconceptions revealed by the wrong options chosen.
+func (s *Service) ReviewFailedQuiz(ctx context.Context, submission models.QuizSubmissionRequest, questions []models.QuizQuestion) ([]models.QuestionReview, error) {
 	if len(questions) == 0 {
 		return nil, fmt.Errorf("no questions provided for review")
 	}
-	if len(submission.Answers) != len(questions) {
-		return nil, fmt.Errorf("answer count mismatch: expected %d, got %d", len(questions), len(submission.Answers))
+	responses := submissionResponses(submission)
+	if len(responses) != len(questions) {
+		return nil, fmt.Errorf("answer count mismatch: expected %d, got %d", len(questions), len(responses))
 	}
+	return s.reviewMissed(ctx, questions, responses, s.scoreResponses(ctx, questions, responses))
+}
+
+// reviewMissed builds review suggestions for the questions whose scores fall
+// short of full credit, aimed at any misconceptions the answers revealed.
+func (s *Service) reviewMissed(ctx context.Context, questions []models.QuizQuestion, responses []models.QuizAnswer, scores []itemScore) ([]models.QuestionReview, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.ReviewFailedQuiz")
+	defer span.End()
 
 	var reviews []models.QuestionReview
 
 	for i, q := range questions {
-		selected := submission.Answers[i]
-		if selected == q.CorrectAnswer {
+		credit := scores[i].Credit
+		if credit == 1 {
 			continue
 		}
 
-		correctOpt := ""
-		if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) {
-			correctOpt = q.Options[q.CorrectAnswer]
-		}
-
 		fq := models.QuestionReview{
 			QuestionID:    q.ID,
 			Question:      q.Question,
 			CorrectAnswer: q.CorrectAnswer,
-			CorrectOption: correctOpt,
+			CorrectOption: grading.AnswerText(q),
+			Credit:        math.Round(credit*100) / 100,
+			Grade:         scores[i].Grade,
 			Explanation:   q.Explanation,
 		}
+		fq.Misconceptions = grading.Misconceptions(q, responses[i])
+		var misconceptions string
+		if len(fq.Misconceptions) > 0 {
+			misconceptions = "The student's answer suggests this misconception: " + strings.Join(fq.Misconceptions, "; ") + "\nAim the suggestions at correcting it.\n"
+		}
 
 		// Ask the AI for up to 3 concise, actionable next steps for improvement
 		prompt := fmt.Sprintf(`
@@ -337,12 +779,12 @@ Provide up to 3 concise, actionable study suggestions (as a JSON array of string
 Question: %s
 Correct Answer: %s
 Explanation: %s
-
+%s
 Suggestions should be practical (e.g., specific exercises, focused readings, videos, or practice techniques).
 Return ONLY a JSON array of strings and nothing else.
-`, fq.Question, fq.CorrectOption, fq.Explanation)
+`, fq.Question, fq.CorrectOption, fq.Explanation, misconceptions)
 
-		suggestionsJSON, err := ai.CallLLMJSON(prompt)
+		suggestionsJSON, err := s.llm.CallJSON(ctx, "quiz.review", prompt)
 		if err == nil {
 			var suggestions []string
 			if err := json.Unmarshal([]byte(suggestionsJSON), &suggestions); err == nil && len(suggestions) > 0 {
@@ -352,11 +794,15 @@ Return ONLY a JSON array of strings and nothing else.
 
 		// Fallback suggestions if AI failed or returned nothing
 		if len(fq.SuggestedNextSteps) == 0 {
+			telemetry.FallbackActivations.Inc("quiz.review", "no_suggestions")
 			fq.SuggestedNextSteps = []string{
 				"Re-read the explanation and underline the key concept.",
 				"Find 3 similar practice questions and solve them without looking at answers.",
 				"Watch a short (5-10 min) concept video or read a concise article on this topic.",
 			}
+			for _, m := range fq.Misconceptions {
+				fq.SuggestedNextSteps = append([]string{"Check your reasoning against a common mistake: " + m + "."}, fq.SuggestedNextSteps...)
+			}
 		}
 
 		reviews = append(reviews, fq)
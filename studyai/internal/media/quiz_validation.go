@@ -0,0 +1,400 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"studyai/internal/grading"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// maxRegenerations bounds how many times rejected or missing questions are
+// asked for again.
+const maxRegenerations = 2
+
+// similarQuestion is the word overlap above which two generated questions are
+// treated as the same question.
+const similarQuestion = 0.8
+
+// rejection records why a generated question was not used, so the retry
+// prompt can steer the model away from the same mistake.
+type rejection struct {
+	question string
+	reason   string // metric label
+	detail   string
+}
+
+// generateValidated asks the LLM for n questions and passes them through a
+// validation and repair stage: structural problems are repaired where the
+// intent is clear and rejected otherwise, near-identical questions are
+// dropped, surplus questions are trimmed, and answer keys are confirmed by a
+// second verification prompt. Rejected or missing questions are requested
+// again, up to maxRegenerations times. It fails only when no question
+// survives; the string result is the fallback reason.
+func (s *Service) generateValidated(ctx context.Context, topic, difficulty string, types []string, n int) ([]models.QuizQuestion, string, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.generateValidated", slog.String("quiz.topic", topic), slog.Int("quiz.requested", n))
+	defer span.End()
+
+	sources, _ := s.retrieve(ctx, topic)
+	var accepted []models.QuizQuestion
+	var rejected []rejection
+	rounds := 0
+	for ; rounds <= maxRegenerations && len(accepted) < n; rounds++ {
+		want := n - len(accepted)
+		batch, reason, err := s.requestQuestions(ctx, topic, difficulty, types, want, sources, accepted, rejected)
+		if err != nil {
+			if rounds == 0 {
+				return nil, reason, err
+			}
+			slog.WarnContext(ctx, "question regeneration failed", "reason", reason, "err", err)
+			break
+		}
+
+		var checked []models.QuizQuestion
+		for _, q := range batch {
+			if len(checked) == want {
+				rejected = append(rejected, reject(q, "surplus", "more questions than requested"))
+				continue
+			}
+			if r, ok := repairGenerated(&q, types); !ok {
+				rejected = append(rejected, r)
+				continue
+			}
+			repeats := func(p models.QuizQuestion) bool { return sameQuestion(p, q) }
+			if slices.ContainsFunc(accepted, repeats) || slices.ContainsFunc(checked, repeats) {
+				rejected = append(rejected, reject(q, "duplicate_question", "repeats another question"))
+				continue
+			}
+			checked = append(checked, q)
+		}
+
+		kept, wrong := s.verifyAnswerKeys(ctx, topic, checked)
+		accepted = append(accepted, kept...)
+		rejected = append(rejected, wrong...)
+	}
+
+	slog.InfoContext(ctx, "validated generated questions", "requested", n, "accepted", len(accepted), "rejected", len(rejected), "rounds", rounds)
+	if len(accepted) == 0 {
+		return nil, "invalid_output", fmt.Errorf("no usable questions in LLM output")
+	}
+	return accepted, "", nil
+}
+
+// requestQuestions makes one generation call for n questions. Questions are
+// based on the course material passages in sources, when there are any, and
+// cite the ones they use. Retries list the questions already accepted, to
+// avoid repeats, and the ones rejected as faulty with the reason.
+func (s *Service) requestQuestions(ctx context.Context, topic, difficulty string, types []string, n int, sources []models.Citation, accepted []models.QuizQuestion, rejected []rejection) ([]models.QuizQuestion, string, error) {
+	var retry strings.Builder
+	if len(accepted) > 0 {
+		retry.WriteString("\nDo not repeat these questions:\n")
+		for _, q := range accepted {
+			fmt.Fprintf(&retry, "- %s\n", q.Question)
+		}
+	}
+	problems := slices.DeleteFunc(slices.Clone(rejected), func(r rejection) bool { return r.reason == "surplus" })
+	if len(problems) > 0 {
+		retry.WriteString("\nThese earlier questions were rejected; avoid the same problems:\n")
+		for _, r := range problems {
+			fmt.Fprintf(&retry, "- %q: %s\n", r.question, r.detail)
+		}
+	}
+
+	prompt := fmt.Sprintf(`
+Generate exactly %d quiz questions about "%s" at difficulty level "%s".
+%s
+Requirements:
+- Questions must be clear and educational
+- Options should be plausible (avoid obvious wrong answers) and distinct
+- Indices are 0-based positions in the options array
+- Include detailed explanations for learning
+%s%s
+Return ONLY valid JSON array, no additional text.
+`, n, topic, difficulty, questionFormats(types), groundingPrompt(sources,
+		`Base the questions on these course materials where they cover the topic, and keep answers consistent with them. `+
+			`Give each question a "sources" array with the numbers of the passages it is based on, such as [1], or [] for none.`), retry.String())
+
+	feature := "quiz.generate"
+	if retry.Len() > 0 {
+		feature = "quiz.regenerate"
+	}
+	reply, err := s.llm.CallJSON(ctx, feature, prompt)
+	if err != nil {
+		return nil, "llm_error", err
+	}
+	var questions []models.QuizQuestion
+	if err := json.Unmarshal([]byte(reply), &questions); err != nil {
+		return nil, "parse_error", err
+	}
+	if len(sources) > 0 {
+		var refs []struct {
+			Sources []int `json:"sources"`
+		}
+		if json.Unmarshal([]byte(reply), &refs) == nil {
+			for i := range questions {
+				questions[i].Citations = citationsFor(sources, refs[i].Sources)
+			}
+		}
+	}
+	return questions, "", nil
+}
+
+// repairGenerated checks one generated question. Blank or duplicate options
+// on choice questions are merged and the answer key remapped, and
+// misconception labels that do not fit the options are dropped; anything else
+// that breaks the answer key rejects the question.
+func repairGenerated(q *models.QuizQuestion, types []string) (rejection, bool) {
+	q.Question = strings.TrimSpace(q.Question)
+	q.Explanation = strings.TrimSpace(q.Explanation)
+	arrangeGenerated(q)
+	if err := grading.Check(q); err != nil {
+		return reject(*q, "invalid_key", err.Error()), false
+	}
+	if !slices.Contains(types, q.Type) {
+		return reject(*q, "unexpected_type", fmt.Sprintf("type %s was not requested", q.Type)), false
+	}
+
+	switch q.Type {
+	case grading.SingleChoice, grading.TrueFalse, grading.MultiSelect:
+		repaired, err := mergeOptions(q)
+		if err != nil {
+			return reject(*q, "duplicate_options", err.Error()), false
+		}
+		if repaired {
+			telemetry.GeneratedQuestionChecks.Inc("repaired", "duplicate_options")
+			if err := grading.Check(q); err != nil {
+				return reject(*q, "duplicate_options", err.Error()), false
+			}
+		}
+	case grading.Ordering, grading.Matching:
+		if hasDuplicates(q.Options) || hasDuplicates(q.MatchTargets) {
+			return reject(*q, "duplicate_options", "options must be distinct"), false
+		}
+	}
+	if tidyMisconceptions(q) {
+		telemetry.GeneratedQuestionChecks.Inc("repaired", "misconceptions")
+	}
+	return rejection{}, true
+}
+
+// tidyMisconceptions trims misconception labels and drops those that cannot
+// be trusted: labels on question types without options to choose, a list
+// that does not match the options, and labels on correct options. It reports
+// whether anything besides spacing changed.
+func tidyMisconceptions(q *models.QuizQuestion) bool {
+	if len(q.Misconceptions) == 0 {
+		return false
+	}
+	if (q.Type != grading.SingleChoice && q.Type != grading.TrueFalse && q.Type != grading.MultiSelect) || len(q.Misconceptions) != len(q.Options) {
+		q.Misconceptions = nil
+		return true
+	}
+	changed := false
+	for i, m := range q.Misconceptions {
+		correct := i == q.CorrectAnswer
+		if q.Type == grading.MultiSelect {
+			correct = slices.Contains(q.CorrectAnswers, i)
+		}
+		if m = strings.TrimSpace(m); correct && m != "" {
+			m, changed = "", true
+		}
+		q.Misconceptions[i] = m
+	}
+	if !slices.ContainsFunc(q.Misconceptions, func(m string) bool { return m != "" }) {
+		q.Misconceptions = nil
+	}
+	return changed
+}
+
+// mergeOptions drops blank options and merges repeated ones, remapping the
+// answer key and misconception labels. It fails when a multi-select key marks
+// one copy of an option correct and another wrong.
+func mergeOptions(q *models.QuizQuestion) (bool, error) {
+	index := make([]int, len(q.Options))
+	seen := map[string]int{}
+	var kept []string
+	for i, opt := range q.Options {
+		key := questionKey(opt)
+		if key == "" {
+			index[i] = -1
+			continue
+		}
+		if j, ok := seen[key]; ok {
+			index[i] = j
+			continue
+		}
+		seen[key] = len(kept)
+		index[i] = len(kept)
+		kept = append(kept, strings.TrimSpace(opt))
+	}
+	if len(kept) == len(q.Options) {
+		return false, nil
+	}
+
+	if q.Type == grading.MultiSelect {
+		correct := map[int]bool{}
+		for i := range q.Options {
+			if index[i] >= 0 {
+				if was, ok := correct[index[i]]; ok && was != slices.Contains(q.CorrectAnswers, i) {
+					return false, fmt.Errorf("option %q is listed twice with different answers", q.Options[i])
+				}
+				correct[index[i]] = slices.Contains(q.CorrectAnswers, i)
+			}
+		}
+		var answers []int
+		for _, a := range q.CorrectAnswers {
+			if index[a] >= 0 && !slices.Contains(answers, index[a]) {
+				answers = append(answers, index[a])
+			}
+		}
+		q.CorrectAnswers = answers
+	} else {
+		if index[q.CorrectAnswer] < 0 {
+			return false, fmt.Errorf("the correct option is blank")
+		}
+		q.CorrectAnswer = index[q.CorrectAnswer]
+	}
+	if len(q.Misconceptions) == len(q.Options) {
+		labels := make([]string, len(kept))
+		for i, m := range q.Misconceptions {
+			if j := index[i]; j >= 0 && labels[j] == "" {
+				labels[j] = m
+			}
+		}
+		q.Misconceptions = labels
+	} else {
+		q.Misconceptions = nil
+	}
+	q.Options = kept
+	return true, nil
+}
+
+// hasDuplicates reports whether two items are the same apart from case and
+// spacing, or one is blank.
+func hasDuplicates(items []string) bool {
+	seen := map[string]bool{}
+	for _, it := range items {
+		key := questionKey(it)
+		if key == "" || seen[key] {
+			return true
+		}
+		seen[key] = true
+	}
+	return false
+}
+
+// sameQuestion reports whether two questions are near-identical.
+func sameQuestion(a, b models.QuizQuestion) bool {
+	return questionKey(a.Question) == questionKey(b.Question) || grading.Similarity(a.Question, b.Question) >= similarQuestion
+}
+
+// reject records a rejected question and counts it.
+func reject(q models.QuizQuestion, reason, detail string) rejection {
+	telemetry.GeneratedQuestionChecks.Inc("rejected", reason)
+	return rejection{question: q.Question, reason: reason, detail: detail}
+}
+
+// keyVerdict is the verifier's judgement of one answer key.
+type keyVerdict struct {
+	ID          string `json:"id"`
+	KeyCorrect  *bool  `json:"key_correct"`
+	Explanation string `json:"explanation"`
+}
+
+// verifyAnswerKeys confirms each question's answer key. Arithmetic and
+// simple algebra are worked out with grading.CheckMath, which also checks the
+// steps of the explanation; the other questions are solved by the LLM in a
+// separate prompt. Questions found wrong are rejected; missing explanations
+// are taken from the verifier or, failing that, stated from the answer key.
+// Free-response questions are graded by rubric and are not verified. If the
+// verifier fails, the questions it was asked about are kept unverified.
+func (s *Service) verifyAnswerKeys(ctx context.Context, topic string, questions []models.QuizQuestion) ([]models.QuizQuestion, []rejection) {
+	mathErrs := map[int]error{}
+	var list strings.Builder
+	for i, q := range questions {
+		if q.Type == grading.FreeResponse {
+			continue
+		}
+		if checked, err := grading.CheckMath(q); checked {
+			mathErrs[i] = err
+			continue
+		}
+		fmt.Fprintf(&list, "ID: v%d\nType: %s\nQuestion: %s\n", i+1, q.Type, q.Question)
+		for j, opt := range q.Options {
+			fmt.Fprintf(&list, "  %d. %s\n", j, opt)
+		}
+		if len(q.MatchTargets) > 0 {
+			fmt.Fprintf(&list, "Match targets: %s\n", strings.Join(q.MatchTargets, "; "))
+		}
+		for _, c := range q.Citations {
+			fmt.Fprintf(&list, "Course material [%d]: %s\n", c.Ref, c.Text)
+		}
+		fmt.Fprintf(&list, "Proposed answer: %s\n\n", grading.AnswerText(q))
+	}
+
+	verdicts := map[string]keyVerdict{}
+	if list.Len() > 0 {
+		prompt := fmt.Sprintf(`
+Check the answer keys of these quiz questions about "%s".
+For each question, work out the answer yourself first, then compare it with the proposed answer.
+Where course material is given, the answer must agree with it.
+
+%s
+Return a JSON array with one entry per question:
+[{"id": "v1", "key_correct": true, "explanation": "Why the correct answer is correct"}]
+
+Return ONLY valid JSON array, no additional text.
+`, topic, list.String())
+
+		reply, err := s.llm.CallJSON(ctx, "quiz.verify", prompt)
+		reason := "llm_error"
+		var out []keyVerdict
+		if err == nil {
+			if err = json.Unmarshal([]byte(reply), &out); err != nil {
+				reason = "parse_error"
+			}
+		}
+		if err != nil {
+			telemetry.FallbackActivations.Inc("quiz.verify", reason)
+			slog.WarnContext(ctx, "answer key verification failed; keeping questions unverified", "reason", reason, "err", err)
+		}
+		for _, v := range out {
+			verdicts[v.ID] = v
+		}
+	}
+
+	var kept []models.QuizQuestion
+	var rejected []rejection
+	for i, q := range questions {
+		if err, checked := mathErrs[i]; checked {
+			if err != nil {
+				rejected = append(rejected, reject(q, "wrong_math", "the math does not check out: "+err.Error()))
+				continue
+			}
+			telemetry.GeneratedQuestionChecks.Inc("verified", "math")
+		}
+		v, ok := verdicts[fmt.Sprintf("v%d", i+1)]
+		if ok && v.KeyCorrect != nil && !*v.KeyCorrect {
+			detail := "the answer key is wrong"
+			if v.Explanation != "" {
+				detail += ": " + v.Explanation
+			}
+			rejected = append(rejected, reject(q, "wrong_key", detail))
+			continue
+		}
+		if q.Explanation == "" {
+			q.Explanation = strings.TrimSpace(v.Explanation)
+			if q.Explanation == "" {
+				q.Explanation = "The correct answer is: " + grading.AnswerText(q) + "."
+			}
+			telemetry.GeneratedQuestionChecks.Inc("repaired", "missing_explanation")
+		}
+		kept = append(kept, q)
+	}
+	return kept, rejected
+}
@@ -0,0 +1,286 @@
+package media
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/grading"
+	"studyai/internal/models"
+)
+
+// Resource difficulties, from easiest.
+var resourceDifficulties = []string{"beginner", "intermediate", "advanced"}
+
+// resourceTypes lists the kinds of resource the catalog holds.
+var resourceTypes = []string{"video", "article", "book", "interactive"}
+
+const (
+	// maxRecommendations bounds how many resources one response recommends.
+	maxRecommendations = 5
+	// minRelevance is the relevance below which a resource is not
+	// recommended.
+	minRelevance = 0.4
+	// similarTopic is the word overlap above which two topic names count as
+	// related.
+	similarTopic = 0.5
+)
+
+// ResourceFilter selects resource catalog entries; empty fields match
+// everything.
+type ResourceFilter struct {
+	Topic      string
+	Type       string
+	Difficulty string
+}
+
+func (f ResourceFilter) matches(r models.Resource) bool {
+	return (f.Topic == "" || slices.ContainsFunc(r.Topics, func(t string) bool { return strings.EqualFold(t, f.Topic) })) &&
+		(f.Type == "" || r.Type == f.Type) &&
+		(f.Difficulty == "" || r.Difficulty == f.Difficulty)
+}
+
+// ListResources returns the catalog entries matching f, ordered by title.
+func (s *Service) ListResources(f ResourceFilter) models.ResourceList {
+	list := s.resources.Filter(f.matches)
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Title) < strings.ToLower(list[j].Title) })
+	return models.ResourceList{Total: len(list), Resources: list}
+}
+
+// GetResource returns one catalog entry.
+func (s *Service) GetResource(id string) (models.Resource, error) {
+	r, ok := s.resources.Get(id)
+	if !ok {
+		return r, apperr.New(apperr.CodeNotFound, "resource not found")
+	}
+	return r, nil
+}
+
+// CreateResource validates r and adds it to the catalog under a new ID.
+func (s *Service) CreateResource(r models.Resource) (models.Resource, error) {
+	if fields := normalizeResource(&r); len(fields) > 0 {
+		return r, apperr.Validation(fields...)
+	}
+	r.ID = newID("res")
+	r.CreatedAt = time.Now().UTC()
+	r.UpdatedAt = r.CreatedAt
+	if err := s.resources.Put(r.ID, r); err != nil {
+		return r, apperr.Wrap(apperr.CodeInternal, "failed to save resource", err)
+	}
+	return r, nil
+}
+
+// UpdateResource replaces an existing catalog entry.
+func (s *Service) UpdateResource(id string, r models.Resource) (models.Resource, error) {
+	if fields := normalizeResource(&r); len(fields) > 0 {
+		return r, apperr.Validation(fields...)
+	}
+	updated, err := s.resources.Update(id, func(cur models.Resource, exists bool) (models.Resource, error) {
+		if !exists {
+			return cur, apperr.New(apperr.CodeNotFound, "resource not found")
+		}
+		r.ID, r.CreatedAt, r.UpdatedAt = id, cur.CreatedAt, time.Now().UTC()
+		return r, nil
+	})
+	if err != nil && !apperr.Is(err, apperr.CodeNotFound) {
+		return r, apperr.Wrap(apperr.CodeInternal, "failed to save resource", err)
+	}
+	return updated, err
+}
+
+// DeleteResource removes a catalog entry.
+func (s *Service) DeleteResource(id string) error {
+	if _, ok := s.resources.Get(id); !ok {
+		return apperr.New(apperr.CodeNotFound, "resource not found")
+	}
+	if err := s.resources.Delete(id); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "failed to delete resource", err)
+	}
+	return nil
+}
+
+// normalizeResource tidies r in place and returns every problem that makes
+// it unusable.
+func normalizeResource(r *models.Resource) []apperr.FieldError {
+	var fields []apperr.FieldError
+	fail := func(field, msg string) {
+		fields = append(fields, apperr.FieldError{Field: field, Message: msg})
+	}
+	r.Title = strings.TrimSpace(r.Title)
+	r.URL = strings.TrimSpace(r.URL)
+	r.Type = strings.ToLower(strings.TrimSpace(r.Type))
+	r.Difficulty = strings.ToLower(strings.TrimSpace(r.Difficulty))
+	r.Description = strings.TrimSpace(r.Description)
+	if r.Difficulty == "" {
+		r.Difficulty = "intermediate"
+	}
+	var topics []string
+	for _, t := range r.Topics {
+		if t = strings.TrimSpace(t); t != "" && !slices.ContainsFunc(topics, func(o string) bool { return strings.EqualFold(o, t) }) {
+			topics = append(topics, t)
+		}
+	}
+	r.Topics = topics
+
+	if r.Title == "" {
+		fail("title", "must not be empty")
+	}
+	if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("url", "must be an http or https link")
+	}
+	if !slices.Contains(resourceTypes, r.Type) {
+		fail("type", "must be one of "+strings.Join(resourceTypes, ", "))
+	}
+	if !slices.Contains(resourceDifficulties, r.Difficulty) {
+		fail("difficulty", "must be one of "+strings.Join(resourceDifficulties, ", "))
+	}
+	if len(r.Topics) == 0 {
+		fail("topics", "must name at least one topic")
+	}
+	return fields
+}
+
+// resourceQuery describes what a student needs resources for.
+type resourceQuery struct {
+	topics     []string // most important first
+	text       string   // what the student is studying, such as worksheet text or missed questions
+	difficulty string   // beginner, intermediate or advanced; empty for any
+}
+
+// recommendResources ranks the catalog for q and returns the most relevant
+// entries, at most maxRecommendations. Only entries reaching minRelevance are
+// recommended, so an empty result means the catalog has nothing suitable.
+func (s *Service) recommendResources(q resourceQuery) []models.LearningMaterial {
+	text := grading.Words(q.text)
+	type scored struct {
+		r     models.Resource
+		score float64
+	}
+	var ranked []scored
+	for _, r := range s.resources.List() {
+		if score := s.relevance(r, q, text); score >= minRelevance {
+			ranked = append(ranked, scored{r, score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].r.Title < ranked[j].r.Title
+	})
+
+	materials := []models.LearningMaterial{}
+	for _, sc := range ranked[:min(len(ranked), maxRecommendations)] {
+		materials = append(materials, models.LearningMaterial{
+			Title:       sc.r.Title,
+			Description: sc.r.Description,
+			Type:        sc.r.Type,
+			URL:         sc.r.URL,
+			Difficulty:  sc.r.Difficulty,
+			ResourceID:  sc.r.ID,
+			Topics:      sc.r.Topics,
+			Relevance:   math.Round(sc.score*100) / 100,
+			Verified:    true,
+		})
+	}
+	return materials
+}
+
+// relevance scores how well a resource matches a query, from 0 to 1. Its
+// topics are matched against the query's topics, by name, curriculum topic
+// or word overlap, with earlier query topics weighing more; failing that,
+// against the words of the text. A resource at the wanted difficulty scores
+// a little higher, and one further than a level away a little lower.
+func (s *Service) relevance(r models.Resource, q resourceQuery, text []string) float64 {
+	g := s.graph.Load()
+	var topic float64
+	for i, want := range q.topics {
+		weight := max(0.5, 1-0.1*float64(i))
+		for _, have := range r.Topics {
+			switch {
+			case strings.EqualFold(have, want) || g.Key(have) == g.Key(want):
+				topic = max(topic, weight)
+			case grading.Similarity(have, want) >= similarTopic:
+				topic = max(topic, weight*grading.Similarity(have, want))
+			}
+		}
+	}
+	var mentioned float64
+	for _, have := range r.Topics {
+		if grading.ContainsPhrase(text, grading.Words(have)) {
+			mentioned = 0.8
+			break
+		}
+	}
+	if title := grading.Words(r.Title); len(title) > 0 && mentioned == 0 {
+		found := 0
+		for _, w := range title {
+			if len(w) > 3 && slices.Contains(text, w) {
+				found++
+			}
+		}
+		mentioned = 0.6 * float64(found) / float64(len(title))
+	}
+
+	score := max(topic, mentioned)
+	if q.difficulty != "" && score > 0 {
+		switch gap := slices.Index(resourceDifficulties, r.Difficulty) - slices.Index(resourceDifficulties, q.difficulty); {
+		case gap == 0:
+			score += 0.1
+		case gap > 1 || gap < -1:
+			score -= 0.1
+		}
+	}
+	return min(1, max(0, score))
+}
+
+// unverifiedMaterials labels LLM-suggested resources as unverified, keeping
+// at most maxRecommendations. Their links are dropped, since a suggested link
+// may not exist.
+func unverifiedMaterials(suggested []models.LearningMaterial) []models.LearningMaterial {
+	materials := []models.LearningMaterial{}
+	for _, m := range suggested {
+		if len(materials) == maxRecommendations {
+			break
+		}
+		if m.Title = strings.TrimSpace(m.Title); m.Title == "" {
+			continue
+		}
+		m.URL, m.ResourceID, m.Relevance, m.Verified = "", "", 0, false
+		materials = append(materials, m)
+	}
+	return materials
+}
+
+// materialDifficulty maps a quiz difficulty to a resource difficulty.
+func materialDifficulty(difficulty string) string {
+	switch difficulty {
+	case "easy":
+		return "beginner"
+	case "hard":
+		return "advanced"
+	case "medium":
+		return "intermediate"
+	}
+	return ""
+}
+
+// reviewResources recommends catalog resources for the weak areas of a
+// graded submission, using the missed questions as the text.
+func (s *Service) reviewResources(areas []models.WeakArea, questions []models.QuizQuestion, scores []itemScore, difficulty string) []models.LearningMaterial {
+	if len(areas) == 0 {
+		return nil
+	}
+	var missed strings.Builder
+	for i, q := range questions {
+		if scores[i].Credit < 1 {
+			fmt.Fprintf(&missed, "%s %s\n", q.Question, grading.AnswerText(q))
+		}
+	}
+	return s.recommendResources(resourceQuery{topics: areaNames(areas), text: missed.String(), difficulty: materialDifficulty(difficulty)})
+}
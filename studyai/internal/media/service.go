@@ -0,0 +1,110 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"studyai/internal/ai"
+	"studyai/internal/config"
+	"studyai/internal/curriculum"
+	"studyai/internal/models"
+	"studyai/internal/store"
+)
+
+// Service bundles the dependencies shared by the analysis, quiz and progress
+// features.
+type Service struct {
+	llm          *ai.Client
+	embedder     *ai.Embedder
+	adaptiveCfg  config.Adaptive
+	quizCfg      config.Quiz
+	retrievalCfg config.Retrieval
+
+	progress   *store.Collection[models.ProgressProfile]
+	quizzes    *store.Collection[QuizRecord]
+	attempts   *store.Collection[models.QuizAttempt]
+	sessions   *store.Collection[SessionRecord]
+	flashcards *store.Collection[models.Flashcard]
+	timers     *store.Collection[models.QuizTimer]
+	solutions  *store.Collection[models.WorkedSolution]
+
+	questionBank *store.Collection[models.BankQuestion]
+	resources    *store.Collection[models.Resource]
+
+	materials *store.Collection[models.CourseMaterial]
+	passages  *store.Collection[PassageRecord]
+
+	curricula *store.Collection[models.Curriculum]
+	standards *store.Collection[models.Standard]
+	graph     atomic.Pointer[curriculum.Graph]
+}
+
+// QuizRecord is a generated quiz kept server-side so that submissions are
+// graded against its answer key rather than one supplied by the client.
+type QuizRecord struct {
+	Quiz      models.QuizResponse `json:"quiz"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// NewService creates a media Service backed by the given LLM client and
+// embedder, keeping quizzes, attempts, quiz timers, worked solutions, test
+// sessions, flashcards, the question bank, the resource catalog, course
+// materials and their passages, the curriculum, the standards catalog and
+// progress profiles in st.
+func NewService(llm *ai.Client, embedder *ai.Embedder, st *store.Store, adaptiveCfg config.Adaptive, quizCfg config.Quiz, retrievalCfg config.Retrieval) (*Service, error) {
+	s := &Service{llm: llm, embedder: embedder, adaptiveCfg: adaptiveCfg, quizCfg: quizCfg, retrievalCfg: retrievalCfg}
+	var err error
+	if s.progress, err = store.NewCollection[models.ProgressProfile](st, "progress"); err != nil {
+		return nil, err
+	}
+	if s.quizzes, err = store.NewCollection[QuizRecord](st, "quizzes"); err != nil {
+		return nil, err
+	}
+	if s.attempts, err = store.NewCollection[models.QuizAttempt](st, "attempts"); err != nil {
+		return nil, err
+	}
+	if s.timers, err = store.NewCollection[models.QuizTimer](st, "quiz_timers"); err != nil {
+		return nil, err
+	}
+	if s.solutions, err = store.NewCollection[models.WorkedSolution](st, "solutions"); err != nil {
+		return nil, err
+	}
+	if s.sessions, err = store.NewCollection[SessionRecord](st, "sessions"); err != nil {
+		return nil, err
+	}
+	if s.flashcards, err = store.NewCollection[models.Flashcard](st, "flashcards"); err != nil {
+		return nil, err
+	}
+	if s.questionBank, err = store.NewCollection[models.BankQuestion](st, "question_bank"); err != nil {
+		return nil, err
+	}
+	if s.resources, err = store.NewCollection[models.Resource](st, "resources"); err != nil {
+		return nil, err
+	}
+	if s.materials, err = store.NewCollection[models.CourseMaterial](st, "course_materials"); err != nil {
+		return nil, err
+	}
+	if s.passages, err = store.NewCollection[PassageRecord](st, "passages"); err != nil {
+		return nil, err
+	}
+	if s.curricula, err = store.NewCollection[models.Curriculum](st, "curriculum"); err != nil {
+		return nil, err
+	}
+	if s.standards, err = store.NewCollection[models.Standard](st, "standards"); err != nil {
+		return nil, err
+	}
+	if err := s.loadCurriculum(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newID returns a unique identifier such as "quiz_1718000000_9f2c4a1b".
+func newID(prefix string) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s_%d_%s", prefix, time.Now().Unix(), hex.EncodeToString(b))
+}
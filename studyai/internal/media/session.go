@@ -0,0 +1,303 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"studyai/internal/adaptive"
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// sessionBatchSize is how many questions are generated at a time for a
+// difficulty level; unused ones stay in the session's pool.
+const sessionBatchSize = 3
+
+// stopPoolExhausted ends a session when no unseen question can be found.
+const stopPoolExhausted = "question_pool_exhausted"
+
+// SessionRecord is the server-side state of an adaptive test session. The
+// pending question carries its answer key and is never sent to clients.
+type SessionRecord struct {
+	Session   models.TestSession    `json:"session"`
+	Pending   *models.QuizQuestion  `json:"pending,omitempty"`
+	Pool      []models.QuizQuestion `json:"pool,omitempty"`
+	Asked     []string              `json:"asked"`
+	PriorMean float64               `json:"prior_mean"`
+	Seed      int64                 `json:"seed"`
+}
+
+// StartSession begins a computerized adaptive test. The starting ability is
+// the student's current estimate for the topic, or average for new students.
+func (s *Service) StartSession(ctx context.Context, req models.TestSessionRequest) (models.TestSession, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.StartSession",
+		slog.String("session.topic", req.TopicName),
+		slog.String("student.id", req.StudentID),
+	)
+	defer span.End()
+
+	prior := 0.0
+	if req.StudentID != "" {
+		profile, err := s.GetStudentProgress(req.StudentID)
+		if err != nil {
+			return models.TestSession{}, err
+		}
+		plan, err := adaptive.Plan(s.adaptiveCfg, adaptive.Input{
+			Profile:      profile,
+			Attempts:     s.StudentAttempts(req.StudentID),
+			Topic:        req.TopicName,
+			NumQuestions: 1,
+		})
+		if err != nil {
+			return models.TestSession{}, err
+		}
+		prior = plan.Topics[0].Ability
+	}
+
+	maxQuestions := s.adaptiveCfg.SessionMaxQuestions
+	if req.MaxQuestions > 0 && req.MaxQuestions < maxQuestions {
+		maxQuestions = req.MaxQuestions
+	}
+	rec := SessionRecord{
+		Session: models.TestSession{
+			SessionID:     newID("session"),
+			StudentID:     req.StudentID,
+			Topic:         req.TopicName,
+			Status:        "active",
+			Ability:       prior,
+			StandardError: 1,
+			TargetSE:      s.adaptiveCfg.SessionTargetSE,
+			MaxQuestions:  maxQuestions,
+			History:       []models.SessionItem{},
+			StartedAt:     time.Now().UTC(),
+		},
+		Asked:     []string{},
+		PriorMean: prior,
+		Seed:      newSeed(),
+	}
+	if !s.serveNext(ctx, &rec) {
+		return models.TestSession{}, apperr.New(apperr.CodeLLMUnavailable, "no questions could be generated for this topic")
+	}
+
+	if err := s.sessions.Put(rec.Session.SessionID, rec); err != nil {
+		return models.TestSession{}, apperr.Wrap(apperr.CodeInternal, "failed to save session", err)
+	}
+	return rec.Session, nil
+}
+
+// GetSession returns a session's current state.
+func (s *Service) GetSession(sessionID string) (models.TestSession, error) {
+	rec, ok := s.sessions.Get(sessionID)
+	if !ok {
+		return models.TestSession{}, apperr.New(apperr.CodeNotFound, "session not found")
+	}
+	return rec.Session, nil
+}
+
+// AnswerSession grades the answer to the current question, updates the
+// ability estimate and either serves the next question or ends the session.
+func (s *Service) AnswerSession(ctx context.Context, sessionID string, req models.SessionAnswerRequest) (models.SessionAnswerResult, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.AnswerSession", slog.String("session.id", sessionID))
+	defer span.End()
+
+	rec, ok := s.sessions.Get(sessionID)
+	if !ok {
+		return models.SessionAnswerResult{}, apperr.New(apperr.CodeNotFound, "session not found")
+	}
+	if rec.Session.Status != "active" || rec.Pending == nil {
+		return models.SessionAnswerResult{}, apperr.New(apperr.CodeConflict, "session is already completed")
+	}
+	q := *rec.Pending
+	if req.QuestionID != q.ID {
+		return models.SessionAnswerResult{}, apperr.New(apperr.CodeConflict, fmt.Sprintf("question %s is not the current question (%s)", req.QuestionID, q.ID))
+	}
+	if req.Answer >= len(q.Options) {
+		return models.SessionAnswerResult{}, apperr.Validation(apperr.FieldError{Field: "answer", Message: fmt.Sprintf("must be less than %d", len(q.Options))})
+	}
+	answeredBefore := rec.Session.Answered
+
+	// Re-estimate ability from every answer in this session, starting from
+	// the student's prior estimate.
+	correct := req.Answer == q.CorrectAnswer
+	rec.Pending = nil
+	rec.Session.Current = nil
+	rec.Session.Answered++
+	if correct {
+		rec.Session.Correct++
+	}
+	rs := make([]adaptive.Response, 0, len(rec.Session.History)+1)
+	for _, item := range rec.Session.History {
+		rs = append(rs, adaptive.Response{Difficulty: adaptive.ItemDifficulty(item.Difficulty), Correct: item.Correct, Weight: 1})
+	}
+	rs = append(rs, adaptive.Response{Difficulty: adaptive.ItemDifficulty(q.Difficulty), Correct: correct, Weight: 1})
+	est := adaptive.EstimateAbility(rs, rec.PriorMean, 1)
+	rec.Session.Ability = round2(est.Theta)
+	rec.Session.StandardError = round2(est.SE)
+	rec.Session.History = append(rec.Session.History, models.SessionItem{
+		QuestionID:    q.ID,
+		Question:      q.Question,
+		Difficulty:    q.Difficulty,
+		Selected:      req.Answer,
+		CorrectAnswer: q.CorrectAnswer,
+		Correct:       correct,
+		AbilityAfter:  rec.Session.Ability,
+	})
+
+	done, reason := adaptive.SessionDone(est, rec.Session.Answered, s.adaptiveCfg.SessionMinQuestions, rec.Session.MaxQuestions, rec.Session.TargetSE)
+	if !done && !s.serveNext(ctx, &rec) {
+		done, reason = true, stopPoolExhausted
+	}
+	if done {
+		now := time.Now().UTC()
+		rec.Session.Status = "completed"
+		rec.Session.StopReason = reason
+		rec.Session.CompletedAt = &now
+		rec.Session.Score = rec.Session.Correct * 100 / rec.Session.Answered
+		rec.Pool = nil
+	}
+
+	// The next question may have taken an LLM call; refuse to overwrite a
+	// concurrent answer to the same question.
+	if _, err := s.sessions.Update(sessionID, func(cur SessionRecord, exists bool) (SessionRecord, error) {
+		if !exists || cur.Session.Answered != answeredBefore {
+			return cur, apperr.New(apperr.CodeConflict, "question was already answered")
+		}
+		return rec, nil
+	}); err != nil {
+		if apperr.Is(err, apperr.CodeConflict) {
+			return models.SessionAnswerResult{}, err
+		}
+		return models.SessionAnswerResult{}, apperr.Wrap(apperr.CodeInternal, "failed to save session", err)
+	}
+
+	if done && rec.Session.StudentID != "" {
+		attemptID, err := s.recordSession(rec.Session)
+		if err != nil {
+			return models.SessionAnswerResult{}, err
+		}
+		rec.Session.AttemptID = attemptID
+	}
+
+	span.SetAttributes(slog.Float64("session.ability", rec.Session.Ability), slog.String("session.status", rec.Session.Status))
+	result := models.SessionAnswerResult{
+		Correct:       correct,
+		CorrectAnswer: q.CorrectAnswer,
+		Explanation:   q.Explanation,
+		Session:       rec.Session,
+	}
+	if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) {
+		result.CorrectOption = q.Options[q.CorrectAnswer]
+	}
+	return result, nil
+}
+
+// serveNext picks the most informative difficulty for the current estimate
+// and makes an unseen question at that level current. It reports false when
+// no unseen question could be found.
+func (s *Service) serveNext(ctx context.Context, rec *SessionRecord) bool {
+	level := adaptive.MostInformativeLevel(rec.Session.Ability)
+	q, ok := s.takeFromPool(rec, level)
+	if !ok {
+		questions, fallback, err := s.generateQuestions(ctx, rec.Session.Topic, level, nil, sessionBatchSize)
+		if err != nil {
+			slog.WarnContext(ctx, "no questions for test session", "session_id", rec.Session.SessionID, "err", err)
+		}
+		rec.Session.IsDevFallback = rec.Session.IsDevFallback || fallback
+		for _, g := range questions {
+			if !slices.Contains(rec.Asked, questionKey(g.Question)) {
+				rec.Pool = append(rec.Pool, g)
+			}
+		}
+		if q, ok = s.takeFromPool(rec, level); !ok {
+			// Settle for a less informative unseen question over ending early.
+			if q, ok = s.takeFromPool(rec, ""); !ok {
+				return false
+			}
+		}
+	}
+
+	number := rec.Session.Answered + 1
+	q.ID = fmt.Sprintf("q_%d", number)
+	shuffleQuestionOptions(&q, rec.Seed, number)
+	rec.Pending = &q
+	rec.Asked = append(rec.Asked, questionKey(q.Question))
+	rec.Session.Current = &models.SessionQuestion{
+		ID:         q.ID,
+		Number:     number,
+		Question:   q.Question,
+		Options:    q.Options,
+		Difficulty: q.Difficulty,
+	}
+	return true
+}
+
+// takeFromPool removes and returns an unseen pooled question at level, or at
+// any level when level is empty.
+func (s *Service) takeFromPool(rec *SessionRecord, level string) (models.QuizQuestion, bool) {
+	for i, q := range rec.Pool {
+		if level != "" && q.Difficulty != level {
+			continue
+		}
+		rec.Pool = slices.Delete(rec.Pool, i, i+1)
+		if slices.Contains(rec.Asked, questionKey(q.Question)) {
+			return s.takeFromPool(rec, level)
+		}
+		return q, true
+	}
+	return models.QuizQuestion{}, false
+}
+
+// recordSession stores a completed session as a quiz attempt so it feeds the
+// student's history like any other graded quiz.
+func (s *Service) recordSession(session models.TestSession) (string, error) {
+	attempt := models.QuizAttempt{
+		ID:          newID("attempt"),
+		StudentID:   session.StudentID,
+		QuizID:      session.SessionID,
+		Topic:       session.Topic,
+		Difficulty:  "adaptive",
+		Correct:     session.Correct,
+		Total:       session.Answered,
+		Score:       session.Score,
+		SubmittedAt: time.Now().UTC(),
+	}
+	for _, item := range session.History {
+		credit := 0.0
+		if item.Correct {
+			credit = 1
+		}
+		attempt.Items = append(attempt.Items, models.ItemOutcome{
+			QuestionID: item.QuestionID,
+			Topic:      session.Topic,
+			Difficulty: item.Difficulty,
+			Selected:   item.Selected,
+			Correct:    item.Correct,
+			Credit:     credit,
+		})
+	}
+	if err := s.saveAttempt(attempt, nil); err != nil {
+		return "", err
+	}
+	if _, err := s.sessions.Update(session.SessionID, func(rec SessionRecord, exists bool) (SessionRecord, error) {
+		rec.Session.AttemptID = attempt.ID
+		return rec, nil
+	}); err != nil {
+		return "", apperr.Wrap(apperr.CodeInternal, "failed to save session", err)
+	}
+	return attempt.ID, nil
+}
+
+// questionKey normalizes question text for duplicate detection.
+func questionKey(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
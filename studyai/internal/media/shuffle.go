@@ -0,0 +1,117 @@
+package media
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/grading"
+	"studyai/internal/models"
+)
+
+// maxSeed keeps quiz seeds exactly representable as JSON numbers.
+const maxSeed = 1 << 53
+
+// newSeed returns a random quiz seed.
+func newSeed() int64 {
+	return rand.Int64N(maxSeed)
+}
+
+// shuffleOptions reorders the options of single-choice and multi-select
+// questions and remaps their answer keys and misconception labels, so the
+// correct answer is not always first. Each question's order depends only on
+// seed and its position, so the same seed reproduces the same quiz. Options
+// such as "None of the above" stay in place.
+func shuffleOptions(questions []models.QuizQuestion, seed int64) {
+	for i := range questions {
+		shuffleQuestionOptions(&questions[i], seed, i)
+	}
+}
+
+// shuffleQuestionOptions shuffles one question's options with the generator
+// for seed and position.
+func shuffleQuestionOptions(q *models.QuizQuestion, seed int64, position int) {
+	t := grading.TypeOf(*q)
+	if t != grading.SingleChoice && t != grading.MultiSelect {
+		return
+	}
+	var movable []int
+	for i, opt := range q.Options {
+		if !strings.HasSuffix(questionKey(opt), "of the above") {
+			movable = append(movable, i)
+		}
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(position)))
+	target := slices.Clone(movable)
+	rng.Shuffle(len(target), func(i, j int) { target[i], target[j] = target[j], target[i] })
+
+	// moved[old] is the option's new index.
+	moved := make([]int, len(q.Options))
+	for i := range moved {
+		moved[i] = i
+	}
+	options := slices.Clone(q.Options)
+	for k, from := range movable {
+		to := target[k]
+		options[to] = q.Options[from]
+		moved[from] = to
+	}
+	q.Options = options
+	if len(q.Misconceptions) == len(moved) {
+		labels := slices.Clone(q.Misconceptions)
+		for from, to := range moved {
+			labels[to] = q.Misconceptions[from]
+		}
+		q.Misconceptions = labels
+	}
+	if t == grading.SingleChoice {
+		if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(moved) {
+			q.CorrectAnswer = moved[q.CorrectAnswer]
+		}
+		return
+	}
+	for i, a := range q.CorrectAnswers {
+		if a >= 0 && a < len(moved) {
+			q.CorrectAnswers[i] = moved[a]
+		}
+	}
+}
+
+// questionOrder is the order a student sees a quiz's questions in: a
+// permutation fixed by the quiz seed and the student ID.
+func questionOrder(quiz models.QuizResponse, studentID string) []int {
+	h := fnv.New64a()
+	h.Write([]byte(studentID))
+	return rand.New(rand.NewPCG(uint64(quiz.Seed), h.Sum64())).Perm(len(quiz.Questions))
+}
+
+// forStudent returns the quiz as studentID sees it. Quizzes with
+// shuffle_questions list their questions in a per-student order; submissions
+// from that student are graded in the same order.
+func forStudent(quiz models.QuizResponse, studentID string) models.QuizResponse {
+	if !quiz.ShuffleQuestions || studentID == "" {
+		return quiz
+	}
+	questions := make([]models.QuizQuestion, len(quiz.Questions))
+	for i, j := range questionOrder(quiz, studentID) {
+		questions[i] = quiz.Questions[j]
+	}
+	quiz.Questions = questions
+	quiz.StudentID = studentID
+	return quiz
+}
+
+// GetQuiz returns a stored quiz as the given student sees it, without its
+// answer keys. Without a student ID, questions are in the quiz's own order.
+func (s *Service) GetQuiz(quizID, studentID string) (models.StudentQuiz, error) {
+	rec, ok := s.quizzes.Get(quizID)
+	if !ok {
+		return models.StudentQuiz{}, apperr.New(apperr.CodeNotFound, "quiz not found")
+	}
+	if studentID == "" {
+		studentID = rec.Quiz.StudentID
+	}
+	return studentQuiz(forStudent(rec.Quiz, studentID)), nil
+}
@@ -0,0 +1,271 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/grading"
+	"studyai/internal/guardrails"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// assessmentWindow is how long an untimed assessment counts as in progress
+// after the student starts it.
+const assessmentWindow = 3 * time.Hour
+
+// maxSolutionContent bounds how much worksheet text goes into the prompt.
+const maxSolutionContent = 4000
+
+// Generic rungs used when the LLM's nudge or hint gives the answer away.
+const (
+	fallbackNudge = "Read the question again and write down what it gives you and what it asks for."
+	fallbackHint  = "Work out which rule or method links what you are given to what you need, then apply it one step at a time."
+)
+
+// CreateSolution builds a hint ladder for one question and reveals its
+// first rung, the nudge. While the student sits a flagged assessment,
+// questions from that assessment are refused and other questions stop at
+// the nudge.
+func (s *Service) CreateSolution(ctx context.Context, req models.SolutionRequest) (models.WorkedSolution, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.CreateSolution")
+	defer span.End()
+
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return models.WorkedSolution{}, apperr.Validation(apperr.FieldError{Field: "question", Message: "must not be empty"})
+	}
+	if _, err := s.solutionHelp(req.StudentID, question, time.Now().UTC()); err != nil {
+		return models.WorkedSolution{}, err
+	}
+
+	sol, err := s.solveQuestion(ctx, question, req.Content, req.Grade)
+	if err != nil {
+		telemetry.FallbackActivations.Inc("solution.ladder", "no_solution")
+		return models.WorkedSolution{}, apperr.Wrap(apperr.CodeLLMUnavailable, "worked solutions are unavailable while the LLM is unavailable", err)
+	}
+	sol.SolutionID = newID("solution")
+	sol.Question = question
+	sol.StudentID = req.StudentID
+	sol.Revealed = guardrails.HelpNudge
+	sol.CreatedAt = time.Now().UTC()
+	if err := s.solutions.Put(sol.SolutionID, sol); err != nil {
+		return models.WorkedSolution{}, apperr.Wrap(apperr.CodeInternal, "failed to save solution", err)
+	}
+	return s.solutionView(sol, time.Now().UTC()), nil
+}
+
+// GetSolution returns a worked solution with only its revealed rungs.
+func (s *Service) GetSolution(solutionID string) (models.WorkedSolution, error) {
+	sol, ok := s.solutions.Get(solutionID)
+	if !ok {
+		return models.WorkedSolution{}, apperr.New(apperr.CodeNotFound, "solution not found")
+	}
+	return s.solutionView(sol, time.Now().UTC()), nil
+}
+
+// RevealSolution reveals the next rung of a worked solution's hint ladder.
+// Revealing past what the assessment guardrail allows is refused; once every
+// rung is shown the solution is returned unchanged.
+func (s *Service) RevealSolution(solutionID string) (models.WorkedSolution, error) {
+	now := time.Now().UTC()
+	sol, err := s.solutions.Update(solutionID, func(sol models.WorkedSolution, exists bool) (models.WorkedSolution, error) {
+		if !exists {
+			return sol, apperr.New(apperr.CodeNotFound, "solution not found")
+		}
+		next := nextLevel(sol.Revealed)
+		if next == "" {
+			return sol, nil
+		}
+		allowed, err := s.solutionHelp(sol.StudentID, sol.Question, now)
+		if err != nil {
+			return sol, err
+		}
+		if levelIndex(next) > levelIndex(allowed) {
+			return sol, apperr.New(apperr.CodeGuardrailRefused, "hints and full solutions are withheld while an assessment is in progress")
+		}
+		sol.Revealed = next
+		return sol, nil
+	})
+	if err != nil {
+		if apperr.Is(err, apperr.CodeNotFound) || apperr.Is(err, apperr.CodeGuardrailRefused) {
+			return models.WorkedSolution{}, err
+		}
+		return models.WorkedSolution{}, apperr.Wrap(apperr.CodeInternal, "failed to save solution", err)
+	}
+	return s.solutionView(sol, now), nil
+}
+
+// solutionHelp applies the assessment guardrail to a student's question,
+// returning the most revealing level they may see now.
+func (s *Service) solutionHelp(studentID, question string, now time.Time) (string, error) {
+	if studentID == "" {
+		return guardrails.SolutionHelp(false, false)
+	}
+	questions, active := s.activeAssessment(studentID, now)
+	fromAssessment := slices.ContainsFunc(questions, func(q models.QuizQuestion) bool {
+		return sameQuestion(q, models.QuizQuestion{Question: question})
+	})
+	return guardrails.SolutionHelp(active, fromAssessment)
+}
+
+// activeAssessment reports whether a student is sitting a flagged
+// assessment: one they have started but not submitted, whose deadline and
+// grace period (or, untimed, assessmentWindow) have not passed. It returns
+// the questions of every such assessment.
+func (s *Service) activeAssessment(studentID string, now time.Time) ([]models.QuizQuestion, bool) {
+	timers := s.timers.Filter(func(t models.QuizTimer) bool {
+		if t.StudentID != studentID || t.SubmittedAt != nil {
+			return false
+		}
+		end := t.StartedAt.Add(assessmentWindow)
+		if t.Deadline != nil {
+			end = t.Deadline.Add(time.Duration(t.GraceSeconds) * time.Second)
+		}
+		return !now.After(end)
+	})
+	var questions []models.QuizQuestion
+	active := false
+	for _, t := range timers {
+		if rec, ok := s.quizzes.Get(t.QuizID); ok && rec.Quiz.Assessment {
+			active = true
+			questions = append(questions, rec.Quiz.Questions...)
+		}
+	}
+	return questions, active
+}
+
+// InAssessment reports whether a student is sitting a flagged assessment
+// now.
+func (s *Service) InAssessment(studentID string) bool {
+	_, active := s.activeAssessment(studentID, time.Now())
+	return active
+}
+
+// solutionView hides the rungs a solution has not revealed, and the ones the
+// assessment guardrail currently withholds.
+func (s *Service) solutionView(sol models.WorkedSolution, now time.Time) models.WorkedSolution {
+	shown := levelIndex(sol.Revealed)
+	allowed, err := s.solutionHelp(sol.StudentID, sol.Question, now)
+	switch {
+	case err != nil:
+		shown = -1
+		sol.Withheld = err.Error()
+	case levelIndex(allowed) < levelIndex(nextLevel(sol.Revealed)):
+		shown = min(shown, levelIndex(allowed))
+		sol.Withheld = "hints and full solutions are withheld while an assessment is in progress"
+	}
+	if shown < 0 {
+		sol.Nudge = ""
+	}
+	if shown < levelIndex(guardrails.HelpHint) {
+		sol.Hint = ""
+	}
+	if shown < levelIndex(guardrails.HelpSolution) {
+		sol.Steps, sol.Answer = nil, ""
+	}
+	if shown >= 0 {
+		sol.Revealed = guardrails.HelpLevels[shown]
+	}
+	if sol.Next = nextLevel(sol.Revealed); sol.Withheld != "" {
+		sol.Next = ""
+	}
+	return sol
+}
+
+// solveQuestion asks the LLM for a question's hint ladder. A nudge or hint
+// that states the answer is swapped for a generic one, so each rung only
+// reveals what it is meant to.
+func (s *Service) solveQuestion(ctx context.Context, question, content string, grade int) (models.WorkedSolution, error) {
+	var source string
+	if content = strings.TrimSpace(content); content != "" {
+		if len(content) > maxSolutionContent {
+			content = strings.ToValidUTF8(content[:maxSolutionContent], "")
+		}
+		source = "\nWORKSHEET TEXT, for reference:\n" + content + "\n"
+	}
+	level := "a school student"
+	if grade > 0 {
+		level = fmt.Sprintf("a Grade %d student", grade)
+	}
+
+	prompt := fmt.Sprintf(`
+Help %s work through this homework question without simply handing over the answer.
+
+QUESTION: %s
+%s
+Return JSON in this format:
+{
+  "nudge": "One sentence pointing at where to start. Do not solve any part of the question",
+  "hint": "The method or first step to use. Do not state the final answer",
+  "steps": ["step 1", "step 2"],
+  "answer": "The final answer, stated briefly"
+}
+
+Notes:
+- steps is the full worked solution, one short step per entry, ending with the answer
+- Answers must agree with the worksheet text when it is given
+- Return ONLY valid JSON, no additional text
+`, level, question, source)
+
+	reply, err := s.llm.CallJSON(ctx, "solution.ladder", prompt)
+	if err != nil {
+		return models.WorkedSolution{}, err
+	}
+	var out struct {
+		Nudge  string   `json:"nudge"`
+		Hint   string   `json:"hint"`
+		Steps  []string `json:"steps"`
+		Answer string   `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(reply), &out); err != nil {
+		return models.WorkedSolution{}, err
+	}
+	var steps []string
+	for _, step := range out.Steps {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	answer := strings.TrimSpace(out.Answer)
+	if len(steps) == 0 || answer == "" {
+		return models.WorkedSolution{}, fmt.Errorf("solution has no steps or answer")
+	}
+
+	sol := models.WorkedSolution{Nudge: strings.TrimSpace(out.Nudge), Hint: strings.TrimSpace(out.Hint), Steps: steps, Answer: answer}
+	if sol.Nudge == "" || givesAway(sol.Nudge, answer) {
+		telemetry.FallbackActivations.Inc("solution.ladder", "leaky_nudge")
+		sol.Nudge = fallbackNudge
+	}
+	if sol.Hint == "" || givesAway(sol.Hint, answer) {
+		telemetry.FallbackActivations.Inc("solution.ladder", "leaky_hint")
+		sol.Hint = fallbackHint
+	}
+	slog.InfoContext(ctx, "built worked solution", "steps", len(steps))
+	return sol, nil
+}
+
+// givesAway reports whether text states the answer: the answer's words, in
+// order, ignoring case and punctuation.
+func givesAway(text, answer string) bool {
+	return grading.ContainsPhrase(grading.Words(text), grading.Words(answer))
+}
+
+// levelIndex is a level's position in the hint ladder, or -1 when unknown.
+func levelIndex(level string) int {
+	return slices.Index(guardrails.HelpLevels, level)
+}
+
+// nextLevel is the level revealed after the given one, or "" at the top.
+func nextLevel(level string) string {
+	if i := levelIndex(level); i+1 < len(guardrails.HelpLevels) {
+		return guardrails.HelpLevels[i+1]
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package media
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"studyai/internal/apperr"
+	"studyai/internal/curriculum"
+	"studyai/internal/models"
+)
+
+// StandardFilter selects catalog standards; empty fields match everything.
+type StandardFilter struct {
+	Framework string
+	Subject   string
+	Grade     string
+}
+
+func (f StandardFilter) matches(st models.Standard) bool {
+	return (f.Framework == "" || strings.EqualFold(st.Framework, f.Framework)) &&
+		(f.Subject == "" || strings.EqualFold(st.Subject, f.Subject)) &&
+		(f.Grade == "" || strings.EqualFold(st.Grade, f.Grade))
+}
+
+// ListStandards returns the catalog standards matching f, ordered by
+// framework and code.
+func (s *Service) ListStandards(f StandardFilter) models.StandardList {
+	list := s.standards.Filter(f.matches)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Framework != list[j].Framework {
+			return list[i].Framework < list[j].Framework
+		}
+		return list[i].Code < list[j].Code
+	})
+	return models.StandardList{Total: len(list), Standards: list}
+}
+
+// ImportStandards adds or updates catalog standards from a JSON or CSV file.
+// Standards are keyed by framework and code, so importing a file again
+// updates them, and frameworks that share a code keep separate entries.
+// Accepted entries are saved with a single write, so a failure saves none of
+// them.
+func (s *Service) ImportStandards(format string, data []byte) (models.StandardImportResult, error) {
+	result := models.StandardImportResult{Format: format, Rejected: []models.BankImportError{}}
+	items, err := curriculum.DecodeStandards(format, data)
+	if err != nil {
+		return result, apperr.Wrap(apperr.CodeInvalidRequest, "could not read "+format+" file: "+err.Error(), err)
+	}
+
+	accepted := map[string]models.Standard{}
+	seen := map[string]int{}
+	var keys []string
+	for i, item := range items {
+		if item.Err != nil {
+			result.Rejected = append(result.Rejected, models.BankImportError{Item: i + 1, Message: item.Err.Error()})
+			continue
+		}
+		key := standardKey(item.Standard)
+		if first, ok := seen[key]; ok {
+			result.Rejected = append(result.Rejected, models.BankImportError{
+				Item:    i + 1,
+				Message: fmt.Sprintf("standard %q in %q repeats item %d", item.Standard.Code, item.Standard.Framework, first),
+			})
+			continue
+		}
+		seen[key] = i + 1
+		accepted[key] = item.Standard
+		keys = append(keys, key)
+	}
+
+	var created, updated int
+	_, err = s.standards.UpdateMany(keys, func(key string, _ models.Standard, exists bool) (models.Standard, error) {
+		if exists {
+			updated++
+		} else {
+			created++
+		}
+		return accepted[key], nil
+	})
+	if err != nil {
+		return result, apperr.Wrap(apperr.CodeInternal, "failed to save standards; none were saved", err)
+	}
+	result.Created, result.Updated = created, updated
+	return result, nil
+}
+
+// standardKey is the catalog key of st.
+func standardKey(st models.Standard) string {
+	return st.Framework + "/" + st.Code
+}
+
+// LoadStandardFiles imports standards catalog files from the local disk,
+// choosing the format from each file's extension. Rejected entries are
+// logged and skipped; a file that cannot be read fails the load.
+func (s *Service) LoadStandardFiles(paths []string) error {
+	for _, path := range paths {
+		format, err := curriculum.FormatOf(path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading standards file: %w", err)
+		}
+		result, err := s.ImportStandards(format, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, r := range result.Rejected {
+			slog.Warn("skipped standard", "file", path, "item", r.Item, "reason", r.Message)
+		}
+		slog.Info("loaded standards file", "file", path, "created", result.Created, "updated", result.Updated, "rejected", len(result.Rejected))
+	}
+	return nil
+}
+
+// checkStandards reports the codes that are not in the catalog under any
+// framework.
+func (s *Service) checkStandards(field string, codes []string) []apperr.FieldError {
+	if len(codes) == 0 {
+		return nil
+	}
+	known := map[string]bool{}
+	for _, st := range s.standards.List() {
+		known[st.Code] = true
+	}
+	var fields []apperr.FieldError
+	for i, c := range codes {
+		if !known[c] {
+			fields = append(fields, apperr.FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Message: fmt.Sprintf("unknown standard %q", c)})
+		}
+	}
+	return fields
+}
+
+// TagQuestionStandards replaces the standard codes of a question in a stored
+// quiz. Attempts already recorded keep the codes they were graded under.
+func (s *Service) TagQuestionStandards(quizID, questionID string, req models.StandardTagRequest) (models.QuizQuestion, error) {
+	codes := curriculum.Codes(req.Standards)
+	if fields := s.checkStandards("standards", codes); len(fields) > 0 {
+		return models.QuizQuestion{}, apperr.Validation(fields...)
+	}
+
+	var updated models.QuizQuestion
+	_, err := s.quizzes.Update(quizID, func(rec QuizRecord, exists bool) (QuizRecord, error) {
+		if !exists {
+			return rec, apperr.New(apperr.CodeNotFound, "quiz not found")
+		}
+		i := slices.IndexFunc(rec.Quiz.Questions, func(q models.QuizQuestion) bool { return q.ID == questionID })
+		if i < 0 {
+			return rec, apperr.New(apperr.CodeNotFound, "question not found")
+		}
+		rec.Quiz.Questions[i].Standards = codes
+		updated = rec.Quiz.Questions[i]
+		return rec, nil
+	})
+	if err != nil && !apperr.Is(err, apperr.CodeNotFound) {
+		return updated, apperr.Wrap(apperr.CodeInternal, "failed to save quiz", err)
+	}
+	return updated, err
+}
+
+// StandardsReport returns a student's mastery of each standard they have
+// answered questions on, optionally limited to one framework. Attempts carry
+// only codes, so a code shared by several frameworks is described by the
+// first framework in name order.
+func (s *Service) StandardsReport(studentID, framework string) models.StandardsReport {
+	catalog := map[string]models.Standard{}
+	for _, st := range s.ListStandards(StandardFilter{Framework: framework}).Standards {
+		if _, ok := catalog[st.Code]; !ok {
+			catalog[st.Code] = st
+		}
+	}
+	return models.StandardsReport{
+		StudentID: studentID,
+		Standards: s.graph.Load().StandardMastery(catalog, s.StudentAttempts(studentID)),
+	}
+}
@@ -0,0 +1,230 @@
+package media
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"studyai/internal/apperr"
+	"studyai/internal/models"
+)
+
+// Late policies for timed quizzes.
+const (
+	LateFlag   = "flag"
+	LateReject = "reject"
+)
+
+// timerKey identifies a student's timer on a quiz.
+func timerKey(quizID, studentID string) string {
+	return quizID + "/" + studentID
+}
+
+// applyLateRules sets a timed quiz's late policy and grace period from the
+// request, falling back to the server defaults.
+func (s *Service) applyLateRules(quiz *models.QuizResponse, policy string, graceSeconds *int) {
+	if quiz.TimeLimit <= 0 {
+		return
+	}
+	quiz.LatePolicy = s.quizCfg.LatePolicy
+	if policy != "" {
+		quiz.LatePolicy = policy
+	}
+	quiz.GraceSeconds = int(s.quizCfg.GracePeriod.Std().Seconds())
+	if graceSeconds != nil {
+		quiz.GraceSeconds = *graceSeconds
+	}
+}
+
+// StartQuiz starts a student's timer on a quiz, or returns the one already
+// running. Without a student ID the quiz's own student is used.
+func (s *Service) StartQuiz(quizID, studentID string) (models.QuizTimer, error) {
+	rec, ok := s.quizzes.Get(quizID)
+	if !ok {
+		return models.QuizTimer{}, apperr.New(apperr.CodeNotFound, "quiz not found")
+	}
+	if studentID == "" {
+		studentID = rec.Quiz.StudentID
+	}
+	return s.startTimer(rec.Quiz, studentID, time.Now().UTC())
+}
+
+// startTimer records when studentID started quiz. Timed quizzes get a
+// deadline time_limit seconds later.
+func (s *Service) startTimer(quiz models.QuizResponse, studentID string, now time.Time) (models.QuizTimer, error) {
+	timer, err := s.timers.Update(timerKey(quiz.QuizID, studentID), func(t models.QuizTimer, exists bool) (models.QuizTimer, error) {
+		if exists {
+			if t.SubmittedAt != nil {
+				return t, apperr.New(apperr.CodeConflict, "quiz was already submitted")
+			}
+			return t, nil
+		}
+		t = models.QuizTimer{
+			QuizID:       quiz.QuizID,
+			StudentID:    studentID,
+			StartedAt:    now,
+			GraceSeconds: quiz.GraceSeconds,
+			LatePolicy:   quiz.LatePolicy,
+			Answers:      []models.TimedAnswer{},
+		}
+		if t.LatePolicy == "" {
+			t.LatePolicy = s.quizCfg.LatePolicy
+			t.GraceSeconds = int(s.quizCfg.GracePeriod.Std().Seconds())
+		}
+		if quiz.TimeLimit > 0 {
+			deadline := now.Add(time.Duration(quiz.TimeLimit) * time.Second)
+			t.Deadline = &deadline
+		}
+		return t, nil
+	})
+	if err != nil {
+		if apperr.Is(err, apperr.CodeConflict) {
+			return timer, err
+		}
+		return timer, apperr.Wrap(apperr.CodeInternal, "failed to save quiz timer", err)
+	}
+	return withRemaining(timer, now), nil
+}
+
+// SaveAnswer stores the answer to one question of a started quiz and adds
+// the time since the previous save (or the start) to that question. Answers
+// may be changed until the quiz is submitted.
+func (s *Service) SaveAnswer(quizID, questionID string, req models.QuizItemAnswerRequest) (models.QuizTimer, error) {
+	rec, ok := s.quizzes.Get(quizID)
+	if !ok {
+		return models.QuizTimer{}, apperr.New(apperr.CodeNotFound, "quiz not found")
+	}
+	if !slices.ContainsFunc(rec.Quiz.Questions, func(q models.QuizQuestion) bool { return q.ID == questionID }) {
+		return models.QuizTimer{}, apperr.New(apperr.CodeNotFound, "question not found")
+	}
+	studentID := req.StudentID
+	if studentID == "" {
+		studentID = rec.Quiz.StudentID
+	}
+
+	now := time.Now().UTC()
+	timer, err := s.timers.Update(timerKey(quizID, studentID), func(t models.QuizTimer, exists bool) (models.QuizTimer, error) {
+		if !exists {
+			return t, apperr.New(apperr.CodeConflict, "quiz has not been started")
+		}
+		if t.SubmittedAt != nil {
+			return t, apperr.New(apperr.CodeConflict, "quiz was already submitted")
+		}
+		late, over := overdue(t, now)
+		if late && t.LatePolicy == LateReject {
+			return t, apperr.New(apperr.CodeConflict, fmt.Sprintf("the time limit passed %d seconds ago", over))
+		}
+
+		since := t.StartedAt
+		for _, a := range t.Answers {
+			if a.AnsweredAt.After(since) {
+				since = a.AnsweredAt
+			}
+		}
+		seconds := round2(now.Sub(since).Seconds())
+		if i := slices.IndexFunc(t.Answers, func(a models.TimedAnswer) bool { return a.QuestionID == questionID }); i >= 0 {
+			a := &t.Answers[i]
+			a.Response, a.AnsweredAt = req.Response, now
+			a.Seconds = round2(a.Seconds + seconds)
+			a.Late = a.Late || late
+		} else {
+			t.Answers = append(t.Answers, models.TimedAnswer{
+				QuestionID: questionID,
+				Response:   req.Response,
+				Seconds:    seconds,
+				AnsweredAt: now,
+				Late:       late,
+			})
+		}
+		return t, nil
+	})
+	if err != nil {
+		if apperr.Is(err, apperr.CodeConflict) {
+			return timer, err
+		}
+		return timer, apperr.Wrap(apperr.CodeInternal, "failed to save quiz timer", err)
+	}
+	return withRemaining(timer, now), nil
+}
+
+// savedResponses returns a timer's saved answers in the order of questions;
+// unanswered questions get an empty response.
+func savedResponses(t models.QuizTimer, questions []models.QuizQuestion) []models.QuizAnswer {
+	responses := make([]models.QuizAnswer, len(questions))
+	for i, q := range questions {
+		if j := slices.IndexFunc(t.Answers, func(a models.TimedAnswer) bool { return a.QuestionID == q.ID }); j >= 0 {
+			responses[i] = t.Answers[j].Response
+		}
+	}
+	return responses
+}
+
+// finishTimer closes studentID's timer on quiz and reports how long the
+// submission took. Untimed quizzes that were never started have no timing;
+// a timed quiz must be started first, so its time limit cannot be skipped.
+// Under the reject policy a submission past the deadline and grace period
+// fails with a conflict and the timer stays open.
+func (s *Service) finishTimer(quiz models.QuizResponse, studentID string, now time.Time) (*models.QuizTiming, error) {
+	key := timerKey(quiz.QuizID, studentID)
+	if _, ok := s.timers.Get(key); !ok {
+		if quiz.TimeLimit > 0 {
+			return nil, apperr.New(apperr.CodeConflict, "timed quiz has not been started; start it before submitting")
+		}
+		return nil, nil
+	}
+
+	var timing models.QuizTiming
+	_, err := s.timers.Update(key, func(t models.QuizTimer, exists bool) (models.QuizTimer, error) {
+		if !exists {
+			return t, apperr.New(apperr.CodeConflict, "quiz has not been started")
+		}
+		if t.SubmittedAt != nil {
+			return t, apperr.New(apperr.CodeConflict, "quiz was already submitted")
+		}
+		late, over := overdue(t, now)
+		if late && t.LatePolicy == LateReject {
+			return t, apperr.New(apperr.CodeConflict, fmt.Sprintf("submitted %d seconds after the time limit", over))
+		}
+		t.SubmittedAt = &now
+
+		timing = models.QuizTiming{
+			StartedAt:      t.StartedAt,
+			SubmittedAt:    now,
+			Deadline:       t.Deadline,
+			ElapsedSeconds: int(now.Sub(t.StartedAt).Seconds()),
+			Late:           late,
+			LateSeconds:    over,
+		}
+		for _, a := range t.Answers {
+			timing.Questions = append(timing.Questions, models.QuestionTime{QuestionID: a.QuestionID, Seconds: a.Seconds})
+		}
+		return t, nil
+	})
+	if err != nil {
+		if apperr.Is(err, apperr.CodeConflict) {
+			return nil, err
+		}
+		return nil, apperr.Wrap(apperr.CodeInternal, "failed to save quiz timer", err)
+	}
+	return &timing, nil
+}
+
+// overdue reports whether now is past t's deadline plus grace period, and
+// how many whole seconds past the deadline it is.
+func overdue(t models.QuizTimer, now time.Time) (bool, int) {
+	if t.Deadline == nil || !now.After(*t.Deadline) {
+		return false, 0
+	}
+	past := now.Sub(*t.Deadline)
+	return past > time.Duration(t.GraceSeconds)*time.Second, int(past.Seconds())
+}
+
+// withRemaining sets the seconds left before t's deadline.
+func withRemaining(t models.QuizTimer, now time.Time) models.QuizTimer {
+	if t.Deadline != nil {
+		remaining := max(0, int(math.Ceil(t.Deadline.Sub(now).Seconds())))
+		t.RemainingSeconds = &remaining
+	}
+	return t
+}
@@ -0,0 +1,223 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"studyai/internal/grading"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// Weak area kinds.
+const (
+	areaTopic = "topic"
+	areaTag   = "tag"
+)
+
+// weakBelow is the share of an area's credit below which it counts as weak.
+const weakBelow = 0.8
+
+// weakAreas groups graded items by topic and by tag and returns the areas
+// where the student missed questions and earned less than weakBelow of the
+// credit, weakest first.
+func weakAreas(items []models.ItemOutcome) []models.WeakArea {
+	type tally struct {
+		area    models.WeakArea
+		credit  float64
+		ordinal int
+	}
+	byKey := map[string]*tally{}
+	add := func(kind, name string, item models.ItemOutcome) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		key := kind + "\x00" + strings.ToLower(name)
+		t, ok := byKey[key]
+		if !ok {
+			t = &tally{area: models.WeakArea{Area: name, Kind: kind, Questions: []string{}}, ordinal: len(byKey)}
+			byKey[key] = t
+		}
+		t.area.Answered++
+		t.credit += item.Credit
+		if item.Credit < 1 {
+			t.area.Missed++
+			t.area.Questions = append(t.area.Questions, item.QuestionID)
+		}
+	}
+	for _, item := range items {
+		add(areaTopic, item.Topic, item)
+		for _, tag := range item.Tags {
+			add(areaTag, tag, item)
+		}
+	}
+
+	var tallies []*tally
+	for _, t := range byKey {
+		t.area.Credit = math.Round(t.credit/float64(t.area.Answered)*100) / 100
+		if t.area.Missed > 0 && t.area.Credit < weakBelow {
+			tallies = append(tallies, t)
+		}
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		a, b := tallies[i].area, tallies[j].area
+		switch {
+		case a.Credit != b.Credit:
+			return a.Credit < b.Credit
+		case a.Missed != b.Missed:
+			return a.Missed > b.Missed
+		case a.Kind != b.Kind:
+			return a.Kind == areaTopic
+		}
+		return tallies[i].ordinal < tallies[j].ordinal
+	})
+	areas := make([]models.WeakArea, len(tallies))
+	for i, t := range tallies {
+		areas[i] = t.area
+	}
+	return areas
+}
+
+// areaNames lists the weak areas by name, dropping a tag that repeats a
+// topic's name.
+func areaNames(areas []models.WeakArea) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, a := range areas {
+		if key := strings.ToLower(a.Area); !seen[key] {
+			seen[key] = true
+			names = append(names, a.Area)
+		}
+	}
+	return names
+}
+
+// gradedItems pairs each question with the credit it earned, for finding weak
+// areas before the attempt is recorded.
+func gradedItems(questions []models.QuizQuestion, scores []itemScore, quizTopic string) []models.ItemOutcome {
+	items := make([]models.ItemOutcome, len(questions))
+	for i, q := range questions {
+		topic := q.Topic
+		if topic == "" {
+			topic = quizTopic
+		}
+		items[i] = models.ItemOutcome{QuestionID: q.ID, Topic: topic, Tags: q.Tags, Credit: scores[i].Credit}
+	}
+	return items
+}
+
+// quizFeedback phrases feedback on a graded submission. The weak areas and
+// the missed questions are decided by the grading; the LLM is only shown
+// those and asked to word the summary and review strategies. When it is
+// unavailable the feedback is built from the same facts.
+func (s *Service) quizFeedback(ctx context.Context, result *models.QuizResult, questions []models.QuizQuestion, responses []models.QuizAnswer, scores []itemScore, timeSpent int) {
+	var missed strings.Builder
+	for i, q := range questions {
+		if scores[i].Credit == 1 {
+			continue
+		}
+		fmt.Fprintf(&missed, "- %s\n  Topic: %s\n  Student answered: %s\n  Correct answer: %s\n  Credit earned: %d%%\n",
+			q.Question, q.Topic, grading.ResponseText(q, responses[i]), grading.AnswerText(q), int(math.Round(scores[i].Credit*100)))
+		if m := grading.Misconceptions(q, responses[i]); len(m) > 0 {
+			fmt.Fprintf(&missed, "  Misconception revealed: %s\n", strings.Join(m, "; "))
+		}
+	}
+	if missed.Len() == 0 {
+		missed.WriteString("(none)\n")
+	}
+	var areas strings.Builder
+	for _, a := range result.WeakAreas {
+		fmt.Fprintf(&areas, "- %s (%s): missed %d of %d, %d%% of the credit earned\n", a.Area, a.Kind, a.Missed, a.Answered, int(math.Round(a.Credit*100)))
+	}
+	if areas.Len() == 0 {
+		areas.WriteString("(none)\n")
+	}
+
+	// Without catalog resources for the weak areas, ask for suggestions; they
+	// are labeled unverified.
+	var resourcesFormat, resourcesNote string
+	suggest := len(result.WeakAreas) > 0 && len(result.Resources) == 0
+	if suggest {
+		resourcesFormat = `,
+  "resources": [{"title": "Resource Name", "description": "Brief description", "type": "video|article|book|interactive", "difficulty": "beginner|intermediate|advanced"}]`
+		resourcesNote = "- resources should be 2-3 well-known learning resources for the weak areas\n"
+	}
+
+	prompt := fmt.Sprintf(`
+A student's quiz has been graded. Write feedback based only on the grading below.
+
+Score: %d%% (%d of %d questions fully correct)
+Time Spent: %d seconds
+
+Weak areas, weakest first:
+%s
+Missed questions:
+%s
+Generate structured feedback in JSON format:
+{
+  "feedback": "Overall performance summary and encouragement (1-2 sentences)",
+  "recommended_review": ["strategy1", "strategy2"]%s
+}
+
+Notes:
+- Refer only to the weak areas and missed questions listed above; do not name other topics
+- recommended_review should be specific study strategies for those weak areas, at most 3
+%s- If nothing was missed, congratulate the student and leave recommended_review empty
+- Keep feedback constructive and motivating
+`, result.Score, result.CorrectCount, result.TotalQuestions, timeSpent, areas.String(), missed.String(), resourcesFormat, resourcesNote)
+
+	feedbackJSON, err := s.llm.CallJSON(ctx, "quiz.feedback", prompt)
+	if err == nil {
+		var feedback struct {
+			Feedback          string                    `json:"feedback"`
+			RecommendedReview []string                  `json:"recommended_review"`
+			Resources         []models.LearningMaterial `json:"resources"`
+		}
+		if err := json.Unmarshal([]byte(feedbackJSON), &feedback); err == nil && strings.TrimSpace(feedback.Feedback) != "" {
+			result.Feedback = strings.TrimSpace(feedback.Feedback)
+			if feedback.RecommendedReview != nil {
+				result.RecommendedReview = feedback.RecommendedReview
+			}
+			if suggest {
+				telemetry.FallbackActivations.Inc("quiz.resources", "no_catalog_match")
+				result.Resources = unverifiedMaterials(feedback.Resources)
+			}
+			return
+		}
+	}
+
+	telemetry.FallbackActivations.Inc("quiz.feedback", "no_feedback")
+	result.Feedback, result.RecommendedReview = gradedFeedback(*result)
+}
+
+// gradedFeedback builds feedback and review suggestions from the grading
+// alone.
+func gradedFeedback(r models.QuizResult) (string, []string) {
+	review := []string{}
+	summary := fmt.Sprintf("You scored %d%% (%d of %d questions fully correct).", r.Score, r.CorrectCount, r.TotalQuestions)
+	switch {
+	case r.CorrectCount == r.TotalQuestions:
+		return summary + " Well done; try a harder quiz next.", review
+	case len(r.WeakAreas) == 0:
+		return summary + " Review the explanations of the questions you missed.", review
+	}
+
+	var focus []string
+	for _, a := range r.WeakAreas {
+		if len(focus) == 3 {
+			break
+		}
+		focus = append(focus, fmt.Sprintf("%s (missed %d of %d)", a.Area, a.Missed, a.Answered))
+		questions := "the missed question"
+		if a.Missed > 1 {
+			questions = fmt.Sprintf("the %d missed questions", a.Missed)
+		}
+		review = append(review, fmt.Sprintf("Review %s: rework %s using the explanations, then try a short quiz on it.", a.Area, questions))
+	}
+	return summary + " Focus on " + strings.Join(focus, ", ") + ".", review
+}
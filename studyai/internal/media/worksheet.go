@@ -0,0 +1,213 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"studyai/internal/adaptive"
+	"studyai/internal/apperr"
+	"studyai/internal/grading"
+	"studyai/internal/models"
+	"studyai/internal/telemetry"
+)
+
+// maxWorksheetQuestions bounds how many worksheet questions one quiz holds.
+const maxWorksheetQuestions = 30
+
+// maxWorksheetContent bounds how much worksheet text goes into the prompt.
+const maxWorksheetContent = 6000
+
+// WorksheetQuiz turns questions taken from a worksheet into a stored quiz
+// that is submitted and graded like any other. The LLM adds options, answer
+// keys and explanations; the converted questions then go through the same
+// repair and answer-key checks as generated ones, and questions that cannot
+// be converted are asked for again before they are dropped.
+func (s *Service) WorksheetQuiz(ctx context.Context, req models.WorksheetQuizRequest) (models.StudentQuiz, error) {
+	ctx, span := telemetry.StartSpan(ctx, "media.WorksheetQuiz", slog.Int("quiz.requested", len(req.Questions)))
+	defer span.End()
+
+	var texts []string
+	for _, q := range req.Questions {
+		if q = strings.TrimSpace(q); q != "" && !slices.ContainsFunc(texts, func(t string) bool { return questionKey(t) == questionKey(q) }) {
+			texts = append(texts, q)
+		}
+	}
+	switch {
+	case len(texts) == 0:
+		return models.StudentQuiz{}, apperr.Validation(apperr.FieldError{Field: "questions", Message: "must contain at least one question"})
+	case len(texts) > maxWorksheetQuestions:
+		return models.StudentQuiz{}, apperr.Validation(apperr.FieldError{Field: "questions", Message: fmt.Sprintf("must contain at most %d questions", maxWorksheetQuestions)})
+	}
+	if err := checkQuestionTypes(req.QuestionTypes); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	types := req.QuestionTypes
+	if len(types) == 0 {
+		types = []string{grading.SingleChoice}
+	}
+	topic := strings.TrimSpace(req.Topic)
+	if topic == "" {
+		topic = "Worksheet"
+	}
+	difficulty := adaptive.NormalizeLevel(req.Difficulty)
+
+	questions, err := s.convertWorksheet(ctx, topic, req.Content, texts, types)
+	if err != nil {
+		return models.StudentQuiz{}, err
+	}
+	for i := range questions {
+		questions[i].ID = fmt.Sprintf("q_%d", i+1)
+		questions[i].Topic = topic
+		questions[i].Difficulty = difficulty
+		questions[i].Source = "worksheet"
+	}
+	seed := newSeed()
+	shuffleOptions(questions, seed)
+
+	quiz := models.QuizResponse{
+		QuizID:     newID("quiz"),
+		Questions:  questions,
+		TimeLimit:  timeLimit(req.TimedMinutes),
+		Topic:      topic,
+		Difficulty: difficulty,
+		StudentID:  req.StudentID,
+		Seed:       seed,
+	}
+	s.applyLateRules(&quiz, "", nil)
+	if err := s.saveQuiz(quiz); err != nil {
+		return models.StudentQuiz{}, err
+	}
+	if req.StudentID != "" {
+		if _, err := s.startTimer(quiz, req.StudentID, time.Now().UTC()); err != nil {
+			return models.StudentQuiz{}, err
+		}
+	}
+	return studentQuiz(forStudent(quiz, req.StudentID)), nil
+}
+
+// convertWorksheet converts each worksheet question, keeping the worksheet
+// order. Questions that are rejected or missing from a reply are requested
+// again, up to maxRegenerations times; it fails only when none converts.
+func (s *Service) convertWorksheet(ctx context.Context, topic, content string, texts []string, types []string) ([]models.QuizQuestion, error) {
+	converted := make([]*models.QuizQuestion, len(texts))
+	var rejected []rejection
+	pending := make([]int, len(texts))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for round := 0; round <= maxRegenerations && len(pending) > 0; round++ {
+		batch, reason, err := s.requestConversions(ctx, topic, content, texts, pending, types, rejected)
+		if err != nil {
+			telemetry.FallbackActivations.Inc("quiz.worksheet", reason)
+			if round == 0 {
+				return nil, apperr.Wrap(apperr.CodeLLMUnavailable, "worksheet questions cannot be converted while the LLM is unavailable", err)
+			}
+			slog.WarnContext(ctx, "worksheet question reconversion failed", "reason", reason, "err", err)
+			break
+		}
+
+		var checked []models.QuizQuestion
+		for _, q := range batch {
+			i, ok := worksheetIndex(q.ID, len(texts))
+			if !ok || !slices.Contains(pending, i) || slices.ContainsFunc(checked, func(c models.QuizQuestion) bool { return c.ID == q.ID }) {
+				rejected = append(rejected, reject(q, "surplus", "does not match a worksheet question"))
+				continue
+			}
+			if r, ok := repairGenerated(&q, types); !ok {
+				rejected = append(rejected, r)
+				continue
+			}
+			checked = append(checked, q)
+		}
+		kept, wrong := s.verifyAnswerKeys(ctx, topic, checked)
+		rejected = append(rejected, wrong...)
+		for _, q := range kept {
+			i, _ := worksheetIndex(q.ID, len(texts))
+			converted[i] = &q
+		}
+		pending = slices.DeleteFunc(pending, func(i int) bool { return converted[i] != nil })
+	}
+
+	var questions []models.QuizQuestion
+	for _, q := range converted {
+		if q != nil {
+			questions = append(questions, *q)
+		}
+	}
+	slog.InfoContext(ctx, "converted worksheet questions", "requested", len(texts), "converted", len(questions), "rejected", len(rejected))
+	if len(questions) == 0 {
+		return nil, apperr.New(apperr.CodeLLMUnavailable, "none of the worksheet questions could be turned into a gradable quiz question")
+	}
+	return questions, nil
+}
+
+// requestConversions makes one conversion call for the pending worksheet
+// questions, identified as w1, w2, ... by their worksheet position. Retries
+// list why earlier conversions were rejected.
+func (s *Service) requestConversions(ctx context.Context, topic, content string, texts []string, pending []int, types []string, rejected []rejection) ([]models.QuizQuestion, string, error) {
+	var list strings.Builder
+	for _, i := range pending {
+		fmt.Fprintf(&list, "w%d. %s\n", i+1, texts[i])
+	}
+	var source string
+	if content = strings.TrimSpace(content); content != "" {
+		if len(content) > maxWorksheetContent {
+			content = strings.ToValidUTF8(content[:maxWorksheetContent], "")
+		}
+		source = "\nWORKSHEET TEXT, for reference:\n" + content + "\n"
+	}
+	var retry strings.Builder
+	problems := slices.DeleteFunc(slices.Clone(rejected), func(r rejection) bool { return r.reason == "surplus" })
+	if len(problems) > 0 {
+		retry.WriteString("\nThese earlier conversions were rejected; avoid the same problems:\n")
+		for _, r := range problems {
+			fmt.Fprintf(&retry, "- %q: %s\n", r.question, r.detail)
+		}
+	}
+
+	prompt := fmt.Sprintf(`
+Turn each of these worksheet questions about "%s" into a quiz question that can be graded automatically.
+%s
+QUESTIONS:
+%s%s
+Requirements:
+- Give each quiz question an "id" field with the worksheet question's ID (w1, w2, ...)
+- Convert every question exactly once, keeping its meaning; rephrase only as the format needs (e.g. "Define osmosis" becomes "Which statement defines osmosis?")
+- Answers must agree with the worksheet text when it is given
+- Options should be plausible and distinct
+- Indices are 0-based positions in the options array
+- Include detailed explanations for learning
+%s
+Return ONLY valid JSON array, no additional text.
+`, topic, source, list.String(), questionFormats(types), retry.String())
+
+	reply, err := s.llm.CallJSON(ctx, "quiz.worksheet", prompt)
+	if err != nil {
+		return nil, "llm_error", err
+	}
+	var questions []models.QuizQuestion
+	if err := json.Unmarshal([]byte(reply), &questions); err != nil {
+		return nil, "parse_error", err
+	}
+	return questions, "", nil
+}
+
+// worksheetIndex reads a worksheet question ID such as "w3" as its position.
+func worksheetIndex(id string, n int) (int, bool) {
+	num, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(id)), "w")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(num)
+	if err != nil || i < 1 || i > n {
+		return 0, false
+	}
+	return i - 1, true
+}
@@ -1,18 +1,23 @@
 package models
 
+import "time"
+
 type StudyRequest struct {
-    Goal           string `json:"goal"`
-    AvailableHours int    `json:"available_hours"`
-    DurationDays   int    `json:"duration_days"`
-    Difficulty     string `json:"difficulty"` // low, medium, high
+    Goal           string `json:"goal" validate:"required,maxlen=500"`
+    AvailableHours int    `json:"available_hours" validate:"required,min=1" doc:"total hours available across the whole duration"`
+    DurationDays   int    `json:"duration_days" validate:"required,min=1"`
+    Difficulty     string `json:"difficulty" doc:"low, medium or high (easy/hard and abbreviations are accepted)"` // low, medium, high
 }
 
 type ImageAnalysisRequest struct {
-    ImageData    string `json:"image_data"`    // base64 encoded
+    ImageData    string `json:"image_data" validate:"required" doc:"base64-encoded image without a data: prefix"`    // base64 encoded
     ImageType    string `json:"image_type"`    // jpg, png, pdf, etc.
     StudentGrade int    `json:"student_grade"` // optional: student's grade level
     StudentAge   int    `json:"student_age"`   // optional: student's age
     WeakAreas    string `json:"weak_areas"`    // optional: areas student struggles with
+    CreateQuiz   bool   `json:"create_quiz,omitempty" doc:"also turn the extracted and revision questions into a playable quiz"`
+    QuizTopic    string `json:"quiz_topic,omitempty" validate:"maxlen=200" doc:"topic of the created quiz; defaults to Worksheet"`
+    StudentID    string `json:"student_id,omitempty" doc:"student the created quiz is for; starts their timer"`
 }
 
 type ImageAnalysisResponse struct {
@@ -23,14 +28,32 @@ type ImageAnalysisResponse struct {
     ImprovementTips      []string                     `json:"improvement_tips"`
     DifficultyAssessment string                       `json:"difficulty_assessment"`
     Disclaimer           string                       `json:"disclaimer"`
+    Citations            []Citation                   `json:"citations,omitempty"` // course material passages the analysis was grounded in
+    Quiz                 *StudentQuiz                 `json:"quiz,omitempty"`       // set when create_quiz was requested and succeeded
+    QuizError            string                       `json:"quiz_error,omitempty"` // why the quiz could not be created
+}
+
+// WorksheetQuizRequest turns questions taken from a worksheet into a quiz.
+type WorksheetQuizRequest struct {
+    Questions     []string `json:"questions" validate:"required,minitems=1" doc:"question text, such as the extracted_questions and revision_questions of an analysis; at most 30"`
+    Content       string   `json:"content,omitempty" doc:"the worksheet text, used to keep answers consistent with it"`
+    Topic         string   `json:"topic,omitempty" validate:"maxlen=200" doc:"defaults to Worksheet"`
+    Difficulty    string   `json:"difficulty,omitempty"`
+    QuestionTypes []string `json:"question_types,omitempty" doc:"question types to convert into: single_choice (default), multi_select, true_false, numeric, short_text, ordering, matching, free_response"`
+    TimedMinutes  int      `json:"timed_minutes" validate:"min=0" doc:"0 for untimed"`
+    StudentID     string   `json:"student_id,omitempty" doc:"links attempts to a student and starts their timer"`
 }
 
 type LearningMaterial struct {
-    Title       string `json:"title"`
-    Description string `json:"description"`
-    Type        string `json:"type"` // video, article, book, interactive
-    URL         string `json:"url"`  // optional resource link
-    Difficulty  string `json:"difficulty"` // beginner, intermediate, advanced
+    Title       string   `json:"title"`
+    Description string   `json:"description"`
+    Type        string   `json:"type"` // video, article, book, interactive
+    URL         string   `json:"url"`  // resource link; empty for unverified suggestions
+    Difficulty  string   `json:"difficulty"` // beginner, intermediate, advanced
+    ResourceID  string   `json:"resource_id,omitempty"` // the catalog entry recommended
+    Topics      []string `json:"topics,omitempty"`
+    Relevance   float64  `json:"relevance,omitempty"` // catalog entries only: how well the resource matches, from 0 to 1
+    Verified    bool     `json:"verified"`            // true for catalog entries; LLM suggestions are unverified
 }
 
 type StudyPlanRecommendation struct {
@@ -47,6 +70,21 @@ type RuleResult struct {
     Issues    []string
 }
 
+type ChatRequest struct {
+    Message string `json:"message" validate:"required,maxlen=4000"`
+}
+
+type ChatResponse struct {
+    Reply     string     `json:"reply"`
+    Citations []Citation `json:"citations,omitempty"` // course material passages the reply was grounded in, cited as [n]
+}
+
+// StatusResponse acknowledges operations that return no resource.
+type StatusResponse struct {
+    Status  string `json:"status"`
+    Message string `json:"message"`
+}
+
 type AgentResponse struct {
     Decision    string   `json:"decision"`
     Score       int      `json:"score"`
@@ -57,54 +95,529 @@ type AgentResponse struct {
 }
 
 type QuizRequest struct {
-    TopicName    string `json:"topic_name"`
+    TopicName    string `json:"topic_name" validate:"required,maxlen=200"`
     Difficulty   string `json:"difficulty"`   // easy, medium, hard
-    NumQuestions int    `json:"num_questions"`
-    TimedMinutes int    `json:"timed_minutes"` // 0 for untimed
+    NumQuestions int    `json:"num_questions" validate:"required,min=1"`
+    TimedMinutes int    `json:"timed_minutes" validate:"min=0" doc:"0 for untimed"` // 0 for untimed
+    StudentID    string `json:"student_id,omitempty" doc:"links attempts to a student; required when adaptive is true"`
+    Adaptive     bool   `json:"adaptive,omitempty" doc:"choose the difficulty from the student's history; requires student_id"`
+    Source       string `json:"source,omitempty" validate:"enum=llm|bank|mixed" doc:"where questions come from: llm (default), bank, or mixed (half from the bank, the rest from the LLM)"`
+    Tags         []string `json:"tags,omitempty" doc:"only draw bank questions carrying all of these tags"`
+    QuestionTypes []string `json:"question_types,omitempty" doc:"question types to generate: single_choice (default), multi_select, true_false, numeric, short_text, ordering, matching, free_response"`
+    Seed          int64  `json:"seed,omitempty" validate:"min=0,max=9007199254740991" doc:"seed for shuffling options and question order; random when 0. Only teachers may set it"`
+    ShuffleQuestions bool `json:"shuffle_questions,omitempty" doc:"give each student their own question order, fixed by the seed and student_id"`
+    LatePolicy    string `json:"late_policy,omitempty" validate:"enum=flag|reject" doc:"for timed quizzes: flag (grade and mark late) or reject late submissions; defaults to the server setting"`
+    GraceSeconds  *int   `json:"grace_seconds,omitempty" validate:"min=0" doc:"seconds after the time limit that still count as on time; defaults to the server setting"`
+    Standards     []string `json:"standards,omitempty" doc:"codes of catalog standards the questions should assess; every question is tagged with them"`
+    Assessment    bool     `json:"assessment,omitempty" doc:"flags the quiz as an assessment: while a student is sitting it, worked solutions are withheld from them"`
 }
 
+// QuizQuestion is one quiz item. Type selects which answer key fields apply:
+// correct_answer for single_choice and true_false, correct_answers for
+// multi_select, numeric_answer and tolerance for numeric, accepted_answers for
+// short_text, correct_order for ordering, match_targets with correct_matches
+// for matching, and rubric with model_answer for free_response.
 type QuizQuestion struct {
     ID       string   `json:"id"`
+    Type     string   `json:"type,omitempty"` // empty means single_choice
     Question string   `json:"question"`
     Options  []string `json:"options"`
     CorrectAnswer int `json:"correct_answer"`
+    CorrectAnswers  []int    `json:"correct_answers,omitempty"`  // multi_select: every correct option
+    Misconceptions  []string `json:"misconceptions,omitempty"`   // choice types: the misconception each wrong option reveals, "" for none
+    NumericAnswer   *float64 `json:"numeric_answer,omitempty"`   // numeric
+    Tolerance       float64  `json:"tolerance,omitempty"`        // numeric: accepted absolute error
+    AcceptedAnswers []string `json:"accepted_answers,omitempty"` // short_text: accepted spellings
+    CorrectOrder    []int    `json:"correct_order,omitempty"`    // ordering: option indices in the correct sequence
+    MatchTargets    []string `json:"match_targets,omitempty"`    // matching: the right-hand column
+    CorrectMatches  []int    `json:"correct_matches,omitempty"`  // matching: target index for each option
+    Rubric          []RubricCriterion `json:"rubric,omitempty"`      // free_response
+    ModelAnswer     string   `json:"model_answer,omitempty"`     // free_response: an exemplary answer
     Explanation string `json:"explanation"`
+    Topic      string `json:"topic,omitempty"`
+    Difficulty string `json:"difficulty,omitempty"` // easy, medium, hard
+    Tags       []string `json:"tags,omitempty"`
+    Standards  []string `json:"standards,omitempty"` // codes of the curriculum standards the question assesses
+    Source     string `json:"source,omitempty"` // llm, bank, sample or worksheet
+    Citations  []Citation `json:"citations,omitempty"` // course material passages the question was based on
 }
 
+// QuizResponse is a quiz with its answer keys, as stored on the server and
+// graded against. Students are served a StudentQuiz.
 type QuizResponse struct {
     QuizID    string          `json:"quiz_id"`
     Questions []QuizQuestion  `json:"questions"`
     TimeLimit int             `json:"time_limit"` // in seconds
     IsDevFallback bool        `json:"is_dev_fallback"` // true if using sample questions
+    Topic      string         `json:"topic"`
+    Difficulty string         `json:"difficulty"` // easy, medium, hard or mixed
+    StudentID  string         `json:"student_id,omitempty"`
+    Adaptive   *AdaptivePlan  `json:"adaptive,omitempty"` // set for adaptive quizzes
+    Seed       int64          `json:"seed"`                        // fixes the option and question order
+    ShuffleQuestions bool     `json:"shuffle_questions,omitempty"` // each student sees their own question order
+    LatePolicy   string         `json:"late_policy,omitempty"`   // timed quizzes: flag or reject
+    GraceSeconds int            `json:"grace_seconds,omitempty"` // timed quizzes: seconds allowed past time_limit
+    RetakeOf     string         `json:"retake_of,omitempty"`     // the quiz this one retakes
+    Assessment   bool           `json:"assessment,omitempty"`    // worked solutions are withheld while a student sits it
+}
+
+// StudentQuiz is a quiz as served to students: its questions carry no answer
+// keys, explanations or misconceptions, and the shuffle seed, from which the
+// keys of bank and sample questions could be recovered, is left out too. The
+// keys stay with the stored quiz.
+type StudentQuiz struct {
+    QuizID    string            `json:"quiz_id"`
+    Questions []StudentQuestion `json:"questions"`
+    TimeLimit int               `json:"time_limit"` // in seconds
+    IsDevFallback bool          `json:"is_dev_fallback"` // true if using sample questions
+    Topic      string           `json:"topic"`
+    Difficulty string           `json:"difficulty"` // easy, medium, hard or mixed
+    StudentID  string           `json:"student_id,omitempty"`
+    Adaptive   *AdaptivePlan    `json:"adaptive,omitempty"` // set for adaptive quizzes
+    ShuffleQuestions bool       `json:"shuffle_questions,omitempty"` // each student sees their own question order
+    LatePolicy   string         `json:"late_policy,omitempty"`   // timed quizzes: flag or reject
+    GraceSeconds int            `json:"grace_seconds,omitempty"` // timed quizzes: seconds allowed past time_limit
+    RetakeOf     string         `json:"retake_of,omitempty"`     // the quiz this one retakes
+    Assessment   bool           `json:"assessment,omitempty"`    // worked solutions are withheld while a student sits it
+}
+
+// StudentQuestion is a quiz question as served to students, without its
+// answer key.
+type StudentQuestion struct {
+    ID       string   `json:"id"`
+    Type     string   `json:"type,omitempty"` // empty means single_choice
+    Question string   `json:"question"`
+    Options  []string `json:"options"`
+    MatchTargets []string `json:"match_targets,omitempty"` // matching: the right-hand column
+    Topic      string `json:"topic,omitempty"`
+    Difficulty string `json:"difficulty,omitempty"` // easy, medium, hard
+    Tags       []string `json:"tags,omitempty"`
+    Standards  []string `json:"standards,omitempty"` // codes of the curriculum standards the question assesses
+    Source     string `json:"source,omitempty"` // llm, bank, sample or worksheet
+    Citations  []Citation `json:"citations,omitempty"` // course material passages the question was based on
+}
+
+// SolutionRequest asks for step-by-step help with one homework question.
+type SolutionRequest struct {
+    Question  string `json:"question" validate:"required,maxlen=2000" doc:"the question to solve, such as one of an analysis's extracted_questions"`
+    Content   string `json:"content,omitempty" validate:"maxlen=20000" doc:"the worksheet text around the question"`
+    StudentID string `json:"student_id,omitempty" doc:"the student asking; help is limited while they sit a flagged assessment"`
+    Grade     int    `json:"grade,omitempty" validate:"min=0,max=12" doc:"pitches the explanation at this grade"`
+}
+
+// WorkedSolution is a hint ladder for one question. Its levels are revealed
+// one at a time: a nudge, then a hint, then the full solution.
+type WorkedSolution struct {
+    SolutionID string    `json:"solution_id"`
+    Question   string    `json:"question"`
+    StudentID  string    `json:"student_id,omitempty"`
+    Revealed   string    `json:"revealed"`             // the most revealing level shown: nudge, hint or solution
+    Nudge      string    `json:"nudge"`                // points at where to start, without solving anything
+    Hint       string    `json:"hint,omitempty"`       // the method or first step
+    Steps      []string  `json:"steps,omitempty"`      // the full solution, step by step
+    Answer     string    `json:"answer,omitempty"`     // the final answer
+    Next       string    `json:"next,omitempty"`       // the level the next reveal shows; empty once everything is shown
+    Withheld   string    `json:"withheld,omitempty"`   // why further levels are unavailable for now
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+// AdaptivePlan explains how an adaptive quiz was tailored to a student.
+type AdaptivePlan struct {
+    Ability       float64           `json:"ability"`        // Rasch ability estimate (logits, 0 = average)
+    StandardError float64           `json:"standard_error"` // uncertainty of the estimate
+    Responses     int               `json:"responses"`      // graded answers the estimate is based on
+    TargetSuccess float64           `json:"target_success"` // intended probability of a correct answer
+    Difficulty    string            `json:"difficulty"`     // overall difficulty, or "mixed"
+    Topics        []TopicAllocation `json:"topics"`
+    Reason        string            `json:"reason"`
+}
+
+// TopicAllocation is one topic's share of an adaptive quiz.
+type TopicAllocation struct {
+    Topic              string  `json:"topic"`
+    Questions          int     `json:"questions"`
+    Ability            float64 `json:"ability"`
+    Difficulty         string  `json:"difficulty"`
+    ExpectedSuccess    float64 `json:"expected_success"`
+}
+
+// NextQuizRequest asks for the quiz best suited to a student's current level.
+type NextQuizRequest struct {
+    StudentID    string `json:"student_id,omitempty" doc:"taken from the path on /v1 routes"`
+    TopicName    string `json:"topic_name,omitempty" validate:"maxlen=200" doc:"restrict to one topic; empty lets the student's history choose the topic mix"`
+    NumQuestions int    `json:"num_questions" validate:"required,min=1"`
+    TimedMinutes int    `json:"timed_minutes" validate:"min=0"`
+    Source       string `json:"source,omitempty" validate:"enum=llm|bank|mixed" doc:"where questions come from: llm (default), bank or mixed"`
+    QuestionTypes []string `json:"question_types,omitempty" doc:"question types to generate; defaults to single_choice"`
 }
 
 type QuizSubmissionRequest struct {
     QuizID      string `json:"quiz_id"`
-    Answers     []int  `json:"answers"` // indices of selected answers
-    TimeSpent   int    `json:"time_spent"` // in seconds
+    Answers     []int  `json:"answers,omitempty" doc:"index of the selected option for each question; use responses for other question types"` // indices of selected answers
+    Responses   []QuizAnswer `json:"responses,omitempty" doc:"one response per question, for any question type; omit answers and responses to submit the answers saved one at a time"`
+    TimeSpent   int    `json:"time_spent" doc:"seconds taken; replaced by the server's measurement for started quizzes"` // in seconds
     Questions   []QuizQuestion `json:"questions"`
+    StudentID   string `json:"student_id,omitempty" doc:"records the attempt in the student's history"`
+}
+
+// QuizAttempt is a graded submission kept as part of a student's history.
+type QuizAttempt struct {
+    ID          string        `json:"id"`
+    StudentID   string        `json:"student_id"`
+    QuizID      string        `json:"quiz_id"`
+    Topic       string        `json:"topic"`
+    Difficulty  string        `json:"difficulty"`
+    Items       []ItemOutcome `json:"items"`
+    Correct     int           `json:"correct"`
+    Total       int           `json:"total"`
+    Score       int           `json:"score"`
+    SubmittedAt time.Time     `json:"submitted_at"`
+    TimeSpent   int           `json:"time_spent,omitempty"` // seconds, measured by the server for started quizzes
+    Late        bool          `json:"late,omitempty"`
+    Result      *QuizResult   `json:"result,omitempty"` // the result returned on submission, with its reviews
+}
+
+// AttemptSummary is one row of a student's attempt history.
+type AttemptSummary struct {
+    ID          string    `json:"id"`
+    QuizID      string    `json:"quiz_id"`
+    Topic       string    `json:"topic"`
+    Difficulty  string    `json:"difficulty"`
+    Score       int       `json:"score"`
+    Correct     int       `json:"correct"`
+    Total       int       `json:"total"`
+    SubmittedAt time.Time `json:"submitted_at"`
+    TimeSpent   int       `json:"time_spent,omitempty"`
+    Late        bool      `json:"late,omitempty"`
+}
+
+// AttemptList is a page of a student's attempts, newest first.
+type AttemptList struct {
+    Attempts []AttemptSummary `json:"attempts"`
+    Total    int              `json:"total"` // attempts matching the filters, before the limit
+}
+
+// RetakeRequest creates a fresh copy of a previous quiz.
+type RetakeRequest struct {
+    StudentID string `json:"student_id,omitempty" doc:"defaults to the quiz's student"`
+}
+
+// ItemOutcome records whether one question was answered correctly.
+type ItemOutcome struct {
+    QuestionID string `json:"question_id"`
+    Topic      string `json:"topic"`
+    Difficulty string `json:"difficulty"`
+    Selected   int    `json:"selected"` // chosen option, or -1 when the question is not single choice
+    Correct    bool   `json:"correct"`
+    Credit     float64 `json:"credit"` // share of the question earned, from 0 to 1
+    Grade      *RubricGrade `json:"grade,omitempty"` // free_response only
+    Seconds    float64 `json:"seconds,omitempty"` // time spent, when answers were saved one at a time
+    Response   *QuizAnswer `json:"response,omitempty"` // the submitted answer
+    Standards  []string `json:"standards,omitempty"` // the question's standard codes
+    Tags       []string `json:"tags,omitempty"`      // the question's tags
+    Misconceptions []string `json:"misconceptions,omitempty"` // labels of the misconceptions the answer revealed
+}
+
+// RubricCriterion is one scored aspect of a free-response answer.
+type RubricCriterion struct {
+    ID          string   `json:"id,omitempty" doc:"assigned as c1, c2, ... when empty"`
+    Description string   `json:"description" validate:"required,maxlen=500"`
+    Points      float64  `json:"points" validate:"required,min=0"`
+    Keywords    []string `json:"keywords,omitempty" doc:"terms a good answer mentions; used when the LLM grader is unavailable"`
+}
+
+// RubricGrade is the grade of one free-response answer, criterion by
+// criterion.
+type RubricGrade struct {
+    QuestionID   string           `json:"question_id"`
+    Response     string           `json:"response"`
+    Criteria     []CriterionScore `json:"criteria"`
+    Points       float64          `json:"points"`
+    MaxPoints    float64          `json:"max_points"`
+    Feedback     string           `json:"feedback,omitempty"`
+    Method       string           `json:"method"`       // llm, fallback or teacher
+    NeedsReview  bool             `json:"needs_review"` // true when graded by the fallback
+    Comment      string           `json:"comment,omitempty"` // teacher's note on an override
+    OverriddenAt *time.Time       `json:"overridden_at,omitempty"`
+}
+
+// CriterionScore is the points awarded for one rubric criterion.
+type CriterionScore struct {
+    CriterionID   string  `json:"criterion_id"`
+    Points        float64 `json:"points"`
+    MaxPoints     float64 `json:"max_points"`
+    Justification string  `json:"justification"`
+}
+
+// RubricRequest sets the rubric of a free-response question.
+type RubricRequest struct {
+    Rubric      []RubricCriterion `json:"rubric" validate:"required,minitems=1"`
+    ModelAnswer string            `json:"model_answer,omitempty" validate:"maxlen=5000"`
+}
+
+// GradeOverrideRequest replaces the scores of some rubric criteria.
+// Criteria that are not listed keep their current scores.
+type GradeOverrideRequest struct {
+    Criteria []CriterionOverride `json:"criteria" validate:"required,minitems=1"`
+    Comment  string              `json:"comment,omitempty" validate:"maxlen=2000"`
+}
+
+// CriterionOverride is a teacher's score for one rubric criterion.
+type CriterionOverride struct {
+    CriterionID   string  `json:"criterion_id" validate:"required"`
+    Points        float64 `json:"points" validate:"min=0"`
+    Justification string  `json:"justification,omitempty" validate:"maxlen=1000"`
+}
+
+// QuizAnswer is a response to one question. Choices holds the selected option
+// for single_choice and true_false, every selected option for multi_select,
+// option indices in the chosen sequence for ordering, and the chosen
+// match_targets index for each option for matching. Text holds short_text and
+// free_response answers.
+type QuizAnswer struct {
+    Choices []int    `json:"choices,omitempty"`
+    Number  *float64 `json:"number,omitempty"` // numeric
+    Text    string   `json:"text,omitempty" validate:"maxlen=20000"` // short_text and free_response
 }
 
 type QuizResult struct {
     Score              int      `json:"score"`
     Percentage         float32  `json:"percentage"`
     CorrectCount       int      `json:"correct_count"`
+    Points             float64  `json:"points"` // sum of per-question credit, including partial credit
     TotalQuestions     int      `json:"total_questions"`
     Feedback           string   `json:"feedback"`
-    WeakTopics         []string `json:"weak_topics"`
+    WeakTopics         []string `json:"weak_topics"` // names of the weak areas, weakest first
+    WeakAreas          []WeakArea `json:"weak_areas,omitempty"` // topics and tags where credit was lost; only for graded answers
     RecommendedReview  []string `json:"recommended_review"`
     Reviews            []QuestionReview `json:"reviews"`
+    Resources          []LearningMaterial `json:"resources,omitempty"` // resources for the weak areas, from the catalog when it has any
+    AttemptID          string   `json:"attempt_id,omitempty"` // set when the attempt was recorded for a student
+    Grades             []RubricGrade `json:"grades,omitempty"` // rubric grades of free-response answers
+    Timing             *QuizTiming `json:"timing,omitempty"` // set when the quiz was started on the server
+}
+
+// WeakArea is a topic or question tag where a graded submission lost credit.
+type WeakArea struct {
+    Area      string   `json:"area"`
+    Kind      string   `json:"kind"`      // topic or tag
+    Answered  int      `json:"answered"`  // questions in the area
+    Missed    int      `json:"missed"`    // questions without full credit
+    Credit    float64  `json:"credit"`    // share of the area's credit earned, from 0 to 1
+    Questions []string `json:"questions"` // IDs of the missed questions
+}
+
+// QuizTiming is the server's record of how long a submission took.
+type QuizTiming struct {
+    StartedAt      time.Time      `json:"started_at"`
+    SubmittedAt    time.Time      `json:"submitted_at"`
+    Deadline       *time.Time     `json:"deadline,omitempty"` // start plus time_limit; nil when untimed
+    ElapsedSeconds int            `json:"elapsed_seconds"`
+    Late           bool           `json:"late"`                   // submitted after the deadline and grace period
+    LateSeconds    int            `json:"late_seconds,omitempty"` // seconds past the deadline
+    Questions      []QuestionTime `json:"questions,omitempty"`    // per question, when answers were saved one at a time
+}
+
+// QuestionTime is the time spent on one question.
+type QuestionTime struct {
+    QuestionID string  `json:"question_id"`
+    Seconds    float64 `json:"seconds"`
+}
+
+// QuizTimer tracks one student's sitting of a quiz: when it started, when it
+// is due, and the answers saved so far.
+type QuizTimer struct {
+    QuizID           string        `json:"quiz_id"`
+    StudentID        string        `json:"student_id,omitempty"`
+    StartedAt        time.Time     `json:"started_at"`
+    Deadline         *time.Time    `json:"deadline,omitempty"` // nil for untimed quizzes
+    GraceSeconds     int           `json:"grace_seconds"`
+    LatePolicy       string        `json:"late_policy"`                 // flag or reject
+    RemainingSeconds *int          `json:"remaining_seconds,omitempty"` // until the deadline, at the time of the response
+    Answers          []TimedAnswer `json:"answers"`
+    SubmittedAt      *time.Time    `json:"submitted_at,omitempty"`
+}
+
+// TimedAnswer is a response saved before the quiz is submitted.
+type TimedAnswer struct {
+    QuestionID string     `json:"question_id"`
+    Response   QuizAnswer `json:"response"`
+    Seconds    float64    `json:"seconds"` // time spent on the question, summed over every save
+    AnsweredAt time.Time  `json:"answered_at"`
+    Late       bool       `json:"late,omitempty"`
+}
+
+// QuizStartRequest starts a student's timer on a quiz.
+type QuizStartRequest struct {
+    StudentID string `json:"student_id,omitempty" doc:"defaults to the quiz's student"`
+}
+
+// QuizItemAnswerRequest saves the answer to one question of a started quiz.
+type QuizItemAnswerRequest struct {
+    StudentID string     `json:"student_id,omitempty" doc:"defaults to the quiz's student"`
+    Response  QuizAnswer `json:"response"`
 }
 
 type QuestionReview struct {
     QuestionID         string   `json:"question_id"`
     Question           string   `json:"question"`
     CorrectAnswer      int      `json:"correct_answer"`
-    CorrectOption      string   `json:"correct_option"`
+    CorrectOption      string   `json:"correct_option"` // the correct answer as text, for any question type
+    Credit             float64  `json:"credit"`
+    Grade              *RubricGrade `json:"grade,omitempty"` // free_response only
     Explanation        string   `json:"explanation"`
+    Misconceptions     []string `json:"misconceptions,omitempty"` // misconceptions revealed by the wrong options chosen
     SuggestedNextSteps []string `json:"suggested_next_steps"`
 }
 
+// BankQuestion is a curated question stored in the question bank.
+type BankQuestion struct {
+    ID            string    `json:"id,omitempty"`
+    Question      string    `json:"question" validate:"required,maxlen=2000"`
+    Options       []string  `json:"options" validate:"required,minitems=2"`
+    CorrectAnswer int       `json:"correct_answer" validate:"required,min=0" doc:"0-indexed position in options"`
+    Explanation   string    `json:"explanation,omitempty"`
+    Topic         string    `json:"topic" validate:"required,maxlen=200"`
+    Tags          []string  `json:"tags,omitempty"`
+    Standards     []string  `json:"standards,omitempty" doc:"codes of catalog standards the question assesses"`
+    Misconceptions []string `json:"misconceptions,omitempty" doc:"one label per option naming the misconception choosing it reveals; empty for the correct option"`
+    Difficulty    string    `json:"difficulty,omitempty" validate:"enum=easy|medium|hard" doc:"defaults to medium"`
+    Source        string    `json:"source,omitempty" doc:"where the question came from, e.g. a textbook or author"`
+    CreatedAt     time.Time `json:"created_at,omitempty"`
+    UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
+
+// BankQuestionList is a filtered listing of the question bank.
+type BankQuestionList struct {
+    Total     int            `json:"total"`
+    Questions []BankQuestion `json:"questions"`
+}
+
+// BankImportResult summarizes a question bank import.
+type BankImportResult struct {
+    Format   string            `json:"format"`
+    Created  int               `json:"created"`
+    Updated  int               `json:"updated"`
+    Rejected []BankImportError `json:"rejected"`
+}
+
+// BankImportError explains why one imported item was skipped.
+type BankImportError struct {
+    Item    int    `json:"item"` // 1-based position in the imported file
+    Message string `json:"message"`
+}
+
+// TestSessionRequest starts a computerized adaptive test on one topic.
+type TestSessionRequest struct {
+    StudentID    string `json:"student_id,omitempty" doc:"seeds the starting ability and records the result"`
+    TopicName    string `json:"topic_name" validate:"required,maxlen=200"`
+    MaxQuestions int    `json:"max_questions,omitempty" validate:"min=0" doc:"0 uses the server limit"`
+}
+
+// TestSession is the client-visible state of an adaptive test session.
+type TestSession struct {
+    SessionID     string           `json:"session_id"`
+    StudentID     string           `json:"student_id,omitempty"`
+    Topic         string           `json:"topic"`
+    Status        string           `json:"status"` // active or completed
+    Ability       float64          `json:"ability"`
+    StandardError float64          `json:"standard_error"`
+    TargetSE      float64          `json:"target_standard_error"`
+    Answered      int              `json:"answered"`
+    Correct       int              `json:"correct"`
+    MaxQuestions  int              `json:"max_questions"`
+    Current       *SessionQuestion `json:"current_question,omitempty"` // nil once completed
+    History       []SessionItem    `json:"history"`
+    StopReason    string           `json:"stop_reason,omitempty"` // precision_reached, max_questions or question_pool_exhausted
+    Score         int              `json:"score"`
+    AttemptID     string           `json:"attempt_id,omitempty"`
+    IsDevFallback bool             `json:"is_dev_fallback"`
+    StartedAt     time.Time        `json:"started_at"`
+    CompletedAt   *time.Time       `json:"completed_at,omitempty"`
+}
+
+// SessionQuestion is a question as served during a session, without its answer.
+type SessionQuestion struct {
+    ID         string   `json:"id"`
+    Number     int      `json:"number"`
+    Question   string   `json:"question"`
+    Options    []string `json:"options"`
+    Difficulty string   `json:"difficulty"`
+}
+
+// SessionItem is one answered question in a session.
+type SessionItem struct {
+    QuestionID    string  `json:"question_id"`
+    Question      string  `json:"question"`
+    Difficulty    string  `json:"difficulty"`
+    Selected      int     `json:"selected"`
+    CorrectAnswer int     `json:"correct_answer"`
+    Correct       bool    `json:"correct"`
+    AbilityAfter  float64 `json:"ability_after"`
+}
+
+// SessionAnswerRequest answers the current question of a session.
+type SessionAnswerRequest struct {
+    QuestionID string `json:"question_id" validate:"required"`
+    Answer     int    `json:"answer" validate:"required,min=0" doc:"0-indexed option"`
+}
+
+// SessionAnswerResult grades one answer and returns the updated session.
+type SessionAnswerResult struct {
+    Correct       bool        `json:"correct"`
+    CorrectAnswer int         `json:"correct_answer"`
+    CorrectOption string      `json:"correct_option"`
+    Explanation   string      `json:"explanation"`
+    Session       TestSession `json:"session"`
+}
+
+// Flashcard is a missed quiz question scheduled for spaced-repetition review.
+type Flashcard struct {
+    ID                 string     `json:"id"`
+    StudentID          string     `json:"student_id"`
+    QuizID             string     `json:"quiz_id"`
+    QuestionID         string     `json:"question_id"`
+    Topic              string     `json:"topic"`
+    Front              string     `json:"front"` // the question
+    Back               string     `json:"back"`  // the correct answer
+    Explanation        string     `json:"explanation"`
+    SuggestedNextSteps []string   `json:"suggested_next_steps,omitempty"`
+    EaseFactor         float64    `json:"ease_factor"`
+    IntervalDays       int        `json:"interval_days"`
+    Repetitions        int        `json:"repetitions"`
+    Lapses             int        `json:"lapses"`
+    DueAt              time.Time  `json:"due_at"`
+    LastReviewedAt     *time.Time `json:"last_reviewed_at,omitempty"`
+    CreatedAt          time.Time  `json:"created_at"`
+}
+
+// DueFlashcards lists the cards a student should review today.
+type DueFlashcards struct {
+    StudentID string      `json:"student_id"`
+    AsOf      time.Time   `json:"as_of"`
+    Total     int         `json:"total"` // due cards, including any beyond the limit
+    Cards     []Flashcard `json:"cards"`
+}
+
+// FlashcardReviewRequest submits how well a card was recalled.
+type FlashcardReviewRequest struct {
+    Grade int `json:"grade" validate:"required,min=0,max=5" doc:"SM-2 recall grade: 0-2 forgotten, 3 hard, 4 good, 5 perfect"`
+}
+
+// ReviewForecast is a student's upcoming review load.
+type ReviewForecast struct {
+    StudentID  string        `json:"student_id"`
+    TotalCards int           `json:"total_cards"`
+    Overdue    int           `json:"overdue"` // due before today
+    Days       []ForecastDay `json:"days"`    // starting today
+}
+
+// ForecastDay is the number of cards falling due on one UTC day.
+type ForecastDay struct {
+    Date string `json:"date"` // YYYY-MM-DD
+    Due  int    `json:"due"`
+}
+
 type ProgressProfile struct {
     StudentID      string   `json:"student_id"`
     Age            int      `json:"age"`
@@ -115,4 +628,232 @@ type ProgressProfile struct {
     AverageScore   float32  `json:"average_score"`
     StudyHours     float32  `json:"study_hours"`
     LastUpdated    string   `json:"last_updated"`
+    Mastery        []TopicMastery `json:"mastery,omitempty" doc:"computed from graded quiz answers; ignored on update"`
+    Misconceptions []MisconceptionTrend `json:"misconceptions,omitempty" doc:"computed from graded quiz answers; ignored on update"`
+}
+
+// MisconceptionTrend is a misconception a student's wrong answers have
+// revealed, tallied across their attempts.
+type MisconceptionTrend struct {
+    Label     string    `json:"label"`
+    Count     int       `json:"count"`     // answers that revealed it
+    Attempts  int       `json:"attempts"`  // attempts in which it appeared
+    Recurring bool      `json:"recurring"` // appeared in more than one attempt
+    Topics    []string  `json:"topics"`
+    LastSeen  time.Time `json:"last_seen"`
+}
+
+// TopicMastery is how well a student knows one topic, from graded quiz answers.
+type TopicMastery struct {
+    Topic     string    `json:"topic"`    // curriculum topic ID, or the quiz topic when it is not in the curriculum
+    Mastery   float64   `json:"mastery"`  // 0 to 1, weighted towards recent answers
+    Level     string    `json:"level"`    // not_started, struggling, developing or mastered
+    Answered  int       `json:"answered"` // graded answers on the topic
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CurriculumTopic is one node of the curriculum graph.
+type CurriculumTopic struct {
+    ID            string   `json:"id,omitempty" doc:"derived from the name when empty"`
+    Name          string   `json:"name" validate:"required,maxlen=200"`
+    Subject       string   `json:"subject,omitempty" validate:"maxlen=100"`
+    Aliases       []string `json:"aliases,omitempty" doc:"other names for the topic, such as quiz topics, whose answers count towards it"`
+    Prerequisites []string `json:"prerequisites,omitempty" doc:"IDs of topics to master first"`
+    Standards     []string `json:"standards,omitempty" doc:"codes of catalog standards the topic covers"`
+}
+
+// Curriculum is the graph of topics and their prerequisites.
+type Curriculum struct {
+    Topics    []CurriculumTopic `json:"topics" validate:"required,minitems=1" doc:"listed in prerequisite order in responses"`
+    UpdatedAt *time.Time        `json:"updated_at,omitempty"` // nil for the built-in curriculum
+}
+
+// Standard is one entry of the standards catalog, such as a Common Core
+// standard or a national curriculum objective.
+type Standard struct {
+    Code        string `json:"code" validate:"required,maxlen=100" doc:"code, unique within the framework, e.g. CCSS.MATH.CONTENT.HSA.SSE.A.2"`
+    Framework   string `json:"framework" validate:"required,maxlen=100" doc:"the set of standards, e.g. Common Core"`
+    Subject     string `json:"subject,omitempty" validate:"maxlen=100"`
+    Grade       string `json:"grade,omitempty" validate:"maxlen=50" doc:"grade or level, e.g. 8 or HS"`
+    Description string `json:"description" validate:"required,maxlen=2000"`
+}
+
+// StandardList is a filtered listing of the standards catalog.
+type StandardList struct {
+    Total     int        `json:"total"`
+    Standards []Standard `json:"standards"`
+}
+
+// StandardImportResult summarizes a standards catalog import.
+type StandardImportResult struct {
+    Format   string            `json:"format"`
+    Created  int               `json:"created"`
+    Updated  int               `json:"updated"`
+    Rejected []BankImportError `json:"rejected"`
+}
+
+// StandardTagRequest replaces the standard codes of a quiz question.
+type StandardTagRequest struct {
+    Standards []string `json:"standards" doc:"catalog standard codes; empty removes them all"`
+}
+
+// StandardsReport is a student's mastery of each standard they have
+// answered questions on.
+type StandardsReport struct {
+    StudentID string            `json:"student_id"`
+    Standards []StandardMastery `json:"standards"`
+}
+
+// StandardMastery is a student's mastery of one standard, from answers to
+// questions tagged with it or on topics that cover it.
+type StandardMastery struct {
+    Code        string    `json:"code"`
+    Framework   string    `json:"framework"`
+    Description string    `json:"description"`
+    Mastery     float64   `json:"mastery"` // 0 to 1, weighted towards recent answers
+    Level       string    `json:"level"`   // struggling, developing or mastered
+    Answered    int       `json:"answered"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// KnowledgeMap is a student's mastery laid over the curriculum graph.
+type KnowledgeMap struct {
+    StudentID string               `json:"student_id"`
+    Topics    []TopicNode          `json:"topics"`             // in prerequisite order
+    Gaps      []KnowledgeGap       `json:"gaps"`               // struggling topics and the prerequisites behind them
+    Next      *TopicRecommendation `json:"next,omitempty"`     // nil once every topic is mastered
+    Unmapped  []TopicMastery       `json:"unmapped,omitempty"` // quiz topics that are not in the curriculum
+}
+
+// TopicNode is one curriculum topic with the student's mastery of it.
+type TopicNode struct {
+    ID            string   `json:"id"`
+    Name          string   `json:"name"`
+    Subject       string   `json:"subject,omitempty"`
+    Prerequisites []string `json:"prerequisites"`
+    Mastery       float64  `json:"mastery"`
+    Level         string   `json:"level"`
+    Answered      int      `json:"answered"`
+    Ready         bool     `json:"ready"` // every prerequisite is mastered
+}
+
+// KnowledgeGap explains why a student is struggling with a topic.
+type KnowledgeGap struct {
+    Topic       string            `json:"topic"`
+    Name        string            `json:"name"`
+    Mastery     float64           `json:"mastery"`
+    Causes      []PrerequisiteGap `json:"causes"` // unmastered prerequisites, most fundamental first
+    Explanation string            `json:"explanation"`
+}
+
+// PrerequisiteGap is an unmastered prerequisite behind a knowledge gap.
+type PrerequisiteGap struct {
+    Topic   string  `json:"topic"`
+    Name    string  `json:"name"`
+    Mastery float64 `json:"mastery"`
+    Level   string  `json:"level"`
+}
+
+// TopicRecommendation is the topic a student should study next, and why.
+type TopicRecommendation struct {
+    Topic   string  `json:"topic"`
+    Name    string  `json:"name"`
+    Mastery float64 `json:"mastery"`
+    Level   string  `json:"level"`
+    Reason  string  `json:"reason"`
+}
+
+// Resource is a curated learning resource in the resource catalog.
+type Resource struct {
+    ID          string    `json:"id,omitempty"`
+    Title       string    `json:"title" validate:"required,maxlen=300"`
+    URL         string    `json:"url" validate:"required,maxlen=2000" doc:"an http or https link"`
+    Type        string    `json:"type" validate:"required,enum=video|article|book|interactive"`
+    Difficulty  string    `json:"difficulty,omitempty" validate:"enum=beginner|intermediate|advanced" doc:"defaults to intermediate"`
+    Topics      []string  `json:"topics" validate:"required,minitems=1" doc:"topics the resource covers, matched against quiz topics and curriculum topic names"`
+    Description string    `json:"description,omitempty" validate:"maxlen=2000"`
+    CreatedAt   time.Time `json:"created_at,omitempty"`
+    UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// ResourceList is a filtered listing of the resource catalog.
+type ResourceList struct {
+    Total     int        `json:"total"`
+    Resources []Resource `json:"resources"`
+}
+
+// CourseMaterial is a document provided by a teacher, such as lecture notes
+// or a textbook chapter. It is split into passages that ground chat replies,
+// generated quizzes and analyses.
+type CourseMaterial struct {
+    ID        string    `json:"id,omitempty"`
+    Title     string    `json:"title" validate:"required,maxlen=300"`
+    Topic     string    `json:"topic,omitempty" validate:"maxlen=200" doc:"what the material covers; matched along with its text"`
+    Text      string    `json:"text,omitempty" validate:"required,maxlen=200000" doc:"the material as plain text; paragraphs separated by blank lines are kept together"`
+    Passages  int       `json:"passages"` // how many passages the text was split into
+    Embedded  bool      `json:"embedded"` // whether the passages have embeddings; otherwise they are ranked with BM25
+    CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// CourseMaterialList lists course materials without their text.
+type CourseMaterialList struct {
+    Total     int              `json:"total"`
+    Materials []CourseMaterial `json:"materials"`
+}
+
+// Citation is a course material passage that grounded generated content.
+// Ref is the number the content cites it by, as in [1].
+type Citation struct {
+    Ref        int     `json:"ref"`
+    MaterialID string  `json:"material_id"`
+    Title      string  `json:"title"`
+    Passage    int     `json:"passage"` // position of the passage in the material, from 1
+    Text       string  `json:"text"`
+    Score      float64 `json:"score"`   // cosine similarity with embeddings, BM25 score otherwise
+}
+
+// PassageSearch is the result of searching the course materials.
+type PassageSearch struct {
+    Query    string     `json:"query"`
+    Method   string     `json:"method"` // embedding or bm25
+    Passages []Citation `json:"passages"`
+}
+
+// TutorRequest is a student's message to the tutor agent.
+type TutorRequest struct {
+    StudentID string `json:"student_id" validate:"required,maxlen=200" doc:"the student asking; the tutor's tools only see this student's data"`
+    Message   string `json:"message" validate:"required,maxlen=4000"`
+}
+
+// TutorRun is one tutor reply together with its audit log: every tool the
+// model called on the way to it.
+type TutorRun struct {
+    ID         string          `json:"id"`
+    StudentID  string          `json:"student_id"`
+    Message    string          `json:"message"`
+    Reply      string          `json:"reply"`
+    Outcome    string          `json:"outcome"` // answered, step_limit or error
+    Error      string          `json:"error,omitempty"`
+    Steps      int             `json:"steps"` // model turns taken
+    ToolCalls  []ToolCallAudit `json:"tool_calls"`
+    StartedAt  time.Time       `json:"started_at"`
+    FinishedAt time.Time       `json:"finished_at"`
+}
+
+// ToolCallAudit records one tool call the tutor model made.
+type ToolCallAudit struct {
+    Step       int       `json:"step"` // model turn that asked for it, from 1
+    Tool       string    `json:"tool"`
+    Arguments  string    `json:"arguments"` // as the model sent them, a JSON object
+    Outcome    string    `json:"outcome"`   // ok, error or rejected
+    Error      string    `json:"error,omitempty"`
+    Result     string    `json:"result,omitempty"` // what the model was told, possibly shortened
+    DurationMS int64     `json:"duration_ms"`
+    At         time.Time `json:"at"`
+}
+
+// TutorRunList lists tutor runs, newest first.
+type TutorRunList struct {
+    Total int        `json:"total"`
+    Runs  []TutorRun `json:"runs"`
 }
@@ -0,0 +1,176 @@
+// Package openapi builds an OpenAPI 3 document from Go types and validates
+// decoded JSON against the generated schemas.
+//
+// Schemas are derived from struct fields and their `json` tags. Constraints
+// come from an optional `validate` tag holding comma-separated rules:
+//
+//	required     the field must be present and non-null (strings also non-empty)
+//	min=N/max=N  numeric bounds
+//	maxlen=N     maximum string length
+//	minitems=N   minimum array length
+//	enum=a|b|c   allowed string values
+//
+// and a `doc` tag holding the field description.
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of the OpenAPI 3.0 Schema Object used by this API.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Float returns a pointer to v, for use with Minimum/Maximum.
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v, for use with length constraints.
+func Int(v int) *int {
+	return &v
+}
+
+// SchemaFor returns the schema for the type of v. Named struct types are
+// registered once under components/schemas and referenced with $ref.
+func (d *Document) SchemaFor(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Component returns the registered schema for a named struct type so callers
+// can adjust constraints that depend on runtime configuration.
+func (d *Document) Component(name string) *Schema {
+	return d.Components.Schemas[name]
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.PkgPath() == "time" && t.Name() == "Time" {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Reserve the name first so self-referencing types terminate.
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := d.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := d.schemaOf(f.Type)
+		if f.Type.Kind() == reflect.Pointer {
+			prop.Nullable = true
+		}
+		// $ref siblings are ignored by OpenAPI 3.0, so only inline schemas
+		// carry a description.
+		if doc := f.Tag.Get("doc"); doc != "" && prop.Ref == "" {
+			prop.Description = doc
+		}
+		if applyRules(prop, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// applyRules copies validate-tag constraints onto s and reports whether the
+// field is required.
+func applyRules(s *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "required":
+			required = true
+			if s.Type == "string" && s.MinLength == nil {
+				s.MinLength = Int(1)
+			}
+		case "min":
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				s.Minimum = Float(f)
+			}
+		case "max":
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				s.Maximum = Float(f)
+			}
+		case "maxlen":
+			if n, err := strconv.Atoi(val); err == nil {
+				s.MaxLength = Int(n)
+			}
+		case "minitems":
+			if n, err := strconv.Atoi(val); err == nil {
+				s.MinItems = Int(n)
+			}
+		case "enum":
+			s.Enum = strings.Split(val, "|")
+		}
+	}
+	return required
+}
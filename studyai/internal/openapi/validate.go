@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldError describes one validation failure. Field is a JSON path such as
+// "questions[2].options"; it is empty for errors about the whole body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidateJSON decodes body and validates it against s.
+func (d *Document) ValidateJSON(s *Schema, body []byte) []FieldError {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []FieldError{{Message: "invalid JSON: " + err.Error()}}
+	}
+	return d.Validate(s, v)
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against s.
+func (d *Document) Validate(s *Schema, v any) []FieldError {
+	var errs []FieldError
+	d.validate(s, v, "", &errs)
+	return errs
+}
+
+func (d *Document) validate(s *Schema, v any, path string, errs *[]FieldError) {
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		d.validate(d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")], v, path, errs)
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if !s.Nullable && s.Type != "" {
+			fail("must not be null")
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if val, present := obj[name]; !present || val == nil {
+				*errs = append(*errs, FieldError{Field: join(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				d.validate(prop, obj[name], join(path, name), errs)
+			} else if s.AdditionalProperties != nil {
+				d.validate(s.AdditionalProperties, obj[name], join(path, name), errs)
+			}
+		}
+
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			fail("must contain at least %d items", *s.MinItems)
+		}
+		for i, item := range arr {
+			d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if s.MinLength != nil && len([]rune(strings.TrimSpace(str))) < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && len([]rune(str)) > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		f, err := num.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if s.Type == "integer" && f != float64(int64(f)) {
+			fail("must be an integer")
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be at most %s", formatNumber(*s.Maximum))
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func formatNumber(f float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%f", f), "0"), ".")
+}
+
+// ValidateParameter checks the raw value of a path or query parameter.
+// present reports whether the parameter was supplied at all.
+func (d *Document) ValidateParameter(p Parameter, raw string, present bool) []FieldError {
+	if !present || raw == "" {
+		if p.Required {
+			return []FieldError{{Field: p.Name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	var v any = raw
+	if p.Schema != nil {
+		switch p.Schema.Type {
+		case "integer", "number":
+			v = json.Number(raw)
+			if _, err := json.Number(raw).Float64(); err != nil {
+				return []FieldError{{Field: p.Name, Message: "must be a number"}}
+			}
+		case "boolean":
+			switch raw {
+			case "true":
+				v = true
+			case "false":
+				v = false
+			default:
+				return []FieldError{{Field: p.Name, Message: "must be true or false"}}
+			}
+		}
+	}
+
+	var errs []FieldError
+	d.validate(p.Schema, v, p.Name, &errs)
+	return errs
+}
@@ -0,0 +1,153 @@
+// Package retrieval splits course materials into passages and ranks them
+// against a query, either by the cosine similarity of embeddings or, without
+// embeddings, lexically with BM25.
+package retrieval
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: k1 limits how much repeating a term helps, and b how much
+// long passages are penalized.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "do": true, "does": true, "for": true, "from": true, "has": true, "have": true, "how": true,
+	"i": true, "if": true, "in": true, "is": true, "it": true, "its": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "to": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true, "why": true, "will": true, "with": true,
+	"you": true, "your": true,
+}
+
+// Chunk splits text into passages of about size words, keeping paragraphs
+// together where they fit. A paragraph longer than size is split into
+// windows that share overlap words with the window before, so a sentence cut
+// at a boundary still appears whole in one of them.
+func Chunk(text string, size, overlap int) []string {
+	var chunks, cur []string
+	flush := func() {
+		if len(cur) > 0 {
+			chunks = append(chunks, strings.Join(cur, " "))
+			cur = nil
+		}
+	}
+	for _, para := range paragraphs(text) {
+		words := strings.Fields(para)
+		if len(cur)+len(words) > size {
+			flush()
+		}
+		if len(words) <= size {
+			cur = append(cur, words...)
+			continue
+		}
+		step := max(1, size-overlap)
+		for start := 0; start < len(words); start += step {
+			end := min(start+size, len(words))
+			chunks = append(chunks, strings.Join(words[start:end], " "))
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	flush()
+	return chunks
+}
+
+// paragraphs splits text at blank lines.
+func paragraphs(text string) []string {
+	var out []string
+	var cur strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteByte('\n')
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// Terms returns the indexable words of text: lower-cased, without stop words,
+// with a plural "s" removed so "cells" matches "cell".
+func Terms(text string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// BM25 scores each document, given as its Terms, against the query terms.
+// A document sharing no term with the query scores 0.
+func BM25(docs [][]string, query []string) []float64 {
+	scores := make([]float64, len(docs))
+	if len(docs) == 0 || len(query) == 0 {
+		return scores
+	}
+	var total int
+	freq := make([]map[string]int, len(docs))
+	containing := map[string]int{}
+	for i, d := range docs {
+		total += len(d)
+		freq[i] = map[string]int{}
+		for _, t := range d {
+			if freq[i][t]++; freq[i][t] == 1 {
+				containing[t]++
+			}
+		}
+	}
+	avgLen := float64(total) / float64(len(docs))
+	n := float64(len(docs))
+	seen := map[string]bool{}
+	for _, t := range query {
+		if seen[t] || containing[t] == 0 {
+			continue
+		}
+		seen[t] = true
+		idf := math.Log(1 + (n-float64(containing[t])+0.5)/(float64(containing[t])+0.5))
+		for i, d := range docs {
+			if f := float64(freq[i][t]); f > 0 {
+				scores[i] += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(len(d))/avgLen))
+			}
+		}
+	}
+	return scores
+}
+
+// Cosine is the cosine similarity of two vectors, or 0 when their lengths
+// differ or either is zero.
+func Cosine(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) == 0 {
+		return 0
+	}
+	var dot, nx, ny float64
+	for i := range x {
+		dot += x[i] * y[i]
+		nx += x[i] * x[i]
+		ny += y[i] * y[i]
+	}
+	if nx == 0 || ny == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(nx*ny)
+}
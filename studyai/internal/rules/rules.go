@@ -1,16 +1,19 @@
 package rules
 
-import "studyai/internal/models"
+import (
+    "studyai/internal/config"
+    "studyai/internal/models"
+)
 
-func Apply(req models.StudyRequest) models.RuleResult {
+func Apply(cfg config.Rules, req models.StudyRequest) models.RuleResult {
     issues := []string{}
     hoursPerDay := float64(req.AvailableHours) / float64(req.DurationDays)
 
-    if req.Difficulty == "high" && hoursPerDay < 2.0 {
+    if req.Difficulty == "high" && hoursPerDay < cfg.HighDifficultyMinDailyHours {
         issues = append(issues, "insufficient daily study time for high difficulty material")
     }
 
-    if hoursPerDay > 8.0 {
+    if hoursPerDay > cfg.BurnoutDailyHours {
         issues = append(issues, "risk of burnout due to excessive daily hours")
     }
 
@@ -0,0 +1,81 @@
+// Package srs schedules flashcard reviews with the SM-2 spaced-repetition
+// algorithm. Each successful recall lengthens the interval by the card's ease
+// factor; a failed recall resets the card to a one-day interval and makes it
+// harder.
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+// Recall grades, from SM-2. Grades below GradePass count as a lapse.
+const (
+	GradeBlackout  = 0 // no recollection
+	GradeWrong     = 1 // wrong, but the answer felt familiar
+	GradeHardWrong = 2 // wrong, but the answer seemed easy once shown
+	GradePass      = 3 // correct with serious difficulty
+	GradeGood      = 4 // correct after hesitation
+	GradePerfect   = 5 // perfect recall
+)
+
+// minEase keeps difficult cards from being scheduled ever more often.
+const minEase = 1.3
+
+// State is a card's scheduling state.
+type State struct {
+	Ease        float64
+	Interval    int // days until the next review
+	Repetitions int // consecutive successful recalls
+	Lapses      int
+}
+
+// New returns the state of a card that has never been reviewed.
+func New() State {
+	return State{Ease: 2.5}
+}
+
+// Review applies a recall grade (0-5) and returns the new state.
+func Review(st State, grade int) State {
+	grade = max(GradeBlackout, min(GradePerfect, grade))
+	if grade < GradePass {
+		st.Repetitions = 0
+		st.Interval = 1
+		st.Lapses++
+	} else {
+		st.Repetitions++
+		switch st.Repetitions {
+		case 1:
+			st.Interval = 1
+		case 2:
+			st.Interval = 6
+		default:
+			st.Interval = int(math.Round(float64(st.Interval) * st.Ease))
+		}
+	}
+	q := float64(GradePerfect - grade)
+	st.Ease = math.Max(minEase, st.Ease+0.1-q*(0.08+q*0.02))
+	return st
+}
+
+// Lapse marks a card as forgotten outside a review, for example when the
+// student misses the same question in another quiz. The card becomes due
+// immediately.
+func Lapse(st State) State {
+	st.Repetitions = 0
+	st.Interval = 0
+	st.Lapses++
+	st.Ease = math.Max(minEase, st.Ease-0.2)
+	return st
+}
+
+// NextDue returns when a card reviewed at t with state st is due again.
+func NextDue(t time.Time, st State) time.Time {
+	return t.AddDate(0, 0, st.Interval)
+}
+
+// StartOfDay truncates t to midnight UTC; review days are UTC calendar days.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,292 @@
+// Package store provides small keyed collections persisted as JSON files.
+// Each collection is held in memory and rewritten atomically on every change,
+// which suits the modest data volumes of a single StudyAI instance. A Store
+// opened with an empty directory keeps everything in memory only.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Store is a directory of JSON collections.
+type Store struct {
+	dir string
+}
+
+// Open prepares dir for use, creating it if necessary. An empty dir yields an
+// in-memory store.
+func Open(dir string) (*Store, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("open store: %w", err)
+		}
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Persistent reports whether collections are written to disk.
+func (s *Store) Persistent() bool {
+	return s.dir != ""
+}
+
+// Ping verifies the data directory is writable.
+func (s *Store) Ping(ctx context.Context) error {
+	if s.dir == "" {
+		return ctx.Err()
+	}
+	f, err := os.CreateTemp(s.dir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("store not writable: %w", err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// Collection is a set of values of type T keyed by string ID.
+type Collection[T any] struct {
+	path string
+
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+// NewCollection opens the collection called name, loading any saved data.
+func NewCollection[T any](s *Store, name string) (*Collection[T], error) {
+	c := &Collection[T]{items: map[string]T{}}
+	if s.dir == "" {
+		return c, nil
+	}
+	c.path = filepath.Join(s.dir, name+".json")
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, &c.items); err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Get returns the value stored under id.
+func (c *Collection[T]) Get(id string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.items[id]
+	return v, ok
+}
+
+// Put stores v under id.
+func (c *Collection[T]) Put(id string, v T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, existed := c.items[id]
+	c.items[id] = v
+	if err := c.save(); err != nil {
+		if existed {
+			c.items[id] = prev
+		} else {
+			delete(c.items, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// PutMany stores each value in items under its key and writes the collection
+// once. If the write fails, no change is kept.
+func (c *Collection[T]) PutMany(items map[string]T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev := make(map[string]T, len(items))
+	for id, v := range items {
+		if p, existed := c.items[id]; existed {
+			prev[id] = p
+		}
+		c.items[id] = v
+	}
+	if err := c.save(); err != nil {
+		for id := range items {
+			if p, existed := prev[id]; existed {
+				c.items[id] = p
+			} else {
+				delete(c.items, id)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Update atomically reads, modifies and writes the value under id. fn receives
+// the current value and whether it exists; returning an error aborts the update.
+func (c *Collection[T]) Update(id string, fn func(v T, exists bool) (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, existed := c.items[id]
+	next, err := fn(prev, existed)
+	if err != nil {
+		return prev, err
+	}
+	c.items[id] = next
+	if err := c.save(); err != nil {
+		if existed {
+			c.items[id] = prev
+		} else {
+			delete(c.items, id)
+		}
+		return prev, err
+	}
+	return next, nil
+}
+
+// UpdateMany applies fn to each id in order, as Update does, and writes the
+// collection once. fn sees the changes made for earlier ids. If fn or the
+// write fails, no change is kept.
+func (c *Collection[T]) UpdateMany(ids []string, fn func(id string, v T, exists bool) (T, error)) ([]T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	type saved struct {
+		v       T
+		existed bool
+	}
+	prev := map[string]saved{}
+	rollback := func() {
+		for id, p := range prev {
+			if p.existed {
+				c.items[id] = p.v
+			} else {
+				delete(c.items, id)
+			}
+		}
+	}
+	out := make([]T, 0, len(ids))
+	for _, id := range ids {
+		cur, exists := c.items[id]
+		if _, seen := prev[id]; !seen {
+			prev[id] = saved{cur, exists}
+		}
+		next, err := fn(id, cur, exists)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		c.items[id] = next
+		out = append(out, next)
+	}
+	if err := c.save(); err != nil {
+		rollback()
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete removes id. Deleting a missing key returns ErrNotFound.
+func (c *Collection[T]) Delete(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, ok := c.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(c.items, id)
+	if err := c.save(); err != nil {
+		c.items[id] = prev
+		return err
+	}
+	return nil
+}
+
+// DeleteMany removes each of ids that exists and writes the collection once.
+// It returns the values removed, keyed by ID. If the write fails, nothing is
+// removed.
+func (c *Collection[T]) DeleteMany(ids []string) (map[string]T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := map[string]T{}
+	for _, id := range ids {
+		if v, ok := c.items[id]; ok {
+			removed[id] = v
+			delete(c.items, id)
+		}
+	}
+	if len(removed) == 0 {
+		return removed, nil
+	}
+	if err := c.save(); err != nil {
+		for id, v := range removed {
+			c.items[id] = v
+		}
+		return nil, err
+	}
+	return removed, nil
+}
+
+// List returns all values ordered by key.
+func (c *Collection[T]) List() []T {
+	return c.Filter(func(T) bool { return true })
+}
+
+// Filter returns the values for which keep returns true, ordered by key.
+func (c *Collection[T]) Filter(keep func(T) bool) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.items))
+	for k := range c.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]T, 0, len(keys))
+	for _, k := range keys {
+		if v := c.items[k]; keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Len returns the number of stored values.
+func (c *Collection[T]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// save writes the collection to disk via a temporary file and rename.
+// Callers must hold the write lock.
+func (c *Collection[T]) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
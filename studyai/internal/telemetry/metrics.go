@@ -0,0 +1,307 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets (in seconds) suited to HTTP and LLM calls.
+var DefaultBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics exposed on /metrics.
+var (
+	HTTPRequestDuration = NewHistogramVec(
+		"studyai_http_request_duration_seconds",
+		"HTTP request latency by method, route and status code.",
+		[]string{"method", "route", "status"}, DefaultBuckets)
+
+	HTTPRequestsInFlight = NewGaugeVec(
+		"studyai_http_requests_in_flight",
+		"HTTP requests currently being served.",
+		nil)
+
+	LLMRequestDuration = NewHistogramVec(
+		"studyai_llm_request_duration_seconds",
+		"LLM call latency by provider and feature.",
+		[]string{"provider", "feature"}, DefaultBuckets)
+
+	LLMRequests = NewCounterVec(
+		"studyai_llm_requests_total",
+		"LLM calls by provider, feature and outcome (ok or error).",
+		[]string{"provider", "feature", "outcome"})
+
+	LLMRequestsInFlight = NewGaugeVec(
+		"studyai_llm_requests_in_flight",
+		"LLM calls currently waiting for the provider.",
+		nil)
+
+	OCRRequestDuration = NewHistogramVec(
+		"studyai_ocr_request_duration_seconds",
+		"OCR call latency by outcome (ok or error).",
+		[]string{"outcome"}, DefaultBuckets)
+
+	FallbackActivations = NewCounterVec(
+		"studyai_fallback_activations_total",
+		"Number of times a deterministic fallback replaced an AI result.",
+		[]string{"component", "reason"})
+
+	GeneratedQuestionChecks = NewCounterVec(
+		"studyai_generated_question_checks_total",
+		"LLM-generated quiz questions verified, repaired or rejected by validation, by reason.",
+		[]string{"outcome", "reason"})
+
+	TutorToolCalls = NewCounterVec(
+		"studyai_tutor_tool_calls_total",
+		"Tools called by the tutor agent, by tool and outcome (ok, error or rejected).",
+		[]string{"tool", "outcome"})
+)
+
+var defaultRegistry = &registry{}
+
+type collector interface {
+	writeTo(w io.Writer)
+}
+
+type registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func (r *registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// MetricsHandler serves all registered metrics in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes all registered metrics in the Prometheus text format.
+func WriteMetrics(w io.Writer) {
+	defaultRegistry.mu.Lock()
+	collectors := append([]collector(nil), defaultRegistry.collectors...)
+	defaultRegistry.mu.Unlock()
+	for _, c := range collectors {
+		c.writeTo(w)
+	}
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec creates and registers a counter.
+func NewCounterVec(name, help string, labels []string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	defaultRegistry.register(c)
+	return c
+}
+
+// Inc adds one to the series identified by labelValues.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must be non-negative) to the series identified by labelValues.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := seriesKey(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *CounterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, s.labelValues, "", ""), formatFloat(s.value))
+	}
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+// NewGaugeVec creates and registers a gauge.
+func NewGaugeVec(name, help string, labels []string) *GaugeVec {
+	g := &GaugeVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	defaultRegistry.register(g)
+	return g
+}
+
+// Add adds v (which may be negative) to the series identified by labelValues.
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	key := seriesKey(g.labels, labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		g.series[key] = s
+	}
+	s.value += v
+}
+
+func (g *GaugeVec) writeTo(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, key := range sortedKeys(g.series) {
+		s := g.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(g.labels, s.labelValues, "", ""), formatFloat(s.value))
+	}
+}
+
+// HistogramVec samples observations into cumulative buckets, partitioned by labels.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// NewHistogramVec creates and registers a histogram with the given upper bounds.
+func NewHistogramVec(name, help string, labels []string, buckets []float64) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: append([]float64(nil), buckets...),
+		series:  map[string]*histogramSeries{},
+	}
+	sort.Float64s(h.buckets)
+	defaultRegistry.register(h)
+	return h
+}
+
+// Observe records v in the series identified by labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := seriesKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+// seriesKey joins label values, padding or truncating to the declared label count.
+func seriesKey(labels, values []string) string {
+	normalized := make([]string, len(labels))
+	copy(normalized, values)
+	return strings.Join(normalized, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs = append(pairs, name+`="`+escapeLabel(v)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+escapeLabel(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
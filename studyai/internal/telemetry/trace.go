@@ -0,0 +1,140 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a lightweight, OpenTelemetry-style unit of work. Spans are
+// propagated through context.Context and exported as structured log records
+// (message "span", debug level) when they end, so a single trace can be
+// reassembled from the logs by trace_id / parent_span_id.
+type Span struct {
+	ctx      context.Context
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []slog.Attr
+	err   error
+	ended bool
+}
+
+type spanKey struct{}
+
+// remoteParent is a span context received from an incoming traceparent header.
+type remoteParent struct {
+	traceID string
+	spanID  string
+}
+
+type remoteParentKey struct{}
+
+// StartSpan starts a child of the span in ctx (or a new root span) and returns
+// a context carrying it. Callers must call End on the returned span.
+func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	s := &Span{
+		name:   name,
+		spanID: randomHex(8),
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else if rp, ok := ctx.Value(remoteParentKey{}).(remoteParent); ok {
+		s.traceID = rp.traceID
+		s.parentID = rp.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	s.ctx = context.WithValue(ctx, spanKey{}, s)
+	return s.ctx, s
+}
+
+// SpanFromContext returns the active span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetAttributes attaches additional attributes to the span.
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// TraceID returns the span's trace ID.
+func (s *Span) TraceID() string {
+	return s.traceID
+}
+
+// TraceParent renders the span as a W3C traceparent header value.
+func (s *Span) TraceParent() string {
+	return "00-" + s.traceID + "-" + s.spanID + "-01"
+}
+
+// End finishes the span and exports it. Calling End more than once is a no-op.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	attrs := []slog.Attr{
+		slog.String("span_name", s.name),
+		slog.String("parent_span_id", s.parentID),
+		slog.Time("start", s.start),
+		slog.Float64("duration_ms", float64(time.Since(s.start).Microseconds())/1000),
+	}
+	attrs = append(attrs, s.attrs...)
+	status := "ok"
+	if s.err != nil {
+		status = "error"
+		attrs = append(attrs, slog.String("error", s.err.Error()))
+	}
+	attrs = append(attrs, slog.String("status", status))
+	s.mu.Unlock()
+
+	slog.LogAttrs(s.ctx, slog.LevelDebug, "span", attrs...)
+}
+
+// ContextWithRemoteParent parses a W3C traceparent header value and, if it is
+// valid, makes it the parent of the next span started from the returned context.
+func ContextWithRemoteParent(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	if !isHex(parts[1]) || !isHex(parts[2]) || strings.Trim(parts[1], "0") == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, remoteParent{traceID: parts[1], spanID: parts[2]})
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,19 +1,26 @@
 package validation
 
 import (
-    "errors"
+    "strings"
+
+    "studyai/internal/apperr"
     "studyai/internal/models"
 )
 
+// Validate checks a study request and reports every invalid field at once.
 func Validate(req models.StudyRequest) error {
-    if req.Goal == "" {
-        return errors.New("study goal is required")
+    var fields []apperr.FieldError
+    if strings.TrimSpace(req.Goal) == "" {
+        fields = append(fields, apperr.FieldError{Field: "goal", Message: "study goal is required"})
     }
     if req.AvailableHours <= 0 {
-        return errors.New("available hours must be greater than zero")
+        fields = append(fields, apperr.FieldError{Field: "available_hours", Message: "available hours must be greater than zero"})
     }
     if req.DurationDays <= 0 {
-        return errors.New("duration days must be greater than zero")
+        fields = append(fields, apperr.FieldError{Field: "duration_days", Message: "duration days must be greater than zero"})
+    }
+    if len(fields) > 0 {
+        return apperr.Validation(fields...)
     }
     return nil
 }